@@ -4,9 +4,16 @@ import (
 	"blog-platform/internal/handler"
 	"blog-platform/internal/models"
 	"blog-platform/internal/service"
+	"blog-platform/pkg/mailer"
 	"blog-platform/pkg/proxy"
+	"blog-platform/pkg/storage"
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,42 +24,185 @@ func main() {
 	db := models.GetDatabaseInstance()
 
 	// Initialize repositories
-	realPostRepo := models.NewPostRepository(db.DB)
-	
+	outboxRepo := models.NewOutboxRepository(db.DB)
+	realPostRepo := models.NewPostRepository(db.DB, outboxRepo)
+	taxonomyRepo := models.NewTaxonomyRepository(db.DB)
+	leaseRepo := models.NewLeaseRepository(db.DB)
+	commentRepo := models.NewCommentRepository(db.DB, outboxRepo)
+	webhookRepo := models.NewWebhookRepository(db.DB)
+	analyticsRepo := models.NewAnalyticsRepository(db.DB)
+	mediaRepo := models.NewMediaRepository(db.DB)
+	seriesRepo := models.NewSeriesRepository(db.DB)
+	reactionRepo := models.NewReactionRepository(db.DB)
+	followRepo := models.NewFollowRepository(db.DB)
+	notificationRepo := models.NewNotificationRepository(db.DB)
+	emailRepo := models.NewEmailRepository(db.DB)
+	newsletterRepo := models.NewNewsletterRepository(db.DB)
+
 	// Wrap repository with Caching Proxy (Proxy pattern)
 	// Cache up to 100 posts with 5-minute TTL
 	postRepo := proxy.NewPostRepositoryCachingProxy(realPostRepo, 100, 5*time.Minute)
 	log.Println("✅ Caching Proxy enabled: Max 100 posts, 5min TTL")
 
 	// Initialize services
-	commandService := service.NewCommandService(postRepo)
-	queryService := service.NewQueryService(postRepo)
+	locales, err := service.NewLocaleConfig(envOr("LOCALES", "en"), os.Getenv("LOCALE_FALLBACKS"))
+	if err != nil {
+		log.Fatalf("Invalid LOCALES or LOCALE_FALLBACKS: %v", err)
+	}
+	if assigned, err := postRepo.AssignDefaultLocale(locales.Default); err != nil {
+		log.Fatalf("Failed to assign the default locale: %v", err)
+	} else if assigned > 0 {
+		log.Printf("Assigned locale %s to %d posts", locales.Default, assigned)
+	}
+
+	// Keep the rendered HTML of up to 500 posts
+	contentRenderer := service.NewContentRenderer(500)
+	commandService := service.NewCommandService(postRepo, taxonomyRepo, locales)
+	queryService := service.NewQueryService(postRepo, taxonomyRepo, commentRepo, seriesRepo, reactionRepo, contentRenderer, locales)
+	taxonomyService := service.NewTaxonomyService(taxonomyRepo)
+	seriesService := service.NewSeriesService(seriesRepo, postRepo, queryService)
+	reactions := service.ParseReactions(envOr("REACTIONS", "❤️,🎉,😂,😮,😢"))
+	reactionService := service.NewReactionService(reactionRepo, postRepo, queryService, reactions)
+	notificationService := service.NewNotificationService(followRepo, notificationRepo, postRepo, taxonomyRepo, seriesRepo)
 	contentFactory := &service.ContentFactory{}
 	postService := service.NewPostService()
-	searchService := service.NewSearchService(postRepo)
+	searchService := service.NewSearchService(postRepo, contentRenderer)
+	commentService := service.NewCommentService(commentRepo, postRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, postRepo)
+	site := service.NewSiteConfig(os.Getenv("SITE_URL"), os.Getenv("SITE_TITLE"), os.Getenv("SITE_DESCRIPTION"))
+	site.Locales = locales
+	if path := os.Getenv("ROBOTS_TXT"); path != "" {
+		robots, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read robots.txt: %v", err)
+		}
+		site.Robots = string(robots)
+	}
+	feedService := service.NewFeedService(queryService, taxonomyRepo, seriesRepo, site)
+	sitemapService := service.NewSitemapService(postRepo, site)
+	seoService := service.NewSEOService(postRepo, queryService, contentRenderer, site)
+	mediaService := service.NewMediaService(mediaRepo, mediaStorage(), envOr("MEDIA_URL", "/media"), mediaMaxBytes())
+	apiURL := envOr("API_URL", "http://localhost:8080")
+	emailService := service.NewEmailService(emailRepo, notificationRepo, queryService, site, service.MailConfig{
+		From:           envOr("MAIL_FROM", "Blog <noreply@localhost>"),
+		UnsubscribeURL: apiURL + "/api/v1/email/unsubscribe",
+	})
+	newsletterService := service.NewNewsletterService(newsletterRepo, postRepo, emailService, site, service.NewsletterConfig{
+		URL:           apiURL + "/api/v1/newsletter",
+		WebhookSecret: os.Getenv("NEWSLETTER_WEBHOOK_SECRET"),
+	})
+	relatedService := service.NewRelatedPostsService(postRepo, taxonomyRepo, queryService)
+	if err := relatedService.Rebuild(); err != nil {
+		log.Printf("Failed to build related posts index: %v", err)
+	}
+
+	// Buffer page views and write them to the daily aggregates every 10 seconds
+	viewTracker, err := service.NewViewTracker(analyticsRepo, 10*time.Second)
+	if err != nil {
+		log.Fatalf("Failed to initialize view tracking: %v", err)
+	}
+	viewTracker.Start()
+
+	// Register observers, each with its own worker pool
+	postService.SubscribeWith(&service.SearchIndexObserver{}, service.SubscriptionOptions{
+		EventTypes: []models.EventType{models.EventPostCreated, models.EventPostUpdated, models.EventPostDeleted},
+	})
+	postService.SubscribeWith(service.NewNotificationObserver(notificationService, emailService), service.SubscriptionOptions{
+		EventTypes: []models.EventType{models.EventPostCreated, models.EventPostUpdated, models.EventCommentModerated},
+	})
+	postService.SubscribeWith(contentRenderer, service.SubscriptionOptions{
+		EventTypes: []models.EventType{models.EventPostUpdated, models.EventPostDeleted},
+	})
+	postService.SubscribeWith(mediaService, service.SubscriptionOptions{
+		EventTypes: []models.EventType{models.EventPostCreated, models.EventPostUpdated},
+	})
+	postService.SubscribeWith(service.NewAnalyticsObserver(viewTracker), service.SubscriptionOptions{
+		EventTypes: []models.EventType{models.EventCommentModerated, models.EventPostDeleted},
+	})
+	postService.SubscribeWith(service.NewWebhookObserver(webhookService), service.SubscriptionOptions{
+		Timeout: 30 * time.Second,
+	})
+
+	// Send queued webhook deliveries
+	webhookSender := service.NewWebhookSender(webhookService, leaseRepo, nil, 5*time.Second)
+	webhookSender.Start()
+
+	// Send queued emails and weekly digests
+	emailSender := service.NewEmailSender(emailService, leaseRepo, newMailer(), 30*time.Second, mailRatePerMinute())
+	emailSender.Start()
+
+	// Queue scheduled newsletter campaigns as they fall due
+	newsletterScheduler := service.NewNewsletterScheduler(newsletterService, leaseRepo, 30*time.Second)
+	newsletterScheduler.Start()
+
+	// Deliver outbox events to the observers (Transactional Outbox)
+	outboxDispatcher := service.NewOutboxDispatcher(outboxRepo, leaseRepo, postService, time.Second)
+	outboxDispatcher.Start()
+
+	// Stream events to the clients connected to this replica, keeping the
+	// last 1000 for resume. Every replica follows the outbox itself, since
+	// the dispatcher only runs on one of them.
+	eventStream := service.NewEventStream(1000, 64)
+	eventFollower := service.NewOutboxFollower(outboxRepo, eventStream, 500*time.Millisecond, 1000)
+	eventFollower.Start()
 
-	// Register observers
-	postService.Subscribe(&service.SearchIndexObserver{})
-	postService.Subscribe(&service.NotificationObserver{})
-	postService.Subscribe(&service.AnalyticsObserver{})
+	// Keep this replica's related posts index and sitemap current. The
+	// index goes back 100 events to cover any committed while it was built;
+	// applying an event twice is harmless.
+	relatedFollower := service.NewOutboxFollower(outboxRepo, relatedService, 500*time.Millisecond, 100)
+	relatedFollower.Start()
+	sitemapFollower := service.NewOutboxFollower(outboxRepo, sitemapService, 500*time.Millisecond, 0)
+	sitemapFollower.Start()
+
+	// Apply scheduled publish/unpublish times every 30 seconds
+	publishScheduler := service.NewPublishScheduler(postRepo, leaseRepo, 30*time.Second)
+	publishScheduler.Start()
+
+	// Recompute trending and popular rankings every 5 minutes
+	rankingRefresher := service.NewRankingRefresher(analyticsRepo, leaseRepo, 5*time.Minute, postRepo.InvalidateRankings)
+	rankingRefresher.Start()
+
+	// Delete uploads no post has referenced for a day, checking hourly
+	mediaCollector := service.NewMediaCollector(mediaService, leaseRepo, time.Hour, 24*time.Hour)
+	mediaCollector.Start()
 
 	// Initialize handlers
 	postHandler := handler.NewPostHandler(
 		commandService,
 		queryService,
 		contentFactory,
-		postService,
 		searchService,
+		viewTracker,
 	)
+	taxonomyHandler := handler.NewTaxonomyHandler(taxonomyService, queryService)
+	seriesHandler := handler.NewSeriesHandler(seriesService)
+	reactionHandler := handler.NewReactionHandler(reactionService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	emailHandler := handler.NewEmailHandler(emailService)
+	newsletterHandler := handler.NewNewsletterHandler(newsletterService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	outboxHandler := handler.NewOutboxHandler(outboxDispatcher)
+	webhookHandler := handler.NewWebhookHandler(webhookService, webhookSender)
+	eventHandler := handler.NewEventHandler(eventStream)
+	observerHandler := handler.NewObserverHandler(postService)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService)
+	relatedHandler := handler.NewRelatedPostsHandler(relatedService)
+	feedHandler := handler.NewFeedHandler(feedService)
+	sitemapHandler := handler.NewSitemapHandler(sitemapService)
+	mediaHandler := handler.NewMediaHandler(mediaService)
+	seoHandler := handler.NewSEOHandler(seoService)
 
 	// Set up Gin router
 	router := gin.Default()
+	router.Use(handler.RequestID())
 
 	// CORS middleware for React frontend
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, X-Actor-ID, X-Device-Token, Last-Event-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -63,6 +213,23 @@ func main() {
 		c.Next()
 	})
 
+	// Syndication feeds: site-wide and per author, type, tag and series
+	for _, feed := range []string{"/feed.rss", "/feed.atom", "/feed.json"} {
+		router.GET(feed, feedHandler.GetFeed)
+		router.GET("/authors/:id"+feed, feedHandler.GetFeed)
+		router.GET("/types/:type"+feed, feedHandler.GetFeed)
+		router.GET("/tags/:slug"+feed, feedHandler.GetFeed)
+		router.GET("/series/:series"+feed, feedHandler.GetFeed)
+	}
+
+	// Crawler routes
+	router.GET("/sitemap.xml", sitemapHandler.GetSitemap)
+	router.GET("/sitemaps/:page", sitemapHandler.GetSitemapPage)
+	router.GET("/robots.txt", sitemapHandler.GetRobots)
+
+	// Uploaded files
+	router.GET("/media/:key", mediaHandler.ServeMedia)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
@@ -75,8 +242,158 @@ func main() {
 			posts.PUT("/:id", postHandler.UpdatePost)
 			posts.DELETE("/:id", postHandler.DeletePost)
 			posts.GET("/search", postHandler.SearchPosts)
+			posts.GET("/trending", postHandler.ListTrendingPosts)
+			posts.GET("/popular", postHandler.ListPopularPosts)
+			posts.GET("/slug/:slug", postHandler.GetPostBySlug)
+			posts.GET("/:id/comments", commentHandler.ListComments)
+			posts.POST("/:id/comments", commentHandler.CreateComment)
+			posts.GET("/:id/stats", analyticsHandler.GetPostStats)
+			posts.GET("/:id/related", relatedHandler.GetRelatedPosts)
+			posts.GET("/:id/head", seoHandler.GetPostHead)
+			posts.GET("/:id/reactions", reactionHandler.GetReactions)
+			posts.PUT("/:id/reactions/:reaction", reactionHandler.AddReaction)
+			posts.DELETE("/:id/reactions/:reaction", reactionHandler.RemoveReaction)
+			posts.PUT("/:id/bookmark", reactionHandler.AddBookmark)
+			posts.DELETE("/:id/bookmark", reactionHandler.RemoveBookmark)
 		}
 
+		// The reader's own bookmarks
+		api.GET("/bookmarks", reactionHandler.ListBookmarks)
+
+		// Follows and the notification inbox of the user in X-Actor-ID
+		follows := api.Group("/follows")
+		{
+			follows.GET("", notificationHandler.ListFollows)
+			follows.PUT("/:type/:id", notificationHandler.Follow)
+			follows.DELETE("/:type/:id", notificationHandler.Unfollow)
+		}
+		notifications := api.Group("/notifications")
+		{
+			notifications.GET("", notificationHandler.ListNotifications)
+			notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
+			notifications.PUT("/:id/read", notificationHandler.MarkRead)
+			notifications.POST("/read-all", notificationHandler.MarkAllRead)
+		}
+
+		// Email preferences, and the unsubscribe links in emails
+		email := api.Group("/email")
+		{
+			email.GET("/preferences", emailHandler.GetPreferences)
+			email.PUT("/preferences", emailHandler.UpdatePreferences)
+			email.GET("/unsubscribe", emailHandler.ConfirmUnsubscribe)
+			email.POST("/unsubscribe", emailHandler.Unsubscribe)
+		}
+
+		// Newsletter lists and campaigns, the links in newsletter email,
+		// and the mail provider's bounce and unsubscribe webhooks
+		newsletter := api.Group("/newsletter")
+		{
+			newsletter.GET("/lists", newsletterHandler.ListLists)
+			newsletter.POST("/lists", newsletterHandler.CreateList)
+			newsletter.GET("/lists/:slug", newsletterHandler.GetList)
+			newsletter.DELETE("/lists/:id", newsletterHandler.DeleteList)
+			newsletter.GET("/lists/:slug/subscribers", newsletterHandler.ListSubscribers)
+			newsletter.POST("/lists/:slug/subscribe", newsletterHandler.Subscribe)
+			newsletter.GET("/confirm", newsletterHandler.ConfirmSubscriptionPage)
+			newsletter.POST("/confirm", newsletterHandler.ConfirmSubscription)
+			newsletter.GET("/unsubscribe", newsletterHandler.UnsubscribePage)
+			newsletter.POST("/unsubscribe", newsletterHandler.Unsubscribe)
+			newsletter.GET("/open/:campaign/:token", newsletterHandler.TrackOpen)
+			newsletter.GET("/click/:campaign/:token", newsletterHandler.TrackClick)
+			newsletter.POST("/webhooks/bounce", newsletterHandler.BounceWebhook)
+			newsletter.POST("/webhooks/unsubscribe", newsletterHandler.UnsubscribeWebhook)
+
+			newsletter.GET("/campaigns", newsletterHandler.ListCampaigns)
+			newsletter.POST("/campaigns", newsletterHandler.CreateCampaign)
+			newsletter.GET("/campaigns/:id", newsletterHandler.GetCampaign)
+			newsletter.PUT("/campaigns/:id", newsletterHandler.UpdateCampaign)
+			newsletter.DELETE("/campaigns/:id", newsletterHandler.DeleteCampaign)
+			newsletter.POST("/campaigns/:id/schedule", newsletterHandler.ScheduleCampaign)
+			newsletter.POST("/campaigns/:id/unschedule", newsletterHandler.UnscheduleCampaign)
+			newsletter.GET("/campaigns/:id/stats", newsletterHandler.GetCampaignStats)
+		}
+
+		// Author routes
+		api.GET("/authors/:id/stats", analyticsHandler.GetAuthorStats)
+
+		// Media routes
+		media := api.Group("/media")
+		{
+			media.GET("", mediaHandler.ListMedia)
+			media.POST("", mediaHandler.UploadMedia)
+			media.GET("/:id", mediaHandler.GetMedia)
+			media.DELETE("/:id", mediaHandler.DeleteMedia)
+		}
+
+		// Comment moderation routes
+		comments := api.Group("/comments")
+		{
+			comments.GET("", commentHandler.ListModerationQueue)
+			comments.PUT("/:id/status", commentHandler.ModerateComment)
+		}
+
+		// Tag routes
+		tags := api.Group("/tags")
+		{
+			tags.GET("", taxonomyHandler.ListTags)
+			tags.POST("", taxonomyHandler.CreateTag)
+			tags.GET("/:slug", taxonomyHandler.GetTag)
+			tags.GET("/:slug/posts", taxonomyHandler.ListPostsByTag)
+			tags.PUT("/:id", taxonomyHandler.RenameTag)
+			tags.DELETE("/:id", taxonomyHandler.DeleteTag)
+			tags.POST("/:id/merge", taxonomyHandler.MergeTags)
+		}
+
+		// Category routes
+		categories := api.Group("/categories")
+		{
+			categories.GET("", taxonomyHandler.ListCategories)
+			categories.POST("", taxonomyHandler.CreateCategory)
+			categories.GET("/:slug", taxonomyHandler.GetCategory)
+			categories.GET("/:slug/posts", taxonomyHandler.ListPostsByCategory)
+			categories.PUT("/:id", taxonomyHandler.UpdateCategory)
+			categories.DELETE("/:id", taxonomyHandler.DeleteCategory)
+		}
+
+		// Series routes
+		series := api.Group("/series")
+		{
+			series.GET("", seriesHandler.ListSeries)
+			series.POST("", seriesHandler.CreateSeries)
+			series.GET("/:slug", seriesHandler.GetSeries)
+			series.PUT("/:id", seriesHandler.UpdateSeries)
+			series.DELETE("/:id", seriesHandler.DeleteSeries)
+			series.PUT("/:id/posts", seriesHandler.SetSeriesPosts)
+			series.POST("/:id/posts", seriesHandler.AddSeriesPost)
+			series.DELETE("/:id/posts/:post_id", seriesHandler.RemoveSeriesPost)
+		}
+
+		// Real-time event stream (Server-Sent Events and WebSocket)
+		api.GET("/events", eventHandler.StreamEvents)
+		api.GET("/events/ws", eventHandler.StreamEventsWebSocket)
+
+		// Webhook routes
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", webhookHandler.ListWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+		}
+
+		// Outbox administration routes
+		outbox := api.Group("/admin/outbox")
+		{
+			outbox.GET("", outboxHandler.ListPending)
+			outbox.GET("/dead-letters", outboxHandler.ListDeadLetters)
+			outbox.POST("/dead-letters/:id/replay", outboxHandler.ReplayDeadLetter)
+		}
+		api.GET("/admin/observers", observerHandler.GetMetrics)
+		api.GET("/admin/emails", emailHandler.ListEmails)
+
 		// Cache statistics endpoint (demonstrates Proxy pattern benefits)
 		api.GET("/cache/stats", func(c *gin.Context) {
 			stats := postRepo.GetStatistics()
@@ -88,8 +405,114 @@ func main() {
 	}
 
 	// Start the server
-	log.Println("Starting server on :8080")
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		log.Println("Starting server on :8080")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Shut down gracefully so background workers release their leases
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	eventFollower.Stop()
+	eventStream.Close()
+	relatedFollower.Stop()
+	sitemapFollower.Stop()
+	publishScheduler.Stop()
+	rankingRefresher.Stop()
+	mediaCollector.Stop()
+	outboxDispatcher.Stop()
+	postService.Close()
+	webhookSender.Stop()
+	newsletterScheduler.Stop()
+	emailSender.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Flush views counted while requests drained
+	viewTracker.Stop()
+}
+
+// mediaStorage returns the storage selected by MEDIA_STORAGE: "local"
+// (the default) keeps files in MEDIA_DIR, "memory" keeps them in process
+func mediaStorage() storage.Storage {
+	switch backend := envOr("MEDIA_STORAGE", "local"); backend {
+	case "local":
+		local, err := storage.NewLocal(envOr("MEDIA_DIR", "./media"))
+		if err != nil {
+			log.Fatalf("Failed to initialize media storage: %v", err)
+		}
+		return local
+	case "memory":
+		return storage.NewMemory()
+	default:
+		log.Fatalf("Unknown MEDIA_STORAGE %q; expected local or memory", backend)
+		return nil
+	}
+}
+
+// newMailer returns the mailer selected by MAIL_TRANSPORT: "file" (the
+// default) writes .eml files to MAIL_DIR, "smtp" sends through SMTP_ADDR
+func newMailer() mailer.Mailer {
+	switch transport := envOr("MAIL_TRANSPORT", "file"); transport {
+	case "file":
+		sink, err := mailer.NewFileSink(envOr("MAIL_DIR", "./mail"))
+		if err != nil {
+			log.Fatalf("Failed to initialize mail directory: %v", err)
+		}
+		return sink
+	case "smtp":
+		smtp, err := mailer.NewSMTP(envOr("SMTP_ADDR", "localhost:1025"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		if err != nil {
+			log.Fatalf("Invalid SMTP_ADDR: %v", err)
+		}
+		return smtp
+	default:
+		log.Fatalf("Unknown MAIL_TRANSPORT %q; expected file or smtp", transport)
+		return nil
+	}
+}
+
+// mailRatePerMinute reads the email send rate from MAIL_RATE_PER_MINUTE,
+// defaulting to 60
+func mailRatePerMinute() int {
+	value := os.Getenv("MAIL_RATE_PER_MINUTE")
+	if value == "" {
+		return 60
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid MAIL_RATE_PER_MINUTE %q", value)
+	}
+	return n
+}
+
+// mediaMaxBytes reads the upload size limit from MEDIA_MAX_BYTES,
+// defaulting to 10 MiB
+func mediaMaxBytes() int64 {
+	value := os.Getenv("MEDIA_MAX_BYTES")
+	if value == "" {
+		return 10 << 20
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid MEDIA_MAX_BYTES %q", value)
+	}
+	return n
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *service.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetPostStats reports a post's views, unique visitors and approved comments
+// per day between ?from= and ?to= (YYYY-MM-DD, default the last 30 days)
+func (h *AnalyticsHandler) GetPostStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	stats, err := h.analyticsService.GetPostStats(service.StatsQuery{ID: id, From: c.Query("from"), To: c.Query("to")})
+	if err != nil {
+		c.JSON(analyticsErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAuthorStats reports the same figures across an author's posts, with a
+// breakdown per post
+func (h *AnalyticsHandler) GetAuthorStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+		return
+	}
+
+	stats, err := h.analyticsService.GetAuthorStats(service.StatsQuery{ID: id, From: c.Query("from"), To: c.Query("to")})
+	if err != nil {
+		c.JSON(analyticsErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func analyticsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidStatsRange):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	commentService *service.CommentService
+}
+
+func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
+	return &CommentHandler{commentService: commentService}
+}
+
+// ListComments returns the threaded comments of a post. Readers get
+// approved comments; moderators may pass ?status=pending,spam etc.
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	query := service.ListCommentsQuery{PostID: postID}
+	if status := c.Query("status"); status != "" {
+		query.Statuses = strings.Split(status, ",")
+	}
+
+	comments, err := h.commentService.ListComments(query)
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var cmd service.CreateCommentCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.PostID = postID
+	cmd.Meta = eventMeta(c)
+
+	comment, err := h.commentService.CreateComment(cmd)
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// ListModerationQueue lists comments awaiting moderation (or in ?status=)
+func (h *CommentHandler) ListModerationQueue(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	comments, err := h.commentService.ListModerationQueue(c.DefaultQuery("status", "pending"), limit, offset)
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *CommentHandler) ModerateComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var cmd service.ModerateCommentCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ID = id
+	cmd.Meta = eventMeta(c)
+
+	comment, err := h.commentService.ModerateComment(cmd)
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// commentErrorStatus maps comment service errors to HTTP status codes
+func commentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound), errors.Is(err, service.ErrPostNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCommentsClosed):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidCommentParent), errors.Is(err, service.ErrInvalidCommentStatus),
+		errors.Is(err, service.ErrInvalidCommentContent):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
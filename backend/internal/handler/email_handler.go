@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage is what a reader sees after following the link in an
+// email. Unsubscribing takes a POST so that link scanners opening the
+// link do not unsubscribe anyone.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto;">
+{{if .Error}}<p>{{.Error}}</p>
+{{else if .Done}}<p>You have been unsubscribed{{if .List}} from {{.List}} emails{{end}}.</p>
+{{else}}<form method="post">
+<p>Stop receiving {{if .List}}{{.List}}{{else}}all{{end}} emails?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+var emailListNames = map[string]string{
+	"new_post":      "new post",
+	"comment_reply": "comment reply",
+	"digest":        "weekly digest",
+}
+
+// EmailHandler serves email preferences and unsubscribe links
+type EmailHandler struct {
+	emailService *service.EmailService
+}
+
+func NewEmailHandler(emailService *service.EmailService) *EmailHandler {
+	return &EmailHandler{emailService: emailService}
+}
+
+// GetPreferences returns the email preferences of the user in X-Actor-ID
+func (h *EmailHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.emailService.GetPreferences(currentReader(c).UserID)
+	if err != nil {
+		c.JSON(emailErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *EmailHandler) UpdatePreferences(c *gin.Context) {
+	var cmd service.UpdateEmailPreferencesCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.UserID = currentReader(c).UserID
+
+	prefs, err := h.emailService.UpdatePreferences(cmd)
+	if err != nil {
+		c.JSON(emailErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// ConfirmUnsubscribe shows the page an unsubscribe link opens
+func (h *EmailHandler) ConfirmUnsubscribe(c *gin.Context) {
+	h.renderUnsubscribe(c, http.StatusOK, false, "")
+}
+
+// Unsubscribe turns off the emails named by ?list= (all of them by
+// default) for the owner of ?token=. Mail clients offering one-click
+// unsubscribe (RFC 8058) post here too.
+func (h *EmailHandler) Unsubscribe(c *gin.Context) {
+	if _, err := h.emailService.Unsubscribe(c.Query("token"), c.Query("list")); err != nil {
+		h.renderUnsubscribe(c, emailErrorStatus(err), false, err.Error())
+		return
+	}
+
+	h.renderUnsubscribe(c, http.StatusOK, true, "")
+}
+
+func (h *EmailHandler) renderUnsubscribe(c *gin.Context, status int, done bool, message string) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(c.Writer, gin.H{
+		"Done":  done,
+		"Error": message,
+		"List":  emailListNames[c.Query("list")],
+	})
+}
+
+// ListEmails shows the outgoing email queue, newest first, optionally
+// filtered by ?status=
+func (h *EmailHandler) ListEmails(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	emails, err := h.emailService.ListEmails(c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(emailErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, emails)
+}
+
+func emailErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAccountRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidUnsubscribeToken):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrUnknownEmailList):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
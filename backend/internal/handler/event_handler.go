@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// streamWriteTimeout bounds a single write so a stalled client cannot
+	// pin its connection forever
+	streamWriteTimeout = 10 * time.Second
+	// streamHeartbeat keeps idle connections open through proxies
+	streamHeartbeat = 15 * time.Second
+)
+
+type EventHandler struct {
+	stream *service.EventStream
+}
+
+func NewEventHandler(stream *service.EventStream) *EventHandler {
+	return &EventHandler{stream: stream}
+}
+
+// StreamEvents sends post events as Server-Sent Events. Clients filter with
+// ?types=post_created,post_updated and ?post_id=1,2, and resume after a
+// disconnect with the Last-Event-ID header (or ?last_event_id=).
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	filter, lastEventID, err := parseStreamParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		if lastEventID, err = strconv.ParseInt(header, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	sub, replay, err := h.stream.Subscribe(filter, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.stream.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	write := func(frame string) error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := io.WriteString(c.Writer, frame); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+	for _, event := range replay {
+		if err := write(sseFrame(event)); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				if sub.Lagged() {
+					write("event: lagged\ndata: {\"error\":\"client too slow; reconnect to resume\"}\n\n")
+				}
+				return
+			}
+			if err := write(sseFrame(event)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := write(": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// StreamEventsWebSocket sends the same events as JSON WebSocket messages.
+// It takes the same filters; ?last_event_id= resumes after a disconnect.
+func (h *EventHandler) StreamEventsWebSocket(c *gin.Context) {
+	filter, lastEventID, err := parseStreamParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		sub, replay, err := h.stream.Subscribe(filter, lastEventID)
+		if err != nil {
+			websocket.JSON.Send(ws, gin.H{"error": err.Error()})
+			return
+		}
+		defer h.stream.Unsubscribe(sub)
+
+		// The client does not send anything; reading only detects when it goes away
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		send := func(v interface{}) error {
+			ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			return websocket.JSON.Send(ws, v)
+		}
+
+		for _, event := range replay {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-gone:
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					if sub.Lagged() {
+						send(gin.H{"error": "client too slow; reconnect to resume"})
+					}
+					return
+				}
+				if err := send(event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				if err := websocket.Message.Send(ws, "{}"); err != nil {
+					return
+				}
+			}
+		}
+	}}
+
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// sseFrame encodes an event as one Server-Sent Events message
+func sseFrame(event service.PostEvent) string {
+	data, err := json.Marshal(event)
+	if err != nil {
+		data = []byte(`{}`)
+	}
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, data)
+}
+
+// parseStreamParams reads the ?types=, ?post_id= and ?last_event_id= parameters
+func parseStreamParams(c *gin.Context) (filter service.StreamFilter, lastEventID int64, err error) {
+	if types := c.Query("types"); types != "" {
+		filter.EventTypes = strings.Split(types, ",")
+	}
+
+	if postIDs := c.Query("post_id"); postIDs != "" {
+		for _, raw := range strings.Split(postIDs, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+			if err != nil {
+				return filter, 0, fmt.Errorf("invalid post_id %q", raw)
+			}
+			filter.PostIDs = append(filter.PostIDs, id)
+		}
+	}
+
+	if raw := c.Query("last_event_id"); raw != "" {
+		if lastEventID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return filter, 0, fmt.Errorf("invalid last_event_id %q", raw)
+		}
+	}
+
+	return filter, lastEventID, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"blog-platform/pkg/feed"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	feedService *service.FeedService
+}
+
+func NewFeedHandler(feedService *service.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+// GetFeed serves a feed in the format named by the path's extension (.rss,
+// .atom or .json). The :id, :type, :slug and :series route parameters scope
+// it to an author, a post type, a tag or a series. ?mode=summary leaves out full content and
+// ?lang= picks the language of the site-wide and type feeds.
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	query := service.FeedQuery{
+		Type:       c.Param("type"),
+		TagSlug:    c.Param("slug"),
+		SeriesSlug: c.Param("series"),
+		Lang:       c.Query("lang"),
+		Full:       c.Query("mode") != "summary",
+		FeedURL:    requestURL(c),
+	}
+	if id := c.Param("id"); id != "" {
+		authorID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+			return
+		}
+		query.AuthorID = authorID
+	}
+
+	result, err := h.feedService.GetFeed(query)
+	if errors.Is(err, service.ErrTagNotFound) || errors.Is(err, service.ErrSeriesNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body []byte
+	var contentType string
+	switch path.Ext(c.Request.URL.Path) {
+	case ".rss":
+		body, err = result.RSS()
+		contentType = feed.RSSContentType
+	case ".atom":
+		body, err = result.Atom()
+		contentType = feed.AtomContentType
+	default:
+		body, err = result.JSON()
+		contentType = feed.JSONContentType
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeCacheable(c, contentType, body, result.Updated)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the multipart framing around an upload of
+// the maximum size
+const multipartOverhead = 1 << 20
+
+type MediaHandler struct {
+	mediaService *service.MediaService
+}
+
+func NewMediaHandler(mediaService *service.MediaService) *MediaHandler {
+	return &MediaHandler{mediaService: mediaService}
+}
+
+// UploadMedia stores the multipart "file" field. It answers 201 for a new
+// file and 200 with the existing media when the same file was uploaded
+// before.
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.mediaService.MaxSize()+multipartOverhead)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrMediaTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a multipart \"file\" field is required"})
+		return
+	}
+	defer file.Close()
+
+	media, created, err := h.mediaService.Upload(service.UploadMediaCommand{
+		Filename:   header.Filename,
+		Data:       file,
+		UploaderID: eventMeta(c).ActorID,
+	})
+	if err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, media)
+}
+
+func (h *MediaHandler) ListMedia(c *gin.Context) {
+	limit, offset := parsePagination(c)
+	media, err := h.mediaService.ListMedia(limit, offset)
+	if err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// GetMedia describes a file, including the posts that reference it
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+
+	media, err := h.mediaService.GetMedia(id)
+	if err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// DeleteMedia removes a file unless a post still references it
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+
+	if err := h.mediaService.DeleteMedia(id); err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+// ServeMedia serves /media/:key. Keys are derived from the file's hash, so
+// responses never change and may be cached indefinitely.
+func (h *MediaHandler) ServeMedia(c *gin.Context) {
+	object, err := h.mediaService.Open(c.Param("key"))
+	if err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer object.Close()
+
+	etag := `"` + object.Hash + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object, map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"ETag":                   etag,
+		"X-Content-Type-Options": "nosniff",
+		// Keep uploaded PDFs and the like from running in the site's origin
+		"Content-Security-Policy": "default-src 'none'; img-src 'self'; media-src 'self'; style-src 'unsafe-inline'",
+	})
+}
+
+// mediaErrorStatus maps media service errors to HTTP status codes
+func mediaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrMediaNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrMediaTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrMediaInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
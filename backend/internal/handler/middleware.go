@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"blog-platform/internal/models"
+	"blog-platform/internal/service"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carries the correlation ID of a request. It is taken
+	// from the caller when present and echoed in every response.
+	RequestIDHeader = "X-Request-ID"
+	// ActorIDHeader identifies the user acting on the request
+	ActorIDHeader = "X-Actor-ID"
+	// DeviceTokenHeader identifies an anonymous reader by a token the
+	// client generates once and keeps
+	DeviceTokenHeader = "X-Device-Token"
+)
+
+// RequestID makes sure every request has a correlation ID, which is
+// recorded with the events the request causes
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+
+		c.Set(RequestIDHeader, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// eventMeta returns the actor and correlation ID to record with events
+// caused by the request
+func eventMeta(c *gin.Context) models.EventMeta {
+	actorID, _ := strconv.ParseInt(c.GetHeader(ActorIDHeader), 10, 64)
+	return models.EventMeta{
+		ActorID:       actorID,
+		CorrelationID: c.GetString(RequestIDHeader),
+	}
+}
+
+// currentReader identifies the reader of a request by account, falling
+// back to the device token
+func currentReader(c *gin.Context) service.Reader {
+	userID, _ := strconv.ParseInt(c.GetHeader(ActorIDHeader), 10, 64)
+	return service.Reader{UserID: userID, DeviceToken: c.GetHeader(DeviceTokenHeader)}
+}
@@ -0,0 +1,412 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newsletterPage is what a reader sees after following a confirmation or
+// unsubscribe link. Acting on it takes a POST so that link scanners
+// opening the link change nothing.
+var newsletterPage = template.Must(template.New("newsletter").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto;">
+{{if .Message}}<p>{{.Message}}</p>
+{{else}}<form method="post">
+<p>{{.Question}}</p>
+<button type="submit">{{.Title}}</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel, _ = base64.StdEncoding.DecodeString("R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7")
+
+// NewsletterHandler serves newsletter lists, campaigns, the links in
+// newsletter email and the mail provider's webhooks
+type NewsletterHandler struct {
+	newsletterService *service.NewsletterService
+}
+
+func NewNewsletterHandler(newsletterService *service.NewsletterService) *NewsletterHandler {
+	return &NewsletterHandler{newsletterService: newsletterService}
+}
+
+func (h *NewsletterHandler) ListLists(c *gin.Context) {
+	lists, err := h.newsletterService.ListLists()
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+func (h *NewsletterHandler) CreateList(c *gin.Context) {
+	var cmd service.CreateNewsletterListCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.newsletterService.CreateList(cmd)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+func (h *NewsletterHandler) GetList(c *gin.Context) {
+	list, err := h.newsletterService.GetList(c.Param("slug"))
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *NewsletterHandler) DeleteList(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list ID"})
+		return
+	}
+
+	if err := h.newsletterService.DeleteList(id); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
+}
+
+// ListSubscribers lists a list's subscribers, newest first, optionally
+// filtered by ?status=
+func (h *NewsletterHandler) ListSubscribers(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	subs, err := h.newsletterService.ListSubscribers(c.Param("slug"), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
+// Subscribe starts the double opt-in: the address gets an email with a
+// confirmation link. The response is the same whether or not the address
+// was already subscribed.
+func (h *NewsletterHandler) Subscribe(c *gin.Context) {
+	var cmd service.SubscribeCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ListSlug = c.Param("slug")
+
+	if err := h.newsletterService.Subscribe(cmd); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your inbox to confirm the subscription"})
+}
+
+// ConfirmSubscriptionPage shows the page a confirmation link opens
+func (h *NewsletterHandler) ConfirmSubscriptionPage(c *gin.Context) {
+	renderNewsletterPage(c, http.StatusOK, "Confirm subscription", "Do you want to receive this newsletter?", "")
+}
+
+func (h *NewsletterHandler) ConfirmSubscription(c *gin.Context) {
+	if _, err := h.newsletterService.Confirm(c.Query("token")); err != nil {
+		renderNewsletterPage(c, newsletterErrorStatus(err), "Confirm subscription", "", err.Error())
+		return
+	}
+
+	renderNewsletterPage(c, http.StatusOK, "Confirm subscription", "", "Your subscription is confirmed.")
+}
+
+// UnsubscribePage shows the page an unsubscribe link opens
+func (h *NewsletterHandler) UnsubscribePage(c *gin.Context) {
+	renderNewsletterPage(c, http.StatusOK, "Unsubscribe", "Stop receiving this newsletter?", "")
+}
+
+// Unsubscribe takes the owner of ?token= off the list. Mail clients
+// offering one-click unsubscribe (RFC 8058) post here too.
+func (h *NewsletterHandler) Unsubscribe(c *gin.Context) {
+	campaignID, _ := strconv.ParseInt(c.Query("campaign"), 10, 64)
+
+	if _, err := h.newsletterService.Unsubscribe(c.Query("token"), campaignID); err != nil {
+		renderNewsletterPage(c, newsletterErrorStatus(err), "Unsubscribe", "", err.Error())
+		return
+	}
+
+	renderNewsletterPage(c, http.StatusOK, "Unsubscribe", "", "You have been unsubscribed.")
+}
+
+func renderNewsletterPage(c *gin.Context, status int, title, question, message string) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	newsletterPage.Execute(c.Writer, gin.H{"Title": title, "Question": question, "Message": message})
+}
+
+// TrackOpen counts an open and serves the tracking pixel. The pixel is
+// served even when counting fails, so the email never shows a broken image.
+func (h *NewsletterHandler) TrackOpen(c *gin.Context) {
+	if campaignID, err := strconv.ParseInt(c.Param("campaign"), 10, 64); err == nil {
+		if err := h.newsletterService.TrackOpen(campaignID, c.Param("token")); err != nil {
+			c.Error(err)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/gif", trackingPixel)
+}
+
+// TrackClick counts a click and redirects to ?url=
+func (h *NewsletterHandler) TrackClick(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("campaign"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	target, err := h.newsletterService.TrackClick(campaignID, c.Param("token"), c.Query("url"))
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// BounceWebhook receives bounces from the mail provider
+func (h *NewsletterHandler) BounceWebhook(c *gin.Context) {
+	var cmd service.BounceCommand
+	if !h.bindWebhook(c, &cmd) {
+		return
+	}
+
+	if err := h.newsletterService.HandleBounce(cmd); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bounce recorded"})
+}
+
+// UnsubscribeWebhook receives unsubscribes and complaints from the mail
+// provider
+func (h *NewsletterHandler) UnsubscribeWebhook(c *gin.Context) {
+	var cmd service.WebhookUnsubscribeCommand
+	if !h.bindWebhook(c, &cmd) {
+		return
+	}
+
+	if err := h.newsletterService.HandleUnsubscribe(cmd); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribe recorded"})
+}
+
+// bindWebhook verifies the signature of a webhook and decodes its body
+func (h *NewsletterHandler) bindWebhook(c *gin.Context, cmd interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	timestamp := c.GetHeader(service.HeaderWebhookTimestamp)
+	signature := c.GetHeader(service.HeaderWebhookSignature)
+	if err := h.newsletterService.VerifyWebhook(timestamp, signature, body); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return false
+	}
+
+	if err := json.Unmarshal(body, cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *NewsletterHandler) ListCampaigns(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	campaigns, err := h.newsletterService.ListCampaigns(limit, offset)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+func (h *NewsletterHandler) CreateCampaign(c *gin.Context) {
+	var cmd service.CreateCampaignCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err := h.newsletterService.CreateCampaign(cmd)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func (h *NewsletterHandler) GetCampaign(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.newsletterService.GetCampaign(id)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (h *NewsletterHandler) UpdateCampaign(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	var cmd service.UpdateCampaignCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ID = id
+
+	campaign, err := h.newsletterService.UpdateCampaign(cmd)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (h *NewsletterHandler) DeleteCampaign(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.newsletterService.DeleteCampaign(id); err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+}
+
+// ScheduleCampaign schedules a campaign for send_at, or to go out right
+// away without a body
+func (h *NewsletterHandler) ScheduleCampaign(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	var cmd service.ScheduleCampaignCommand
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&cmd); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	cmd.ID = id
+
+	campaign, err := h.newsletterService.ScheduleCampaign(cmd)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (h *NewsletterHandler) UnscheduleCampaign(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.newsletterService.UnscheduleCampaign(id)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (h *NewsletterHandler) GetCampaignStats(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.newsletterService.CampaignStats(id)
+	if err != nil {
+		c.JSON(newsletterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func campaignID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func newsletterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNewsletterListNotFound), errors.Is(err, service.ErrCampaignNotFound),
+		errors.Is(err, service.ErrInvalidSubscriberToken):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNewsletterListExists), errors.Is(err, service.ErrCampaignLocked),
+		errors.Is(err, service.ErrCampaignNotScheduled):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidWebhookSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrNewsletterNameMissing),
+		errors.Is(err, service.ErrCampaignSubjectMissing), errors.Is(err, service.ErrInvalidCampaignPosts),
+		errors.Is(err, service.ErrInvalidTrackingLink):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
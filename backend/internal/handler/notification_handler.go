@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"blog-platform/internal/models"
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler serves the follows and notification inbox of the
+// user in X-Actor-ID
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+func (h *NotificationHandler) ListFollows(c *gin.Context) {
+	follows, err := h.notificationService.ListFollows(currentReader(c).UserID)
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, follows)
+}
+
+// Follow follows the author, tag or series named by :type and :id
+func (h *NotificationHandler) Follow(c *gin.Context) {
+	cmd, ok := followCommand(c)
+	if !ok {
+		return
+	}
+
+	follow, err := h.notificationService.Follow(cmd)
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, follow)
+}
+
+func (h *NotificationHandler) Unfollow(c *gin.Context) {
+	cmd, ok := followCommand(c)
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.Unfollow(cmd); err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unfollowed successfully"})
+}
+
+func followCommand(c *gin.Context) (service.FollowCommand, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return service.FollowCommand{}, false
+	}
+
+	return service.FollowCommand{
+		UserID: currentReader(c).UserID,
+		Target: models.FollowTarget{Type: c.Param("type"), ID: id},
+	}, true
+}
+
+// ListNotifications returns the inbox, newest first; ?unread=true leaves
+// out notifications already read
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	inbox, err := h.notificationService.ListNotifications(service.ListNotificationsQuery{
+		UserID:     currentReader(c).UserID,
+		UnreadOnly: c.Query("unread") == "true",
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, inbox)
+}
+
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	count, err := h.notificationService.UnreadCount(currentReader(c).UserID)
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	count, err := h.notificationService.MarkRead(currentReader(c).UserID, id)
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	marked, err := h.notificationService.MarkAllRead(currentReader(c).UserID)
+	if err != nil {
+		c.JSON(notificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked, "unread_count": 0})
+}
+
+func notificationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAccountRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrFollowTargetNotFound), errors.Is(err, service.ErrNotificationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidFollowTarget):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
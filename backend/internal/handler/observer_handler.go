@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ObserverHandler struct {
+	postService *service.PostService
+}
+
+func NewObserverHandler(postService *service.PostService) *ObserverHandler {
+	return &ObserverHandler{postService: postService}
+}
+
+// GetMetrics reports deliveries, failures and latency per observer since startup
+func (h *ObserverHandler) GetMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, h.postService.Metrics())
+}
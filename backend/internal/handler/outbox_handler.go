@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OutboxHandler struct {
+	dispatcher *service.OutboxDispatcher
+}
+
+func NewOutboxHandler(dispatcher *service.OutboxDispatcher) *OutboxHandler {
+	return &OutboxHandler{dispatcher: dispatcher}
+}
+
+// ListPending returns undelivered events with their attempt counts
+func (h *OutboxHandler) ListPending(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	events, total, err := h.dispatcher.PendingEvents(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending": total,
+		"events":  events,
+	})
+}
+
+func (h *OutboxHandler) ListDeadLetters(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	letters, err := h.dispatcher.DeadLetters(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, letters)
+}
+
+// ReplayDeadLetter queues a dead letter for delivery again. Observers that
+// already received the event are skipped.
+func (h *OutboxHandler) ReplayDeadLetter(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter ID"})
+		return
+	}
+
+	if err := h.dispatcher.Replay(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDeadLetterNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued for redelivery"})
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"blog-platform/internal/service"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,23 +15,23 @@ type PostHandler struct {
 	commandService *service.CommandService
 	queryService   *service.QueryService
 	contentFactory *service.ContentFactory
-	postService    *service.PostService
 	searchService  *service.SearchService
+	viewTracker    *service.ViewTracker
 }
 
 func NewPostHandler(
 	cmdService *service.CommandService,
 	queryService *service.QueryService,
 	contentFactory *service.ContentFactory,
-	postService *service.PostService,
 	searchService *service.SearchService,
+	viewTracker *service.ViewTracker,
 ) *PostHandler {
 	return &PostHandler{
 		commandService: cmdService,
 		queryService:   queryService,
 		contentFactory: contentFactory,
-		postService:    postService,
 		searchService:  searchService,
+		viewTracker:    viewTracker,
 	}
 }
 
@@ -38,6 +41,7 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	createCmd.Meta = eventMeta(c)
 
 	// Use the content factory to create appropriate content type
 	content, err := h.contentFactory.CreateContent(createCmd.Type, map[string]interface{}{
@@ -58,21 +62,38 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	// Create the post
 	post, err := h.commandService.CreatePost(createCmd)
+	if errors.Is(err, service.ErrCategoryNotFound) || errors.Is(err, service.ErrInvalidSchedule) ||
+		errors.Is(err, service.ErrInvalidFormat) || errors.Is(err, service.ErrInvalidURL) ||
+		errors.Is(err, service.ErrUnsupportedLocale) || errors.Is(err, service.ErrTranslationNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrTranslationExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Notify observers about the new post
-	h.postService.Notify(service.PostEvent{
-		EventType: "post_created",
-		PostID:    post.ID,
-		Data:      post,
-	})
+	h.respondWithPost(c, http.StatusCreated, post.ID)
+}
 
-	c.JSON(http.StatusCreated, post)
+// respondWithPost writes the read model of a post, including its tags and
+// categories, after a write
+func (h *PostHandler) respondWithPost(c *gin.Context, status int, id int64) {
+	post, err := h.queryService.GetPost(service.GetPostQuery{ID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, post)
 }
 
+// GetPost serves a post, or the translation of it that best matches
+// ?lang= or the Accept-Language header
 func (h *PostHandler) GetPost(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -80,7 +101,12 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.queryService.GetPost(service.GetPostQuery{ID: id})
+	post, err := h.queryService.GetPost(service.GetPostQuery{
+		ID:             id,
+		Lang:           c.Query("lang"),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+	})
+	c.Header("Vary", "Accept-Language")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -91,35 +117,95 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
+	h.trackView(c, post)
+	c.Header("Content-Language", post.Locale)
 	c.JSON(http.StatusOK, post)
 }
 
-func (h *PostHandler) ListPosts(c *gin.Context) {
-	query := service.ListPostsQuery{
-		Status: c.Query("status"),
-		Type:   c.Query("type"),
-		Limit:  10, // Default limit
+// GetPostBySlug serves a post by permalink. Retired slugs answer with a
+// 301 pointing at the post's current slug.
+func (h *PostHandler) GetPostBySlug(c *gin.Context) {
+	post, redirected, err := h.queryService.GetPostBySlug(service.GetPostBySlugQuery{Slug: c.Param("slug")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
-			query.Limit = limit
-		}
+	if post == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
 	}
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
-			query.Offset = offset
-		}
+	if redirected {
+		location := strings.TrimSuffix(c.Request.URL.Path, c.Param("slug")) + url.PathEscape(post.Slug)
+		c.Header("Location", location)
+		c.JSON(http.StatusMovedPermanently, gin.H{"slug": post.Slug, "location": location})
+		return
+	}
+
+	h.trackView(c, post)
+	c.JSON(http.StatusOK, post)
+}
+
+// trackView counts a reader's view of a published post
+func (h *PostHandler) trackView(c *gin.Context, post *service.PostViewModel) {
+	if post.Status == "published" {
+		h.viewTracker.TrackView(post.ID, c.ClientIP(), c.Request.UserAgent())
+	}
+}
+
+// ListPosts lists posts; a language preference lists each post once, in
+// the best matching locale
+func (h *PostHandler) ListPosts(c *gin.Context) {
+	limit, offset := parsePagination(c)
+	query := service.ListPostsQuery{
+		Status:         c.Query("status"),
+		Type:           c.Query("type"),
+		Lang:           c.Query("lang"),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Limit:          limit,
+		Offset:         offset,
 	}
 
 	posts, err := h.queryService.ListPosts(query)
+	c.Header("Vary", "Accept-Language")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, posts)
+	c.JSON(http.StatusOK, service.Summaries(posts))
+}
+
+// ListTrendingPosts lists posts by time-decayed views over ?window= (24h,
+// 7d or 30d), optionally filtered by ?type=
+func (h *PostHandler) ListTrendingPosts(c *gin.Context) {
+	h.listRanked(c, h.queryService.ListTrendingPosts)
+}
+
+// ListPopularPosts lists posts by views over ?window=, optionally filtered by ?type=
+func (h *PostHandler) ListPopularPosts(c *gin.Context) {
+	h.listRanked(c, h.queryService.ListPopularPosts)
+}
+
+func (h *PostHandler) listRanked(c *gin.Context, list func(service.ListRankedPostsQuery) ([]service.PostViewModel, error)) {
+	limit, offset := parsePagination(c)
+	posts, err := list(service.ListRankedPostsQuery{
+		Window: c.Query("window"),
+		Type:   c.Query("type"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if errors.Is(err, service.ErrInvalidRankingWindow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, service.Summaries(posts))
 }
 
 func (h *PostHandler) UpdatePost(c *gin.Context) {
@@ -136,22 +222,26 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 	}
 
 	updateCmd.ID = id
+	updateCmd.Meta = eventMeta(c)
 
 	// Update the post
 	post, err := h.commandService.UpdatePost(updateCmd)
+	if errors.Is(err, service.ErrCategoryNotFound) || errors.Is(err, service.ErrInvalidSchedule) ||
+		errors.Is(err, service.ErrInvalidFormat) || errors.Is(err, service.ErrInvalidURL) ||
+		errors.Is(err, service.ErrUnsupportedLocale) || errors.Is(err, service.ErrTranslationNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrTranslationExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Notify observers about the updated post
-	h.postService.Notify(service.PostEvent{
-		EventType: "post_updated",
-		PostID:    post.ID,
-		Data:      post,
-	})
-
-	c.JSON(http.StatusOK, post)
+	h.respondWithPost(c, http.StatusOK, post.ID)
 }
 
 func (h *PostHandler) DeletePost(c *gin.Context) {
@@ -161,7 +251,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	deleteCmd := service.DeletePostCommand{ID: id}
+	deleteCmd := service.DeletePostCommand{ID: id, Meta: eventMeta(c)}
 
 	// Delete the post
 	if err := h.commandService.DeletePost(deleteCmd); err != nil {
@@ -169,13 +259,6 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	// Notify observers about the deleted post
-	h.postService.Notify(service.PostEvent{
-		EventType: "post_deleted",
-		PostID:    id,
-		Data:      nil,
-	})
-
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
@@ -200,7 +283,27 @@ func (h *PostHandler) SearchPosts(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"query":           query,
 		"count":           len(results),
-		"results":         results,
+		"results":         service.Summaries(results),
 		"circuit_breaker": h.searchService.GetCircuitBreakerState(),
 	})
 }
+
+// parsePagination reads the limit and offset query parameters, defaulting
+// to the first 10 results and capping the limit at 100
+func parsePagination(c *gin.Context) (limit, offset int) {
+	limit = 10 // Default limit
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	return limit, offset
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReactionHandler serves reactions and bookmarks. The reader is the
+// account in X-Actor-ID, or else the anonymous device in X-Device-Token.
+type ReactionHandler struct {
+	reactionService *service.ReactionService
+}
+
+func NewReactionHandler(reactionService *service.ReactionService) *ReactionHandler {
+	return &ReactionHandler{reactionService: reactionService}
+}
+
+// GetReactions returns a post's reaction counts and the reader's own
+// reactions
+func (h *ReactionHandler) GetReactions(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	summary, err := h.reactionService.GetReactions(postID, currentReader(c))
+	if err != nil {
+		c.JSON(reactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// AddReaction reacts to a post; reacting twice has no further effect
+func (h *ReactionHandler) AddReaction(c *gin.Context) {
+	h.react(c, h.reactionService.AddReaction)
+}
+
+func (h *ReactionHandler) RemoveReaction(c *gin.Context) {
+	h.react(c, h.reactionService.RemoveReaction)
+}
+
+func (h *ReactionHandler) react(c *gin.Context, apply func(service.ReactCommand) (*service.ReactionSummary, error)) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	summary, err := apply(service.ReactCommand{
+		PostID:   postID,
+		Reader:   currentReader(c),
+		Reaction: c.Param("reaction"),
+	})
+	if err != nil {
+		c.JSON(reactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// AddBookmark bookmarks a post; bookmarking twice has no further effect
+func (h *ReactionHandler) AddBookmark(c *gin.Context) {
+	h.bookmark(c, h.reactionService.AddBookmark)
+}
+
+func (h *ReactionHandler) RemoveBookmark(c *gin.Context) {
+	h.bookmark(c, h.reactionService.RemoveBookmark)
+}
+
+func (h *ReactionHandler) bookmark(c *gin.Context, apply func(service.BookmarkCommand) (*service.ReactionSummary, error)) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	summary, err := apply(service.BookmarkCommand{PostID: postID, Reader: currentReader(c)})
+	if err != nil {
+		c.JSON(reactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ListBookmarks returns the reader's bookmarked posts
+func (h *ReactionHandler) ListBookmarks(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	posts, err := h.reactionService.ListBookmarks(currentReader(c), limit, offset)
+	if err != nil {
+		c.JSON(reactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, service.Summaries(posts))
+}
+
+func reactionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrReaderMissing), errors.Is(err, service.ErrInvalidDeviceToken),
+		errors.Is(err, service.ErrUnknownReaction):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
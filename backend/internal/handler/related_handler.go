@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RelatedPostsHandler struct {
+	relatedService *service.RelatedPostsService
+}
+
+func NewRelatedPostsHandler(relatedService *service.RelatedPostsService) *RelatedPostsHandler {
+	return &RelatedPostsHandler{relatedService: relatedService}
+}
+
+// GetRelatedPosts lists the published posts most similar to a post, up to
+// ?limit= (default 5, at most 20)
+func (h *RelatedPostsHandler) GetRelatedPosts(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	posts, err := h.relatedService.GetRelatedPosts(service.GetRelatedPostsQuery{ID: id, Limit: limit})
+	if errors.Is(err, service.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, posts)
+}
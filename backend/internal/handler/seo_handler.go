@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SEOHandler struct {
+	seoService *service.SEOService
+}
+
+func NewSEOHandler(seoService *service.SEOService) *SEOHandler {
+	return &SEOHandler{seoService: seoService}
+}
+
+// GetPostHead returns the <head> metadata of a post's page as JSON, or with
+// ?format=html only the tags to embed
+func (h *SEOHandler) GetPostHead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	head, err := h.seoService.GetPostHead(service.GetPostHeadQuery{ID: id})
+	if errors.Is(err, service.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "html" {
+		writeCacheable(c, "text/html; charset=utf-8", []byte(head.HTML), head.UpdatedAt)
+		return
+	}
+
+	body, err := json.Marshal(head)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeCacheable(c, "application/json; charset=utf-8", body, head.UpdatedAt)
+}
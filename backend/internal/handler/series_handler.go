@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SeriesHandler struct {
+	seriesService *service.SeriesService
+}
+
+func NewSeriesHandler(seriesService *service.SeriesService) *SeriesHandler {
+	return &SeriesHandler{seriesService: seriesService}
+}
+
+func (h *SeriesHandler) ListSeries(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	series, err := h.seriesService.ListSeries(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var cmd service.CreateSeriesCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := h.seriesService.CreateSeries(cmd)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
+
+// GetSeries returns a series with its posts in order; ?status= narrows
+// the posts, e.g. to those published
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	series, err := h.seriesService.GetSeries(c.Param("slug"), c.Query("status"))
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var cmd service.UpdateSeriesCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ID = id
+
+	series, err := h.seriesService.UpdateSeries(cmd)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	if err := h.seriesService.DeleteSeries(id); err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted successfully"})
+}
+
+// SetSeriesPosts replaces the posts of a series with post_ids, in order
+func (h *SeriesHandler) SetSeriesPosts(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var cmd service.SetSeriesPostsCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.SeriesID = id
+
+	series, err := h.seriesService.SetSeriesPosts(cmd)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// AddSeriesPost inserts post_id at position, or appends it without one
+func (h *SeriesHandler) AddSeriesPost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var cmd service.AddSeriesPostCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.SeriesID = id
+
+	series, err := h.seriesService.AddSeriesPost(cmd)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *SeriesHandler) RemoveSeriesPost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	series, err := h.seriesService.RemoveSeriesPost(id, postID)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func seriesErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrSeriesNotFound), errors.Is(err, service.ErrPostNotInSeries):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrSeriesExists), errors.Is(err, service.ErrPostInOtherSeries):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrSeriesTitleMissing), errors.Is(err, service.ErrSeriesPostNotFound),
+		errors.Is(err, service.ErrSeriesPostRepeated):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"blog-platform/pkg/sitemap"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SitemapHandler struct {
+	sitemapService *service.SitemapService
+}
+
+func NewSitemapHandler(sitemapService *service.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService}
+}
+
+// GetSitemap serves /sitemap.xml, which is a sitemap index once the site
+// outgrows a single sitemap
+func (h *SitemapHandler) GetSitemap(c *gin.Context) {
+	base := strings.TrimSuffix(requestURL(c), "/sitemap.xml") + "/sitemaps/"
+	document, err := h.sitemapService.GetSitemap(base)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeCacheable(c, sitemap.ContentType, document.Body, document.LastModified)
+}
+
+// GetSitemapPage serves /sitemaps/:page, e.g. /sitemaps/2.xml
+func (h *SitemapHandler) GetSitemapPage(c *gin.Context) {
+	n, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrSitemapPageNotFound.Error()})
+		return
+	}
+
+	document, err := h.sitemapService.GetSitemapPage(n)
+	if errors.Is(err, service.ErrSitemapPageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeCacheable(c, sitemap.ContentType, document.Body, document.LastModified)
+}
+
+// GetRobots serves /robots.txt
+func (h *SitemapHandler) GetRobots(c *gin.Context) {
+	sitemapURL := strings.TrimSuffix(requestURL(c), "/robots.txt") + "/sitemap.xml"
+	writeCacheable(c, "text/plain; charset=utf-8", []byte(h.sitemapService.Robots(sitemapURL)), time.Time{})
+}
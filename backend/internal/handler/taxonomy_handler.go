@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"blog-platform/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxonomyHandler struct {
+	taxonomyService *service.TaxonomyService
+	queryService    *service.QueryService
+}
+
+func NewTaxonomyHandler(taxonomyService *service.TaxonomyService, queryService *service.QueryService) *TaxonomyHandler {
+	return &TaxonomyHandler{
+		taxonomyService: taxonomyService,
+		queryService:    queryService,
+	}
+}
+
+// Tags
+
+func (h *TaxonomyHandler) ListTags(c *gin.Context) {
+	tags, err := h.taxonomyService.ListTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *TaxonomyHandler) CreateTag(c *gin.Context) {
+	var cmd service.CreateTagCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.taxonomyService.CreateTag(cmd)
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (h *TaxonomyHandler) GetTag(c *gin.Context) {
+	tag, err := h.taxonomyService.GetTagBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (h *TaxonomyHandler) RenameTag(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	var cmd service.RenameTagCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ID = id
+
+	tag, err := h.taxonomyService.RenameTag(cmd)
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// MergeTags folds the tag in the URL into the tag given as target_id
+func (h *TaxonomyHandler) MergeTags(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	var cmd service.MergeTagsCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.SourceID = id
+
+	tag, err := h.taxonomyService.MergeTags(cmd)
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (h *TaxonomyHandler) DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	if err := h.taxonomyService.DeleteTag(id); err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+func (h *TaxonomyHandler) ListPostsByTag(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	posts, err := h.queryService.ListPostsByTag(service.ListPostsByTagQuery{
+		TagSlug: c.Param("slug"),
+		Status:  c.Query("status"),
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, service.Summaries(posts))
+}
+
+// Categories
+
+// ListCategories returns the full category tree
+func (h *TaxonomyHandler) ListCategories(c *gin.Context) {
+	tree, err := h.taxonomyService.GetCategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+func (h *TaxonomyHandler) CreateCategory(c *gin.Context) {
+	var cmd service.CreateCategoryCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.taxonomyService.CreateCategory(cmd)
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func (h *TaxonomyHandler) GetCategory(c *gin.Context) {
+	category, err := h.taxonomyService.GetCategoryBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *TaxonomyHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var cmd service.UpdateCategoryCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.ID = id
+
+	category, err := h.taxonomyService.UpdateCategory(cmd)
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *TaxonomyHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.taxonomyService.DeleteCategory(id); err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// ListPostsByCategory includes posts from subcategories unless
+// ?include_descendants=false is given
+func (h *TaxonomyHandler) ListPostsByCategory(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	posts, err := h.queryService.ListPostsByCategory(service.ListPostsByCategoryQuery{
+		CategorySlug:       c.Param("slug"),
+		IncludeDescendants: c.DefaultQuery("include_descendants", "true") != "false",
+		Status:             c.Query("status"),
+		Limit:              limit,
+		Offset:             offset,
+	})
+	if err != nil {
+		c.JSON(taxonomyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, service.Summaries(posts))
+}
+
+// taxonomyErrorStatus maps taxonomy service errors to HTTP status codes
+func taxonomyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound), errors.Is(err, service.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrTagExists), errors.Is(err, service.ErrCategoryExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidCategoryTree), errors.Is(err, service.ErrTaxonomyNameMissing),
+		errors.Is(err, service.ErrSelfMerge):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
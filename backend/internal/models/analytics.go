@@ -0,0 +1,238 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"time"
+
+	"blog-platform/pkg/hyperloglog"
+)
+
+// DailyPostStats is the traffic of one post on one UTC day
+type DailyPostStats struct {
+	PostID   int64               `json:"post_id" db:"post_id"`
+	Day      string              `json:"day" db:"day"` // YYYY-MM-DD
+	Views    int64               `json:"views" db:"views"`
+	Comments int64               `json:"comments" db:"comments"`
+	Visitors *hyperloglog.Sketch `json:"-" db:"visitors"` // distinct visitor hashes
+}
+
+// HourLayout is the format of the hours views are bucketed by (UTC)
+const HourLayout = "2006-01-02T15"
+
+// HourlyPostViews is the number of views of one post in one UTC hour.
+// Rankings are computed from these; they are kept for 31 days.
+type HourlyPostViews struct {
+	PostID int64  `json:"post_id" db:"post_id"`
+	Hour   string `json:"hour" db:"hour"` // YYYY-MM-DDTHH
+	Views  int64  `json:"views" db:"views"`
+}
+
+// RankingWindows are the periods trending and popular posts are ranked
+// over, by name
+var RankingWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// PostRanking is a post's place in the trending and popular lists of one
+// ranking window
+type PostRanking struct {
+	Window string  `json:"window" db:"period"`
+	PostID int64   `json:"post_id" db:"post_id"`
+	Score  float64 `json:"score" db:"score"` // time-decayed views
+	Views  int64   `json:"views" db:"views"` // views within the window
+}
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// VisitorSalt returns the secret mixed into visitor hashes, creating it on
+// first use. Without it a visitor hash could be reversed by hashing every
+// IP address.
+func (r *AnalyticsRepository) VisitorSalt() ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.db.Exec(`INSERT OR IGNORE INTO analytics_settings (key, value) VALUES ('visitor_salt', ?)`, salt); err != nil {
+		return nil, err
+	}
+
+	err := r.db.QueryRow(`SELECT value FROM analytics_settings WHERE key = 'visitor_salt'`).Scan(&salt)
+	return salt, err
+}
+
+// MergeDailyStats adds buffered counts to the daily aggregates in one
+// transaction. Visitor sketches are merged, so flushing the same visitor
+// twice does not count them twice. Stats for deleted posts are dropped.
+func (r *AnalyticsRepository) MergeDailyStats(stats []*DailyPostStats) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delta := range stats {
+		visitors := hyperloglog.New()
+		var existing []byte
+		err := tx.QueryRow(`SELECT visitors FROM post_stats_daily WHERE post_id = ? AND day = ?`,
+			delta.PostID, delta.Day).Scan(&existing)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if len(existing) > 0 {
+			if err := visitors.UnmarshalBinary(existing); err != nil {
+				return err
+			}
+		}
+		if delta.Visitors != nil {
+			visitors.Merge(delta.Visitors)
+		}
+
+		encoded, err := visitors.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO post_stats_daily (post_id, day, views, comments, visitors)
+		          SELECT ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM posts WHERE id = ?)
+		          ON CONFLICT (post_id, day) DO UPDATE SET
+		              views = views + excluded.views,
+		              comments = comments + excluded.comments,
+		              visitors = excluded.visitors`
+		_, err = tx.Exec(query, delta.PostID, delta.Day, delta.Views, delta.Comments, encoded, delta.PostID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddHourlyViews adds buffered view counts to the hourly buckets. Views of
+// deleted posts are dropped.
+func (r *AnalyticsRepository) AddHourlyViews(views []*HourlyPostViews) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO post_views_hourly (post_id, hour, views)
+	          SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM posts WHERE id = ?)
+	          ON CONFLICT (post_id, hour) DO UPDATE SET views = views + excluded.views`
+	for _, v := range views {
+		if _, err := tx.Exec(query, v.PostID, v.Hour, v.Views, v.PostID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindHourlyViews returns the hourly views of published posts from the
+// given hour on
+func (r *AnalyticsRepository) FindHourlyViews(since string) ([]*HourlyPostViews, error) {
+	query := `SELECT v.post_id, v.hour, v.views FROM post_views_hourly v
+	          JOIN posts p ON p.id = v.post_id
+	          WHERE v.hour >= ? AND p.status = 'published'`
+	rows, err := r.db.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*HourlyPostViews
+	for rows.Next() {
+		v := &HourlyPostViews{}
+		if err := rows.Scan(&v.PostID, &v.Hour, &v.Views); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+
+	return views, rows.Err()
+}
+
+// PruneHourlyViews deletes the hourly buckets before the given hour
+func (r *AnalyticsRepository) PruneHourlyViews(before string) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM post_views_hourly WHERE hour < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReplaceRankings swaps in a freshly computed ranking for a window, so
+// readers see either the old or the new ranking and never a mix
+func (r *AnalyticsRepository) ReplaceRankings(window string, rankings []*PostRanking, refreshedAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM post_rankings WHERE period = ?`, window); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO post_rankings (period, post_id, score, views, refreshed_at)
+	          SELECT ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM posts WHERE id = ?)`
+	for _, ranking := range rankings {
+		_, err := tx.Exec(query, window, ranking.PostID, ranking.Score, ranking.Views, refreshedAt.UTC(), ranking.PostID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindPostStats returns the daily stats of a post between two days, inclusive
+func (r *AnalyticsRepository) FindPostStats(postID int64, from, to string) ([]*DailyPostStats, error) {
+	query := `SELECT post_id, day, views, comments, visitors FROM post_stats_daily
+	          WHERE post_id = ? AND day BETWEEN ? AND ? ORDER BY day`
+	return r.queryStats(query, postID, from, to)
+}
+
+// FindAuthorStats returns the daily stats of every post by an author
+// between two days, inclusive
+func (r *AnalyticsRepository) FindAuthorStats(authorID int64, from, to string) ([]*DailyPostStats, error) {
+	query := `SELECT s.post_id, s.day, s.views, s.comments, s.visitors
+	          FROM post_stats_daily s JOIN posts p ON p.id = s.post_id
+	          WHERE p.author_id = ? AND s.day BETWEEN ? AND ? ORDER BY s.day, s.post_id`
+	return r.queryStats(query, authorID, from, to)
+}
+
+func (r *AnalyticsRepository) queryStats(query string, args ...interface{}) ([]*DailyPostStats, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*DailyPostStats
+	for rows.Next() {
+		day := &DailyPostStats{Visitors: hyperloglog.New()}
+		var visitors []byte
+		if err := rows.Scan(&day.PostID, &day.Day, &day.Views, &day.Comments, &visitors); err != nil {
+			return nil, err
+		}
+		if len(visitors) > 0 {
+			if err := day.Visitors.UnmarshalBinary(visitors); err != nil {
+				return nil, err
+			}
+		}
+		stats = append(stats, day)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AnalyticsRepositoryInterface defines the contract for aggregated post traffic
+type AnalyticsRepositoryInterface interface {
+	VisitorSalt() ([]byte, error)
+	MergeDailyStats(stats []*DailyPostStats) error
+	FindPostStats(postID int64, from, to string) ([]*DailyPostStats, error)
+	FindAuthorStats(authorID int64, from, to string) ([]*DailyPostStats, error)
+	AddHourlyViews(views []*HourlyPostViews) error
+	FindHourlyViews(since string) ([]*HourlyPostViews, error)
+	PruneHourlyViews(before string) (int64, error)
+	ReplaceRankings(window string, rankings []*PostRanking, refreshedAt time.Time) error
+}
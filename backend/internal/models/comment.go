@@ -0,0 +1,239 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Comment moderation states
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentSpam     = "spam"
+	CommentRemoved  = "removed"
+)
+
+type Comment struct {
+	ID         int64     `json:"id" db:"id"`
+	PostID     int64     `json:"post_id" db:"post_id"`
+	ParentID   *int64    `json:"parent_id" db:"parent_id"` // nil for top-level comments
+	AuthorID   int64     `json:"author_id" db:"author_id"` // 0 for anonymous readers
+	AuthorName string    `json:"author_name" db:"author_name"`
+	Content    string    `json:"content" db:"content"`
+	Status     string    `json:"status" db:"status"` // pending, approved, spam, removed
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CommentEvent is the part of comment event payloads shared by
+// CommentCreated and CommentModerated. It carries the IDs observers need to
+// decide whom to notify.
+type CommentEvent struct {
+	Comment        *Comment `json:"comment"`
+	PostAuthorID   int64    `json:"post_author_id"`
+	ParentAuthorID int64    `json:"parent_author_id,omitempty"` // set for replies
+}
+
+const commentColumns = `id, post_id, parent_id, author_id, author_name, content, status, created_at, updated_at`
+
+func scanComment(row rowScanner) (*Comment, error) {
+	comment := &Comment{}
+	var parentID sql.NullInt64
+	err := row.Scan(
+		&comment.ID, &comment.PostID, &parentID, &comment.AuthorID, &comment.AuthorName,
+		&comment.Content, &comment.Status, &comment.CreatedAt, &comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := parentID.Int64
+		comment.ParentID = &id
+	}
+	return comment, nil
+}
+
+type CommentRepository struct {
+	db     *sql.DB
+	outbox *OutboxRepository
+}
+
+// NewCommentRepository creates a repository recording its events in outbox
+func NewCommentRepository(db *sql.DB, outbox *OutboxRepository) *CommentRepository {
+	return &CommentRepository{db: db, outbox: outbox}
+}
+
+// Create inserts the comment and records a comment_created event in the
+// outbox within the same transaction
+func (r *CommentRepository) Create(comment *Comment, meta EventMeta) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO comments (post_id, parent_id, author_id, author_name, content, status)
+	          VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.Exec(query, comment.PostID, nullableID(comment.ParentID), comment.AuthorID,
+		comment.AuthorName, comment.Content, comment.Status)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	comment.ID = id
+
+	selectQuery := `SELECT created_at, updated_at FROM comments WHERE id = ?`
+	if err := tx.QueryRow(selectQuery, id).Scan(&comment.CreatedAt, &comment.UpdatedAt); err != nil {
+		return err
+	}
+
+	event, err := commentEvent(tx, comment)
+	if err != nil {
+		return err
+	}
+	if err := appendOutboxEvent(tx, comment.PostID, CommentCreated{CommentEvent: event}, meta); err != nil {
+		return err
+	}
+
+	return r.outbox.commit(tx)
+}
+
+func (r *CommentRepository) FindByID(id int64) (*Comment, error) {
+	comment, err := scanComment(r.db.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return comment, err
+}
+
+// FindByPost returns the comments of a post in the given states, oldest
+// first. No states means every state.
+func (r *CommentRepository) FindByPost(postID int64, statuses []string) ([]*Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE post_id = ?`
+	queryParams := []interface{}{postID}
+
+	if len(statuses) > 0 {
+		query += ` AND status IN (` + placeholders(len(statuses)) + `)`
+		for _, status := range statuses {
+			queryParams = append(queryParams, status)
+		}
+	}
+
+	query += ` ORDER BY created_at, id`
+
+	return r.queryComments(query, queryParams...)
+}
+
+// FindByStatus lists comments across all posts, oldest first, for moderation queues
+func (r *CommentRepository) FindByStatus(status string, limit, offset int) ([]*Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE status = ?
+	          ORDER BY created_at, id LIMIT ? OFFSET ?`
+	return r.queryComments(query, status, limit, offset)
+}
+
+// UpdateStatus changes the moderation state and records a comment_moderated event
+func (r *CommentRepository) UpdateStatus(id int64, status string, meta EventMeta) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousStatus string
+	if err := tx.QueryRow(`SELECT status FROM comments WHERE id = ?`, id).Scan(&previousStatus); err != nil {
+		return err
+	}
+
+	query := `UPDATE comments SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := tx.Exec(query, status, id); err != nil {
+		return err
+	}
+
+	comment, err := scanComment(tx.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+	if err != nil {
+		return err
+	}
+
+	event, err := commentEvent(tx, comment)
+	if err != nil {
+		return err
+	}
+	moderated := CommentModerated{CommentEvent: event, PreviousStatus: previousStatus}
+	if err := appendOutboxEvent(tx, comment.PostID, moderated, meta); err != nil {
+		return err
+	}
+
+	return r.outbox.commit(tx)
+}
+
+// commentEvent looks up the authors an event about the comment concerns
+func commentEvent(tx *sql.Tx, comment *Comment) (CommentEvent, error) {
+	event := CommentEvent{Comment: comment}
+
+	err := tx.QueryRow(`SELECT author_id FROM posts WHERE id = ?`, comment.PostID).Scan(&event.PostAuthorID)
+	if err != nil {
+		return event, err
+	}
+
+	if comment.ParentID != nil {
+		err := tx.QueryRow(`SELECT author_id FROM comments WHERE id = ?`, *comment.ParentID).Scan(&event.ParentAuthorID)
+		if err != nil && err != sql.ErrNoRows {
+			return event, err
+		}
+	}
+
+	return event, nil
+}
+
+// CountApprovedByPostIDs returns the number of approved comments per post
+func (r *CommentRepository) CountApprovedByPostIDs(postIDs []int64) (map[int64]int, error) {
+	counts := make(map[int64]int)
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `SELECT post_id, COUNT(*) FROM comments
+	          WHERE status = ? AND post_id IN (` + placeholders(len(postIDs)) + `)
+	          GROUP BY post_id`
+
+	rows, err := r.db.Query(query, append([]interface{}{CommentApproved}, int64Args(postIDs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var count int
+		if err := rows.Scan(&postID, &count); err != nil {
+			return nil, err
+		}
+		counts[postID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *CommentRepository) queryComments(query string, args ...interface{}) ([]*Comment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
@@ -0,0 +1,11 @@
+package models
+
+// CommentRepositoryInterface defines the contract for comment storage
+type CommentRepositoryInterface interface {
+	Create(comment *Comment, meta EventMeta) error
+	FindByID(id int64) (*Comment, error)
+	FindByPost(postID int64, statuses []string) ([]*Comment, error)
+	FindByStatus(status string, limit, offset int) ([]*Comment, error)
+	UpdateStatus(id int64, status string, meta EventMeta) error
+	CountApprovedByPostIDs(postIDs []int64) (map[int64]int, error)
+}
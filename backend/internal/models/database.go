@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +20,396 @@ var (
 	once     sync.Once
 )
 
+// schema lists the statements run at startup. Every statement must be
+// idempotent so it can safely be applied to an existing database.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS posts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		type TEXT NOT NULL,
+		author_id INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		slug TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS post_tags (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		PRIMARY KEY (post_id, tag_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag_id);`,
+	`CREATE TABLE IF NOT EXISTS categories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		slug TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS post_categories (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+		PRIMARY KEY (post_id, category_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_categories_category ON post_categories(category_id);`,
+	`CREATE TABLE IF NOT EXISTS comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+		author_id INTEGER NOT NULL DEFAULT 0,
+		author_name TEXT NOT NULL,
+		content TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_post ON comments(post_id, status);`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_status ON comments(status, created_at);`,
+	`CREATE TABLE IF NOT EXISTS leases (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type TEXT NOT NULL,
+		post_id INTEGER NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_post ON outbox(post_id, id);`,
+	`CREATE TABLE IF NOT EXISTS outbox_deliveries (
+		event_id INTEGER NOT NULL,
+		observer TEXT NOT NULL,
+		delivered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (event_id, observer)
+	);`,
+	`CREATE TABLE IF NOT EXISTS outbox_dead_letters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		post_id INTEGER NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL,
+		last_error TEXT NOT NULL,
+		failed_observers TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		dead_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '',
+		secret TEXT NOT NULL,
+		active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		delivery_id TEXT NOT NULL UNIQUE,
+		event_type TEXT NOT NULL,
+		post_id INTEGER NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		response_body TEXT NOT NULL DEFAULT '',
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		delivered_at DATETIME
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);`,
+	`CREATE TABLE IF NOT EXISTS post_stats_daily (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		day TEXT NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		comments INTEGER NOT NULL DEFAULT 0,
+		visitors BLOB,
+		PRIMARY KEY (post_id, day)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_stats_daily_day ON post_stats_daily(day);`,
+	`CREATE TABLE IF NOT EXISTS post_views_hourly (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		hour TEXT NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (post_id, hour)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_views_hourly_hour ON post_views_hourly(hour);`,
+	`CREATE TABLE IF NOT EXISTS post_rankings (
+		period TEXT NOT NULL,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		score REAL NOT NULL,
+		views INTEGER NOT NULL,
+		refreshed_at DATETIME NOT NULL,
+		PRIMARY KEY (period, post_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_rankings_score ON post_rankings(period, score DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_rankings_views ON post_rankings(period, views DESC);`,
+	`CREATE TABLE IF NOT EXISTS analytics_settings (
+		key TEXT PRIMARY KEY,
+		value BLOB NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS post_slug_redirects (
+		old_slug TEXT PRIMARY KEY,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS series (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		slug TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		author_id INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// A post belongs to at most one series; position orders the parts from 1
+	`CREATE TABLE IF NOT EXISTS series_posts (
+		series_id INTEGER NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+		post_id INTEGER NOT NULL UNIQUE REFERENCES posts(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (series_id, post_id)
+	);`,
+	// A reader is "user:<id>" for accounts or "device:<token hash>" for
+	// anonymous readers
+	`CREATE TABLE IF NOT EXISTS reactions (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		reader TEXT NOT NULL,
+		reaction TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (post_id, reader, reaction)
+	);`,
+	// Counters kept alongside reactions and bookmarks so reads never count rows
+	`CREATE TABLE IF NOT EXISTS reaction_counts (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		reaction TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (post_id, reaction)
+	);`,
+	`CREATE TABLE IF NOT EXISTS bookmarks (
+		reader TEXT NOT NULL,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (reader, post_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_bookmarks_reader ON bookmarks(reader, created_at);`,
+	`CREATE TABLE IF NOT EXISTS bookmark_counts (
+		post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+		count INTEGER NOT NULL DEFAULT 0
+	);`,
+	// target_type is author, tag or series; target_id is the user, tag or
+	// series followed
+	`CREATE TABLE IF NOT EXISTS follows (
+		user_id INTEGER NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, target_type, target_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_follows_target ON follows(target_type, target_id);`,
+	// One notification per user, kind, post and comment, so redelivered
+	// events and overlapping follows notify once
+	`CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		comment_id INTEGER NOT NULL DEFAULT 0,
+		source_type TEXT NOT NULL DEFAULT '',
+		source_id INTEGER NOT NULL DEFAULT 0,
+		read_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, kind, post_id, comment_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, read_at, id);`,
+	`CREATE TABLE IF NOT EXISTS email_preferences (
+		user_id INTEGER PRIMARY KEY,
+		email TEXT NOT NULL DEFAULT '',
+		new_posts BOOLEAN NOT NULL DEFAULT 1,
+		comment_replies BOOLEAN NOT NULL DEFAULT 1,
+		weekly_digest BOOLEAN NOT NULL DEFAULT 0,
+		unsubscribe_token TEXT NOT NULL UNIQUE,
+		last_digest_at DATETIME,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// dedupe_key makes queuing the same email twice a no-op
+	`CREATE TABLE IF NOT EXISTS email_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		kind TEXT NOT NULL,
+		dedupe_key TEXT NOT NULL UNIQUE,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		text_body TEXT NOT NULL,
+		html_body TEXT NOT NULL,
+		headers TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_email_queue_due ON email_queue(status, next_attempt_at);`,
+	`CREATE TABLE IF NOT EXISTS newsletter_lists (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		slug TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// token identifies the subscriber in confirmation, unsubscribe and
+	// tracking links
+	`CREATE TABLE IF NOT EXISTS newsletter_subscribers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		list_id INTEGER NOT NULL REFERENCES newsletter_lists(id) ON DELETE CASCADE,
+		email TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'pending',
+		token TEXT NOT NULL UNIQUE,
+		bounce_count INTEGER NOT NULL DEFAULT 0,
+		confirmed_at DATETIME,
+		unsubscribed_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (list_id, email)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_newsletter_subscribers_email ON newsletter_subscribers(email);`,
+	`CREATE TABLE IF NOT EXISTS campaigns (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		list_id INTEGER NOT NULL REFERENCES newsletter_lists(id) ON DELETE CASCADE,
+		subject TEXT NOT NULL,
+		intro TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'draft',
+		scheduled_at DATETIME,
+		sent_at DATETIME,
+		recipient_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_campaigns_due ON campaigns(status, scheduled_at);`,
+	`CREATE TABLE IF NOT EXISTS campaign_posts (
+		campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (campaign_id, post_id)
+	);`,
+	// One row per subscriber a campaign went to, holding what they did with it
+	`CREATE TABLE IF NOT EXISTS campaign_recipients (
+		campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+		subscriber_id INTEGER NOT NULL REFERENCES newsletter_subscribers(id) ON DELETE CASCADE,
+		opens INTEGER NOT NULL DEFAULT 0,
+		clicks INTEGER NOT NULL DEFAULT 0,
+		opened_at DATETIME,
+		clicked_at DATETIME,
+		bounced_at DATETIME,
+		unsubscribed_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (campaign_id, subscriber_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_campaign_recipients_subscriber ON campaign_recipients(subscriber_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS campaign_links (
+		campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		clicks INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (campaign_id, url)
+	);`,
+	`CREATE TABLE IF NOT EXISTS media (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		hash TEXT NOT NULL UNIQUE,
+		storage_key TEXT NOT NULL,
+		filename TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		uploader_id INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS media_variants (
+		media_id INTEGER NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		storage_key TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		PRIMARY KEY (media_id, name)
+	);`,
+	`CREATE TABLE IF NOT EXISTS post_media (
+		post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		media_id INTEGER NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+		PRIMARY KEY (post_id, media_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_post_media_media ON post_media(media_id);`,
+}
+
+// columns lists columns added to existing tables after their creation.
+// They are applied before the indexes that depend on them.
+var columns = []struct {
+	table      string
+	name       string
+	definition string
+}{
+	{"posts", "slug", "TEXT"},
+	{"posts", "publish_at", "DATETIME"},
+	{"posts", "unpublish_at", "DATETIME"},
+	// Posts written before formats existed were shown as plain text
+	{"posts", "format", "TEXT NOT NULL DEFAULT 'plaintext'"},
+	{"posts", "cover_image", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "meta_title", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "meta_description", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "canonical_url", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "social_image", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "noindex", "BOOLEAN NOT NULL DEFAULT 0"},
+	// Backfilled with the default locale at startup
+	{"posts", "locale", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "translation_group", "INTEGER"},
+	{"outbox", "schema_version", "INTEGER NOT NULL DEFAULT 0"},
+	{"outbox", "actor_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"outbox", "correlation_id", "TEXT NOT NULL DEFAULT ''"},
+	// Set once every observer has the event; NULL while pending
+	{"outbox", "completed_at", "DATETIME"},
+	{"outbox_dead_letters", "schema_version", "INTEGER NOT NULL DEFAULT 0"},
+	{"outbox_dead_letters", "actor_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"outbox_dead_letters", "correlation_id", "TEXT NOT NULL DEFAULT ''"},
+	// Set on newsletter email instead of user_id
+	{"email_queue", "campaign_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"email_queue", "subscriber_id", "INTEGER NOT NULL DEFAULT 0"},
+	// The outbox event a delivery was queued for; 0 for redeliveries
+	{"webhook_deliveries", "event_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"campaigns", "attempts", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// indexes lists indexes over the columns above
+var indexes = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_slug ON posts(slug);`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts(publish_at) WHERE publish_at IS NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_unpublish_at ON posts(unpublish_at) WHERE unpublish_at IS NOT NULL;`,
+	// One post per locale in a translation group
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_translation ON posts(translation_group, locale) WHERE translation_group IS NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_completed ON outbox(completed_at) WHERE completed_at IS NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_email_queue_campaign ON email_queue(campaign_id) WHERE campaign_id != 0;`,
+	`CREATE INDEX IF NOT EXISTS idx_email_queue_subscriber ON email_queue(subscriber_id) WHERE subscriber_id != 0;`,
+	// One delivery per event and webhook, so queuing an event again is a no-op
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(event_id, webhook_id) WHERE event_id != 0;`,
+}
+
 func GetDatabaseInstance() *Database {
 	once.Do(func() {
 		// Get database path from environment variable or use default
@@ -27,29 +418,7 @@ func GetDatabaseInstance() *Database {
 			dbPath = "./blog.db"
 		}
 
-		db, err := sql.Open("sqlite3", dbPath)
-		if err != nil {
-			panic(err)
-		}
-
-		db.SetMaxOpenConns(1) // SQLite works best with a single connection
-		db.SetMaxIdleConns(1)
-		db.SetConnMaxLifetime(5 * time.Minute)
-
-		// Create posts table if it doesn't exist
-		createTableSQL := `
-		CREATE TABLE IF NOT EXISTS posts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			title TEXT NOT NULL,
-			content TEXT NOT NULL,
-			type TEXT NOT NULL,
-			author_id INTEGER NOT NULL,
-			status TEXT NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);`
-
-		_, err = db.Exec(createTableSQL)
+		db, err := OpenDatabase(dbPath)
 		if err != nil {
 			panic(err)
 		}
@@ -60,3 +429,84 @@ func GetDatabaseInstance() *Database {
 	})
 	return instance
 }
+
+// OpenDatabase opens the SQLite database at dsn and brings its schema up
+// to date. GetDatabaseInstance uses it for the shared database; tests use
+// it for private in-memory ones.
+func OpenDatabase(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", withForeignKeys(dsn))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1) // SQLite works best with a single connection
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrate applies the schema to the database
+func migrate(db *sql.DB) error {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfMissing(db, col.table, col.name, col.definition); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range indexes {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return backfillPostSlugs(db)
+}
+
+// addColumnIfMissing adds a column unless the table already has it
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+// withForeignKeys enables foreign key enforcement on every connection so
+// join tables are cleaned up by ON DELETE CASCADE
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Email kinds. The first three are also the lists a user can unsubscribe
+// from; newsletter email goes to subscribers rather than users.
+const (
+	EmailNewPost           = "new_post"
+	EmailCommentReply      = "comment_reply"
+	EmailDigest            = "digest"
+	EmailNewsletter        = "newsletter"
+	EmailNewsletterConfirm = "newsletter_confirm"
+)
+
+// Email queue states
+const (
+	EmailPending   = "pending"
+	EmailSent      = "sent"
+	EmailFailed    = "failed"
+	EmailCancelled = "cancelled" // the recipient unsubscribed or bounced before it was sent
+)
+
+// EmailPreferences are a user's address and the email they want
+type EmailPreferences struct {
+	UserID           int64      `json:"user_id" db:"user_id"`
+	Email            string     `json:"email" db:"email"`
+	NewPosts         bool       `json:"new_posts" db:"new_posts"`
+	CommentReplies   bool       `json:"comment_replies" db:"comment_replies"`
+	WeeklyDigest     bool       `json:"weekly_digest" db:"weekly_digest"`
+	UnsubscribeToken string     `json:"-" db:"unsubscribe_token"`
+	LastDigestAt     *time.Time `json:"last_digest_at,omitempty" db:"last_digest_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Wants reports whether the user wants email of the given kind
+func (p *EmailPreferences) Wants(kind string) bool {
+	if p.Email == "" {
+		return false
+	}
+	switch kind {
+	case EmailNewPost:
+		return p.NewPosts
+	case EmailCommentReply:
+		return p.CommentReplies
+	case EmailDigest:
+		return p.WeeklyDigest
+	default:
+		return false
+	}
+}
+
+// QueuedEmail is a rendered email waiting to be sent, or the record of one
+// that was
+type QueuedEmail struct {
+	ID            int64             `json:"id" db:"id"`
+	UserID        int64             `json:"user_id,omitempty" db:"user_id"`
+	CampaignID    int64             `json:"campaign_id,omitempty" db:"campaign_id"`
+	SubscriberID  int64             `json:"subscriber_id,omitempty" db:"subscriber_id"`
+	Kind          string            `json:"kind" db:"kind"`
+	DedupeKey     string            `json:"dedupe_key" db:"dedupe_key"`
+	Recipient     string            `json:"recipient" db:"recipient"`
+	Subject       string            `json:"subject" db:"subject"`
+	Text          string            `json:"-" db:"text_body"`
+	HTML          string            `json:"-" db:"html_body"`
+	Headers       map[string]string `json:"-" db:"headers"`
+	Status        string            `json:"status" db:"status"` // pending, sent, failed, cancelled
+	Attempts      int               `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string            `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	SentAt        *time.Time        `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+const emailPreferencesColumns = `user_id, email, new_posts, comment_replies, weekly_digest, unsubscribe_token,
+	last_digest_at, updated_at`
+
+func scanEmailPreferences(row rowScanner) (*EmailPreferences, error) {
+	prefs := &EmailPreferences{}
+	var lastDigestAt sql.NullTime
+	err := row.Scan(&prefs.UserID, &prefs.Email, &prefs.NewPosts, &prefs.CommentReplies, &prefs.WeeklyDigest,
+		&prefs.UnsubscribeToken, &lastDigestAt, &prefs.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	prefs.LastDigestAt = nullTimePtr(lastDigestAt)
+	return prefs, nil
+}
+
+const queuedEmailColumns = `id, user_id, campaign_id, subscriber_id, kind, dedupe_key, recipient, subject,
+	text_body, html_body, headers, status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+func scanQueuedEmail(row rowScanner) (*QueuedEmail, error) {
+	email := &QueuedEmail{}
+	var headers string
+	var sentAt sql.NullTime
+	err := row.Scan(&email.ID, &email.UserID, &email.CampaignID, &email.SubscriberID, &email.Kind, &email.DedupeKey,
+		&email.Recipient, &email.Subject, &email.Text, &email.HTML, &headers, &email.Status, &email.Attempts, &email.NextAttemptAt,
+		&email.LastError, &email.CreatedAt, &sentAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(headers), &email.Headers); err != nil {
+		return nil, err
+	}
+	email.SentAt = nullTimePtr(sentAt)
+	return email, nil
+}
+
+type EmailRepository struct {
+	db *sql.DB
+}
+
+func NewEmailRepository(db *sql.DB) *EmailRepository {
+	return &EmailRepository{db: db}
+}
+
+func (r *EmailRepository) FindPreferences(userID int64) (*EmailPreferences, error) {
+	return r.findPreferences(`SELECT `+emailPreferencesColumns+` FROM email_preferences WHERE user_id = ?`, userID)
+}
+
+func (r *EmailRepository) FindPreferencesByToken(token string) (*EmailPreferences, error) {
+	return r.findPreferences(`SELECT `+emailPreferencesColumns+` FROM email_preferences WHERE unsubscribe_token = ?`, token)
+}
+
+func (r *EmailRepository) findPreferences(query string, arg interface{}) (*EmailPreferences, error) {
+	prefs, err := scanEmailPreferences(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return prefs, err
+}
+
+// SavePreferences creates or replaces the user's preferences. The
+// unsubscribe token is kept once stored; the last digest time is only
+// set if there was none, since MarkDigestSent moves it on.
+func (r *EmailRepository) SavePreferences(prefs *EmailPreferences) error {
+	query := `INSERT INTO email_preferences
+	              (user_id, email, new_posts, comment_replies, weekly_digest, unsubscribe_token, last_digest_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)
+	          ON CONFLICT(user_id) DO UPDATE SET
+	              email = excluded.email,
+	              new_posts = excluded.new_posts,
+	              comment_replies = excluded.comment_replies,
+	              weekly_digest = excluded.weekly_digest,
+	              last_digest_at = COALESCE(email_preferences.last_digest_at, excluded.last_digest_at),
+	              updated_at = CURRENT_TIMESTAMP`
+
+	_, err := r.db.Exec(query, prefs.UserID, prefs.Email, prefs.NewPosts, prefs.CommentReplies, prefs.WeeklyDigest,
+		prefs.UnsubscribeToken, utcTimePtr(prefs.LastDigestAt))
+	if err != nil {
+		return err
+	}
+
+	saved, err := r.FindPreferences(prefs.UserID)
+	if err != nil {
+		return err
+	}
+	*prefs = *saved
+	return nil
+}
+
+// FindDigestRecipients returns the users wanting a digest who have not had
+// one since before
+func (r *EmailRepository) FindDigestRecipients(before time.Time) ([]*EmailPreferences, error) {
+	query := `SELECT ` + emailPreferencesColumns + ` FROM email_preferences
+	          WHERE weekly_digest = 1 AND email != '' AND (last_digest_at IS NULL OR last_digest_at <= ?)
+	          ORDER BY user_id`
+
+	rows, err := r.db.Query(query, before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*EmailPreferences
+	for rows.Next() {
+		prefs, err := scanEmailPreferences(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, prefs)
+	}
+	return recipients, rows.Err()
+}
+
+func (r *EmailRepository) MarkDigestSent(userID int64, at time.Time) error {
+	_, err := r.db.Exec(`UPDATE email_preferences SET last_digest_at = ? WHERE user_id = ?`, at.UTC(), userID)
+	return err
+}
+
+// Enqueue stores a pending email. It reports false, storing nothing, when
+// an email with the same dedupe key was queued before.
+func (r *EmailRepository) Enqueue(email *QueuedEmail) (bool, error) {
+	headers, err := json.Marshal(email.Headers)
+	if err != nil {
+		return false, err
+	}
+
+	query := `INSERT OR IGNORE INTO email_queue
+	          (user_id, campaign_id, subscriber_id, kind, dedupe_key, recipient, subject, text_body, html_body, headers,
+	           status, next_attempt_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, email.UserID, email.CampaignID, email.SubscriberID, email.Kind, email.DedupeKey,
+		email.Recipient, email.Subject, email.Text, email.HTML, string(headers), email.Status, email.NextAttemptAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if email.ID, err = result.LastInsertId(); err != nil {
+		return false, err
+	}
+	return true, r.db.QueryRow(`SELECT created_at FROM email_queue WHERE id = ?`, email.ID).Scan(&email.CreatedAt)
+}
+
+// FindDue returns pending emails whose next attempt is due, oldest first
+func (r *EmailRepository) FindDue(now time.Time, limit int) ([]*QueuedEmail, error) {
+	query := `SELECT ` + queuedEmailColumns + ` FROM email_queue
+	          WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`
+	return r.queryEmails(query, EmailPending, now.UTC(), limit)
+}
+
+// FindAll lists queued and sent emails, newest first, optionally only
+// those in one state
+func (r *EmailRepository) FindAll(status string, limit, offset int) ([]*QueuedEmail, error) {
+	query := `SELECT ` + queuedEmailColumns + ` FROM email_queue`
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	return r.queryEmails(query, append(args, limit, offset)...)
+}
+
+// Update records the outcome of an attempt
+func (r *EmailRepository) Update(email *QueuedEmail) error {
+	query := `UPDATE email_queue SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, sent_at = ?
+	          WHERE id = ?`
+
+	_, err := r.db.Exec(query, email.Status, email.Attempts, email.NextAttemptAt.UTC(), email.LastError,
+		utcTimePtr(email.SentAt), email.ID)
+	return err
+}
+
+// CancelForSubscribers cancels the pending email of newsletter subscribers
+// who left their list
+func (r *EmailRepository) CancelForSubscribers(subscriberIDs []int64) (int64, error) {
+	if len(subscriberIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `UPDATE email_queue SET status = ? WHERE status = ? AND subscriber_id IN (` + placeholders(len(subscriberIDs)) + `)`
+	result, err := r.db.Exec(query, append([]interface{}{EmailCancelled, EmailPending}, int64Args(subscriberIDs)...)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *EmailRepository) queryEmails(query string, args ...interface{}) ([]*QueuedEmail, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*QueuedEmail
+	for rows.Next() {
+		email, err := scanQueuedEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
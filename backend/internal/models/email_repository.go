@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EmailRepositoryInterface defines the contract for users' email
+// preferences and the outgoing email queue
+type EmailRepositoryInterface interface {
+	FindPreferences(userID int64) (*EmailPreferences, error)
+	FindPreferencesByToken(token string) (*EmailPreferences, error)
+	SavePreferences(prefs *EmailPreferences) error
+	FindDigestRecipients(before time.Time) ([]*EmailPreferences, error)
+	MarkDigestSent(userID int64, at time.Time) error
+	Enqueue(email *QueuedEmail) (bool, error)
+	FindDue(now time.Time, limit int) ([]*QueuedEmail, error)
+	FindAll(status string, limit, offset int) ([]*QueuedEmail, error)
+	Update(email *QueuedEmail) error
+	CancelForSubscribers(subscriberIDs []int64) (int64, error)
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// EventType names a kind of post lifecycle event. The values are part of
+// the external contract (webhooks, the event stream) and never change.
+type EventType string
+
+const (
+	EventPostCreated      EventType = "post_created"
+	EventPostUpdated      EventType = "post_updated"
+	EventPostDeleted      EventType = "post_deleted"
+	EventCommentCreated   EventType = "comment_created"
+	EventCommentModerated EventType = "comment_moderated"
+)
+
+// EventTypes lists every event type in a stable order
+var EventTypes = []EventType{
+	EventPostCreated, EventPostUpdated, EventPostDeleted, EventCommentCreated, EventCommentModerated,
+}
+
+// EventSchemaVersion is the version of the payload types below. Bump it
+// whenever a payload changes incompatibly and keep decoding older versions.
+//
+// Version 0 events (recorded before versioning) carried the bare post for
+// post events and a CommentEvent for comment events, with no change set.
+const EventSchemaVersion = 1
+
+// EventMeta describes who caused a change and which request it belongs to.
+// The zero value means a system action outside any request.
+type EventMeta struct {
+	ActorID       int64  `json:"actor_id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// EventPayload is implemented by the typed payload of each event kind
+type EventPayload interface {
+	EventType() EventType
+}
+
+// FieldChange is the value of a field before and after an update
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type PostCreated struct {
+	Post *Post `json:"post"`
+}
+
+// PostUpdated carries the post after the update and the fields that
+// changed, keyed by their JSON name
+type PostUpdated struct {
+	Post    *Post                  `json:"post"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// PostDeleted carries the post as it was before deletion
+type PostDeleted struct {
+	Post *Post `json:"post"`
+}
+
+type CommentCreated struct {
+	CommentEvent
+}
+
+type CommentModerated struct {
+	CommentEvent
+	PreviousStatus string `json:"previous_status"`
+}
+
+func (PostCreated) EventType() EventType      { return EventPostCreated }
+func (PostUpdated) EventType() EventType      { return EventPostUpdated }
+func (PostDeleted) EventType() EventType      { return EventPostDeleted }
+func (CommentCreated) EventType() EventType   { return EventCommentCreated }
+func (CommentModerated) EventType() EventType { return EventCommentModerated }
+
+// DecodeEventPayload decodes a stored payload into the current payload type
+// of the event, upgrading payloads recorded at older schema versions
+func DecodeEventPayload(eventType EventType, version int, raw []byte) (EventPayload, error) {
+	if version > EventSchemaVersion {
+		return nil, fmt.Errorf("%s event has unsupported schema version %d", eventType, version)
+	}
+
+	switch eventType {
+	case EventPostCreated:
+		if version == 0 {
+			post, err := decodeLegacyPost(raw)
+			return PostCreated{Post: post}, err
+		}
+		var data PostCreated
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	case EventPostUpdated:
+		if version == 0 {
+			post, err := decodeLegacyPost(raw)
+			return PostUpdated{Post: post, Changes: map[string]FieldChange{}}, err
+		}
+		var data PostUpdated
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	case EventPostDeleted:
+		if version == 0 {
+			post, err := decodeLegacyPost(raw)
+			return PostDeleted{Post: post}, err
+		}
+		var data PostDeleted
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	case EventCommentCreated:
+		// Version 0 had the same shape
+		var data CommentCreated
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	case EventCommentModerated:
+		// Version 0 had the same shape without previous_status
+		var data CommentModerated
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	default:
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+}
+
+// decodeLegacyPost reads a version 0 post event, whose payload was the post
+func decodeLegacyPost(raw []byte) (*Post, error) {
+	post := &Post{}
+	if err := json.Unmarshal(raw, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// postChanges compares the editable fields of two versions of a post
+func postChanges(before, after *Post) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+
+	compare := func(name string, old, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			changes[name] = FieldChange{Before: old, After: new}
+		}
+	}
+
+	compare("title", before.Title, after.Title)
+	compare("content", before.Content, after.Content)
+	compare("format", before.Format, after.Format)
+	compare("type", before.Type, after.Type)
+	compare("status", before.Status, after.Status)
+	compare("slug", before.Slug, after.Slug)
+	compare("publish_at", timeValue(before.PublishAt), timeValue(after.PublishAt))
+	compare("unpublish_at", timeValue(before.UnpublishAt), timeValue(after.UnpublishAt))
+	compare("locale", before.Locale, after.Locale)
+	compare("translation_group", int64Value(before.TranslationGroup), int64Value(after.TranslationGroup))
+	compare("cover_image", before.CoverImage, after.CoverImage)
+	compare("seo", before.SEO, after.SEO)
+
+	return changes
+}
+
+// int64Value dereferences an optional number so equal values compare equal
+func int64Value(n *int64) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// timeValue formats an optional time the way it is encoded in JSON, so
+// equal instants compare equal
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// What users can follow
+const (
+	FollowAuthor = "author"
+	FollowTag    = "tag"
+	FollowSeries = "series"
+)
+
+// FollowTarget is something a user can follow: an author, a tag or a series
+type FollowTarget struct {
+	Type string `json:"type" db:"target_type"`
+	ID   int64  `json:"id" db:"target_id"`
+}
+
+type Follow struct {
+	UserID    int64        `json:"user_id" db:"user_id"`
+	Target    FollowTarget `json:"target"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+const followColumns = `user_id, target_type, target_id, created_at`
+
+func scanFollow(row rowScanner) (*Follow, error) {
+	follow := &Follow{}
+	err := row.Scan(&follow.UserID, &follow.Target.Type, &follow.Target.ID, &follow.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return follow, nil
+}
+
+type FollowRepository struct {
+	db *sql.DB
+}
+
+func NewFollowRepository(db *sql.DB) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+// Create records the follow. It reports false when the user already
+// followed the target.
+func (r *FollowRepository) Create(follow *Follow) (bool, error) {
+	result, err := r.db.Exec(`INSERT OR IGNORE INTO follows (user_id, target_type, target_id) VALUES (?, ?, ?)`,
+		follow.UserID, follow.Target.Type, follow.Target.ID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	err = r.db.QueryRow(`SELECT created_at FROM follows WHERE user_id = ? AND target_type = ? AND target_id = ?`,
+		follow.UserID, follow.Target.Type, follow.Target.ID).Scan(&follow.CreatedAt)
+	return affected > 0, err
+}
+
+// Delete stops the user following the target. It reports false when the
+// user did not follow it.
+func (r *FollowRepository) Delete(userID int64, target FollowTarget) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM follows WHERE user_id = ? AND target_type = ? AND target_id = ?`,
+		userID, target.Type, target.ID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FindByUser lists what the user follows, most recent first
+func (r *FollowRepository) FindByUser(userID int64) ([]*Follow, error) {
+	return r.queryFollows(`SELECT `+followColumns+` FROM follows WHERE user_id = ? ORDER BY created_at DESC, rowid DESC`,
+		userID)
+}
+
+// FindFollowers returns the follows of any of the targets
+func (r *FollowRepository) FindFollowers(targets []FollowTarget) ([]*Follow, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	conditions := make([]string, len(targets))
+	args := make([]interface{}, 0, 2*len(targets))
+	for i, target := range targets {
+		conditions[i] = "(target_type = ? AND target_id = ?)"
+		args = append(args, target.Type, target.ID)
+	}
+
+	return r.queryFollows(`SELECT `+followColumns+` FROM follows WHERE `+strings.Join(conditions, " OR "), args...)
+}
+
+func (r *FollowRepository) queryFollows(query string, args ...interface{}) ([]*Follow, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []*Follow
+	for rows.Next() {
+		follow, err := scanFollow(rows)
+		if err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
@@ -0,0 +1,9 @@
+package models
+
+// FollowRepositoryInterface defines the contract for what users follow
+type FollowRepositoryInterface interface {
+	Create(follow *Follow) (bool, error)
+	Delete(userID int64, target FollowTarget) (bool, error)
+	FindByUser(userID int64) ([]*Follow, error)
+	FindFollowers(targets []FollowTarget) ([]*Follow, error)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// LeaseRepository hands out named, time-limited leases stored in the
+// database so that only one process at a time runs a given background job
+type LeaseRepository struct {
+	db *sql.DB
+}
+
+func NewLeaseRepository(db *sql.DB) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// Acquire takes or renews the named lease for holder. It returns false when
+// another holder owns an unexpired lease.
+func (r *LeaseRepository) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+
+	query := `INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)
+	          ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+	          WHERE leases.holder = excluded.holder OR leases.expires_at < ?`
+
+	result, err := r.db.Exec(query, name, holder, now.Add(ttl), now)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Release gives up the lease if holder owns it
+func (r *LeaseRepository) Release(name, holder string) error {
+	_, err := r.db.Exec(`DELETE FROM leases WHERE name = ? AND holder = ?`, name, holder)
+	return err
+}
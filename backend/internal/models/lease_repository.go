@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// LeaseRepositoryInterface defines the contract for distributed job leases
+type LeaseRepositoryInterface interface {
+	Acquire(name, holder string, ttl time.Duration) (bool, error)
+	Release(name, holder string) error
+}
@@ -0,0 +1,257 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Media is an uploaded file. Files are content addressed: Hash is the
+// SHA-256 of the bytes, so uploading the same file twice yields one record.
+type Media struct {
+	ID          int64          `json:"id" db:"id"`
+	Hash        string         `json:"hash" db:"hash"`
+	StorageKey  string         `json:"storage_key" db:"storage_key"`
+	Filename    string         `json:"filename" db:"filename"` // as uploaded
+	ContentType string         `json:"content_type" db:"content_type"`
+	Size        int64          `json:"size" db:"size"`
+	Width       int            `json:"width,omitempty" db:"width"` // images only
+	Height      int            `json:"height,omitempty" db:"height"`
+	UploaderID  int64          `json:"uploader_id" db:"uploader_id"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	Variants    []MediaVariant `json:"variants"`
+}
+
+// MediaVariant is a resized copy of an image, such as its thumbnail
+type MediaVariant struct {
+	Name        string `json:"name" db:"name"`
+	StorageKey  string `json:"storage_key" db:"storage_key"`
+	ContentType string `json:"content_type" db:"content_type"`
+	Size        int64  `json:"size" db:"size"`
+	Width       int    `json:"width" db:"width"`
+	Height      int    `json:"height" db:"height"`
+}
+
+const mediaColumns = `id, hash, storage_key, filename, content_type, size, width, height, uploader_id, created_at`
+
+func scanMedia(row rowScanner) (*Media, error) {
+	media := &Media{Variants: []MediaVariant{}}
+	err := row.Scan(&media.ID, &media.Hash, &media.StorageKey, &media.Filename, &media.ContentType,
+		&media.Size, &media.Width, &media.Height, &media.UploaderID, &media.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+type MediaRepository struct {
+	db *sql.DB
+}
+
+func NewMediaRepository(db *sql.DB) *MediaRepository {
+	return &MediaRepository{db: db}
+}
+
+// Create stores the media and its variants unless media with the same hash
+// exists. Either way media ends up holding the stored record; created
+// reports whether it is new.
+func (r *MediaRepository) Create(media *Media) (created bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO media (hash, storage_key, filename, content_type, size, width, height, uploader_id)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	          ON CONFLICT(hash) DO NOTHING`
+
+	result, err := tx.Exec(query, media.Hash, media.StorageKey, media.Filename, media.ContentType,
+		media.Size, media.Width, media.Height, media.UploaderID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected > 0 {
+		if media.ID, err = result.LastInsertId(); err != nil {
+			return false, err
+		}
+		for _, variant := range media.Variants {
+			_, err := tx.Exec(`INSERT INTO media_variants (media_id, name, storage_key, content_type, size, width, height)
+			                   VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				media.ID, variant.Name, variant.StorageKey, variant.ContentType, variant.Size, variant.Width, variant.Height)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	stored, err := scanMedia(tx.QueryRow(`SELECT `+mediaColumns+` FROM media WHERE hash = ?`, media.Hash))
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	if err := r.attachVariants([]*Media{stored}); err != nil {
+		return false, err
+	}
+	*media = *stored
+	return affected > 0, nil
+}
+
+func (r *MediaRepository) FindByID(id int64) (*Media, error) {
+	return r.findOne(`SELECT `+mediaColumns+` FROM media WHERE id = ?`, id)
+}
+
+func (r *MediaRepository) FindByHash(hash string) (*Media, error) {
+	return r.findOne(`SELECT `+mediaColumns+` FROM media WHERE hash = ?`, hash)
+}
+
+func (r *MediaRepository) findOne(query string, arg interface{}) (*Media, error) {
+	media, err := scanMedia(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachVariants([]*Media{media}); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// FindAll lists media newest first
+func (r *MediaRepository) FindAll(limit, offset int) ([]*Media, error) {
+	query := `SELECT ` + mediaColumns + ` FROM media ORDER BY id DESC LIMIT ? OFFSET ?`
+	return r.findMany(query, limit, offset)
+}
+
+// FindOrphans lists media created before the cutoff that no post references
+func (r *MediaRepository) FindOrphans(createdBefore time.Time, limit int) ([]*Media, error) {
+	query := `SELECT ` + mediaColumns + ` FROM media m
+	          WHERE created_at < ? AND NOT EXISTS (SELECT 1 FROM post_media pm WHERE pm.media_id = m.id)
+	          ORDER BY id LIMIT ?`
+	return r.findMany(query, createdBefore.UTC(), limit)
+}
+
+func (r *MediaRepository) findMany(query string, args ...interface{}) ([]*Media, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var media []*Media
+	for rows.Next() {
+		item, err := scanMedia(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		media = append(media, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachVariants(media); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// attachVariants loads the variants of every item with one query
+func (r *MediaRepository) attachVariants(media []*Media) error {
+	if len(media) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Media, len(media))
+	ids := make([]int64, len(media))
+	for i, item := range media {
+		byID[item.ID] = item
+		ids[i] = item.ID
+	}
+
+	query := `SELECT media_id, name, storage_key, content_type, size, width, height FROM media_variants
+	          WHERE media_id IN (` + placeholders(len(ids)) + `) ORDER BY media_id, width`
+
+	rows, err := r.db.Query(query, int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mediaID int64
+		var variant MediaVariant
+		if err := rows.Scan(&mediaID, &variant.Name, &variant.StorageKey, &variant.ContentType,
+			&variant.Size, &variant.Width, &variant.Height); err != nil {
+			return err
+		}
+		byID[mediaID].Variants = append(byID[mediaID].Variants, variant)
+	}
+	return rows.Err()
+}
+
+// Delete removes the media record and its variants; references go with it
+func (r *MediaRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM media WHERE id = ?`, id)
+	return err
+}
+
+// SetPostMedia replaces the media a post references with the media having
+// the given hashes. Unknown hashes are ignored.
+func (r *MediaRepository) SetPostMedia(postID int64, hashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM post_media WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+
+	if len(hashes) > 0 {
+		args := []interface{}{postID}
+		for _, hash := range hashes {
+			args = append(args, hash)
+		}
+		// The post may have been deleted since the event was recorded
+		query := `INSERT INTO post_media (post_id, media_id)
+		          SELECT p.id, m.id FROM posts p, media m
+		          WHERE p.id = ? AND m.hash IN (` + placeholders(len(hashes)) + `)`
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindReferencingPostIDs returns the posts that reference the media
+func (r *MediaRepository) FindReferencingPostIDs(mediaID int64) ([]int64, error) {
+	rows, err := r.db.Query(`SELECT post_id FROM post_media WHERE media_id = ? ORDER BY post_id`, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	postIDs := []int64{}
+	for rows.Next() {
+		var postID int64
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		postIDs = append(postIDs, postID)
+	}
+	return postIDs, rows.Err()
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// MediaRepositoryInterface defines the contract for uploaded files, their
+// resized variants and the posts referencing them
+type MediaRepositoryInterface interface {
+	Create(media *Media) (created bool, err error)
+	FindByID(id int64) (*Media, error)
+	FindByHash(hash string) (*Media, error)
+	FindAll(limit, offset int) ([]*Media, error)
+	FindOrphans(createdBefore time.Time, limit int) ([]*Media, error)
+	Delete(id int64) error
+	SetPostMedia(postID int64, hashes []string) error
+	FindReferencingPostIDs(mediaID int64) ([]int64, error)
+}
@@ -0,0 +1,575 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Subscriber states. Only confirmed subscribers receive campaigns.
+const (
+	SubscriberPending      = "pending" // waiting for the double opt-in confirmation
+	SubscriberConfirmed    = "confirmed"
+	SubscriberUnsubscribed = "unsubscribed"
+	SubscriberBounced      = "bounced"
+)
+
+// Campaign states
+const (
+	CampaignDraft     = "draft"
+	CampaignScheduled = "scheduled"
+	CampaignSending   = "sending" // being queued for its recipients
+	CampaignSent      = "sent"
+	CampaignFailed    = "failed"
+)
+
+// NewsletterList is a list people subscribe to and campaigns are sent to
+type NewsletterList struct {
+	ID              int64     `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	Slug            string    `json:"slug" db:"slug"`
+	Description     string    `json:"description" db:"description"`
+	SubscriberCount int       `json:"subscriber_count"` // confirmed subscribers
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// Subscriber is an address on a list
+type Subscriber struct {
+	ID             int64      `json:"id" db:"id"`
+	ListID         int64      `json:"list_id" db:"list_id"`
+	Email          string     `json:"email" db:"email"`
+	Name           string     `json:"name" db:"name"`
+	Status         string     `json:"status" db:"status"` // pending, confirmed, unsubscribed, bounced
+	Token          string     `json:"-" db:"token"`
+	BounceCount    int        `json:"bounce_count" db:"bounce_count"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty" db:"unsubscribed_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Campaign is a newsletter issue made of published posts
+type Campaign struct {
+	ID             int64      `json:"id" db:"id"`
+	ListID         int64      `json:"list_id" db:"list_id"`
+	Subject        string     `json:"subject" db:"subject"`
+	Intro          string     `json:"intro" db:"intro"`
+	PostIDs        []int64    `json:"post_ids"`
+	Status         string     `json:"status" db:"status"` // draft, scheduled, sending, sent, failed
+	ScheduledAt    *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	RecipientCount int        `json:"recipient_count" db:"recipient_count"`
+	Attempts       int        `json:"attempts" db:"attempts"` // failed attempts to queue it
+	Error          string     `json:"error,omitempty" db:"error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CampaignStats sums up what happened to a campaign's email. Opens and
+// clicks count recipients; the totals count every open and click.
+type CampaignStats struct {
+	Recipients   int            `json:"recipients"`
+	Sent         int            `json:"sent"`
+	Pending      int            `json:"pending"`
+	Failed       int            `json:"failed"`
+	Opens        int            `json:"opens"`
+	TotalOpens   int            `json:"total_opens"`
+	Clicks       int            `json:"clicks"`
+	TotalClicks  int            `json:"total_clicks"`
+	Bounces      int            `json:"bounces"`
+	Unsubscribes int            `json:"unsubscribes"`
+	OpenRate     float64        `json:"open_rate"`  // opens per sent email
+	ClickRate    float64        `json:"click_rate"` // clicks per sent email
+	Links        []CampaignLink `json:"links"`
+}
+
+// CampaignLink counts the clicks on one link of a campaign
+type CampaignLink struct {
+	URL    string `json:"url"`
+	Clicks int    `json:"clicks"`
+}
+
+const newsletterListColumns = `l.id, l.name, l.slug, l.description,
+	(SELECT COUNT(*) FROM newsletter_subscribers s WHERE s.list_id = l.id AND s.status = 'confirmed'), l.created_at`
+
+func scanNewsletterList(row rowScanner) (*NewsletterList, error) {
+	list := &NewsletterList{}
+	err := row.Scan(&list.ID, &list.Name, &list.Slug, &list.Description, &list.SubscriberCount, &list.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+const subscriberColumns = `id, list_id, email, name, status, token, bounce_count, confirmed_at, unsubscribed_at,
+	created_at, updated_at`
+
+func scanSubscriber(row rowScanner) (*Subscriber, error) {
+	sub := &Subscriber{}
+	var confirmedAt, unsubscribedAt sql.NullTime
+	err := row.Scan(&sub.ID, &sub.ListID, &sub.Email, &sub.Name, &sub.Status, &sub.Token, &sub.BounceCount,
+		&confirmedAt, &unsubscribedAt, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	sub.ConfirmedAt = nullTimePtr(confirmedAt)
+	sub.UnsubscribedAt = nullTimePtr(unsubscribedAt)
+	return sub, nil
+}
+
+const campaignColumns = `id, list_id, subject, intro, status, scheduled_at, sent_at, recipient_count, attempts,
+	error, created_at, updated_at`
+
+func scanCampaign(row rowScanner) (*Campaign, error) {
+	campaign := &Campaign{}
+	var scheduledAt, sentAt sql.NullTime
+	err := row.Scan(&campaign.ID, &campaign.ListID, &campaign.Subject, &campaign.Intro, &campaign.Status,
+		&scheduledAt, &sentAt, &campaign.RecipientCount, &campaign.Attempts, &campaign.Error, &campaign.CreatedAt,
+		&campaign.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	campaign.ScheduledAt = nullTimePtr(scheduledAt)
+	campaign.SentAt = nullTimePtr(sentAt)
+	return campaign, nil
+}
+
+type NewsletterRepository struct {
+	db *sql.DB
+}
+
+func NewNewsletterRepository(db *sql.DB) *NewsletterRepository {
+	return &NewsletterRepository{db: db}
+}
+
+func (r *NewsletterRepository) CreateList(list *NewsletterList) error {
+	result, err := r.db.Exec(`INSERT INTO newsletter_lists (name, slug, description) VALUES (?, ?, ?)`,
+		list.Name, list.Slug, list.Description)
+	if err != nil {
+		return err
+	}
+
+	if list.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRow(`SELECT created_at FROM newsletter_lists WHERE id = ?`, list.ID).Scan(&list.CreatedAt)
+}
+
+func (r *NewsletterRepository) FindLists() ([]*NewsletterList, error) {
+	rows, err := r.db.Query(`SELECT ` + newsletterListColumns + ` FROM newsletter_lists l ORDER BY l.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []*NewsletterList
+	for rows.Next() {
+		list, err := scanNewsletterList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (r *NewsletterRepository) FindListByID(id int64) (*NewsletterList, error) {
+	return r.findList(`SELECT `+newsletterListColumns+` FROM newsletter_lists l WHERE l.id = ?`, id)
+}
+
+func (r *NewsletterRepository) FindListBySlug(slug string) (*NewsletterList, error) {
+	return r.findList(`SELECT `+newsletterListColumns+` FROM newsletter_lists l WHERE l.slug = ?`, slug)
+}
+
+func (r *NewsletterRepository) findList(query string, arg interface{}) (*NewsletterList, error) {
+	list, err := scanNewsletterList(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return list, err
+}
+
+// DeleteList removes a list with its subscribers and campaigns
+func (r *NewsletterRepository) DeleteList(id int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM newsletter_lists WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *NewsletterRepository) CreateSubscriber(sub *Subscriber) error {
+	query := `INSERT INTO newsletter_subscribers (list_id, email, name, status, token) VALUES (?, ?, ?, ?, ?)`
+	result, err := r.db.Exec(query, sub.ListID, sub.Email, sub.Name, sub.Status, sub.Token)
+	if err != nil {
+		return err
+	}
+
+	if sub.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRow(`SELECT created_at, updated_at FROM newsletter_subscribers WHERE id = ?`, sub.ID).
+		Scan(&sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *NewsletterRepository) UpdateSubscriber(sub *Subscriber) error {
+	query := `UPDATE newsletter_subscribers
+	          SET name = ?, status = ?, bounce_count = ?, confirmed_at = ?, unsubscribed_at = ?,
+	              updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ?`
+
+	_, err := r.db.Exec(query, sub.Name, sub.Status, sub.BounceCount, utcTimePtr(sub.ConfirmedAt),
+		utcTimePtr(sub.UnsubscribedAt), sub.ID)
+	return err
+}
+
+func (r *NewsletterRepository) FindSubscriber(listID int64, email string) (*Subscriber, error) {
+	sub, err := scanSubscriber(r.db.QueryRow(
+		`SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE list_id = ? AND email = ?`, listID, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sub, err
+}
+
+func (r *NewsletterRepository) FindSubscriberByToken(token string) (*Subscriber, error) {
+	sub, err := scanSubscriber(r.db.QueryRow(
+		`SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE token = ?`, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sub, err
+}
+
+// FindSubscribersByEmail returns an address's subscriptions on every list
+func (r *NewsletterRepository) FindSubscribersByEmail(email string) ([]*Subscriber, error) {
+	return r.querySubscribers(`SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE email = ? ORDER BY id`,
+		email)
+}
+
+// FindSubscribers lists a list's subscribers, newest first, optionally only
+// those in one state
+func (r *NewsletterRepository) FindSubscribers(listID int64, status string, limit, offset int) ([]*Subscriber, error) {
+	query := `SELECT ` + subscriberColumns + ` FROM newsletter_subscribers WHERE list_id = ?`
+	args := []interface{}{listID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	return r.querySubscribers(query, append(args, limit, offset)...)
+}
+
+// FindConfirmedSubscribers returns everyone a campaign to the list goes to
+func (r *NewsletterRepository) FindConfirmedSubscribers(listID int64) ([]*Subscriber, error) {
+	query := `SELECT ` + subscriberColumns + ` FROM newsletter_subscribers WHERE list_id = ? AND status = ? ORDER BY id`
+	return r.querySubscribers(query, listID, SubscriberConfirmed)
+}
+
+func (r *NewsletterRepository) querySubscribers(query string, args ...interface{}) ([]*Subscriber, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*Subscriber
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *NewsletterRepository) CreateCampaign(campaign *Campaign) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO campaigns (list_id, subject, intro, status, scheduled_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, campaign.ListID, campaign.Subject, campaign.Intro, campaign.Status,
+		utcTimePtr(campaign.ScheduledAt))
+	if err != nil {
+		return err
+	}
+	if campaign.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	if err := setCampaignPosts(tx, campaign); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(`SELECT created_at, updated_at FROM campaigns WHERE id = ?`, campaign.ID).
+		Scan(&campaign.CreatedAt, &campaign.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateCampaign saves every field and replaces the campaign's posts
+func (r *NewsletterRepository) UpdateCampaign(campaign *Campaign) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE campaigns
+	          SET list_id = ?, subject = ?, intro = ?, status = ?, scheduled_at = ?, sent_at = ?,
+	              recipient_count = ?, attempts = ?, error = ?, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ?`
+	_, err = tx.Exec(query, campaign.ListID, campaign.Subject, campaign.Intro, campaign.Status,
+		utcTimePtr(campaign.ScheduledAt), utcTimePtr(campaign.SentAt), campaign.RecipientCount, campaign.Attempts,
+		campaign.Error, campaign.ID)
+	if err != nil {
+		return err
+	}
+	if err := setCampaignPosts(tx, campaign); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(`SELECT updated_at FROM campaigns WHERE id = ?`, campaign.ID).
+		Scan(&campaign.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setCampaignPosts(tx *sql.Tx, campaign *Campaign) error {
+	if _, err := tx.Exec(`DELETE FROM campaign_posts WHERE campaign_id = ?`, campaign.ID); err != nil {
+		return err
+	}
+	for i, postID := range campaign.PostIDs {
+		_, err := tx.Exec(`INSERT INTO campaign_posts (campaign_id, post_id, position) VALUES (?, ?, ?)`,
+			campaign.ID, postID, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *NewsletterRepository) FindCampaignByID(id int64) (*Campaign, error) {
+	campaigns, err := r.queryCampaigns(`SELECT `+campaignColumns+` FROM campaigns WHERE id = ?`, id)
+	if err != nil || len(campaigns) == 0 {
+		return nil, err
+	}
+	return campaigns[0], nil
+}
+
+// FindCampaigns lists campaigns, newest first
+func (r *NewsletterRepository) FindCampaigns(limit, offset int) ([]*Campaign, error) {
+	return r.queryCampaigns(`SELECT `+campaignColumns+` FROM campaigns ORDER BY id DESC LIMIT ? OFFSET ?`,
+		limit, offset)
+}
+
+// FindDueCampaigns returns scheduled campaigns whose time has come, and
+// campaigns whose sending was interrupted
+func (r *NewsletterRepository) FindDueCampaigns(now time.Time) ([]*Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns
+	          WHERE (status = ? AND scheduled_at <= ?) OR status = ?
+	          ORDER BY scheduled_at, id`
+	return r.queryCampaigns(query, CampaignScheduled, now.UTC(), CampaignSending)
+}
+
+func (r *NewsletterRepository) DeleteCampaign(id int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM campaigns WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// queryCampaigns reads campaigns along with their post IDs in order
+func (r *NewsletterRepository) queryCampaigns(query string, args ...interface{}) ([]*Campaign, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var campaigns []*Campaign
+	byID := make(map[int64]*Campaign)
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		campaign.PostIDs = []int64{}
+		campaigns = append(campaigns, campaign)
+		byID[campaign.ID] = campaign
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if len(campaigns) == 0 {
+		return campaigns, nil
+	}
+
+	ids := make([]int64, len(campaigns))
+	for i, campaign := range campaigns {
+		ids[i] = campaign.ID
+	}
+	rows, err = r.db.Query(`SELECT campaign_id, post_id FROM campaign_posts
+	                        WHERE campaign_id IN (`+placeholders(len(ids))+`) ORDER BY campaign_id, position`,
+		int64Args(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaignID, postID int64
+		if err := rows.Scan(&campaignID, &postID); err != nil {
+			return nil, err
+		}
+		byID[campaignID].PostIDs = append(byID[campaignID].PostIDs, postID)
+	}
+	return campaigns, rows.Err()
+}
+
+// AddRecipient records that a campaign went to a subscriber
+func (r *NewsletterRepository) AddRecipient(campaignID, subscriberID int64) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO campaign_recipients (campaign_id, subscriber_id) VALUES (?, ?)`,
+		campaignID, subscriberID)
+	return err
+}
+
+// RecordOpen counts an open by the subscriber with the token. It reports
+// false when the campaign did not go to them.
+func (r *NewsletterRepository) RecordOpen(campaignID int64, token string) (bool, error) {
+	query := `UPDATE campaign_recipients SET opens = opens + 1, opened_at = COALESCE(opened_at, CURRENT_TIMESTAMP)
+	          WHERE campaign_id = ? AND subscriber_id = (SELECT id FROM newsletter_subscribers WHERE token = ?)`
+
+	result, err := r.db.Exec(query, campaignID, token)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RecordClick counts a click on url by the subscriber with the token. A
+// click also counts as an open, since many mail clients block the pixel
+// opens are tracked with.
+func (r *NewsletterRepository) RecordClick(campaignID int64, token, url string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE campaign_recipients
+	          SET clicks = clicks + 1, clicked_at = COALESCE(clicked_at, CURRENT_TIMESTAMP),
+	              opened_at = COALESCE(opened_at, CURRENT_TIMESTAMP)
+	          WHERE campaign_id = ? AND subscriber_id = (SELECT id FROM newsletter_subscribers WHERE token = ?)`
+	result, err := tx.Exec(query, campaignID, token)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO campaign_links (campaign_id, url, clicks) VALUES (?, ?, 1)
+	                  ON CONFLICT(campaign_id, url) DO UPDATE SET clicks = clicks + 1`, campaignID, url)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RecordBounce notes that a campaign's email to a subscriber bounced
+func (r *NewsletterRepository) RecordBounce(campaignID, subscriberID int64) error {
+	_, err := r.db.Exec(`UPDATE campaign_recipients SET bounced_at = COALESCE(bounced_at, CURRENT_TIMESTAMP)
+	                     WHERE campaign_id = ? AND subscriber_id = ?`, campaignID, subscriberID)
+	return err
+}
+
+// RecordUnsubscribe notes that a subscriber left the list from a campaign
+func (r *NewsletterRepository) RecordUnsubscribe(campaignID, subscriberID int64) error {
+	_, err := r.db.Exec(`UPDATE campaign_recipients SET unsubscribed_at = COALESCE(unsubscribed_at, CURRENT_TIMESTAMP)
+	                     WHERE campaign_id = ? AND subscriber_id = ?`, campaignID, subscriberID)
+	return err
+}
+
+// FindLatestRecipient returns the campaign most recently sent to any of
+// the subscribers, and which of them it went to; both are 0 if none was
+func (r *NewsletterRepository) FindLatestRecipient(subscriberIDs []int64) (campaignID, subscriberID int64, err error) {
+	if len(subscriberIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	query := `SELECT campaign_id, subscriber_id FROM campaign_recipients
+	          WHERE subscriber_id IN (` + placeholders(len(subscriberIDs)) + `)
+	          ORDER BY created_at DESC, campaign_id DESC LIMIT 1`
+	err = r.db.QueryRow(query, int64Args(subscriberIDs)...).Scan(&campaignID, &subscriberID)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return campaignID, subscriberID, err
+}
+
+// FindCampaignStats counts a campaign's email by state and its recipients'
+// opens, clicks, bounces and unsubscribes. Rates are left to the caller.
+func (r *NewsletterRepository) FindCampaignStats(campaignID int64) (*CampaignStats, error) {
+	stats := &CampaignStats{Links: []CampaignLink{}}
+
+	query := `SELECT COUNT(*), COUNT(opened_at), COALESCE(SUM(opens), 0), COUNT(clicked_at), COALESCE(SUM(clicks), 0),
+	                 COUNT(bounced_at), COUNT(unsubscribed_at)
+	          FROM campaign_recipients WHERE campaign_id = ?`
+	err := r.db.QueryRow(query, campaignID).Scan(&stats.Recipients, &stats.Opens, &stats.TotalOpens, &stats.Clicks,
+		&stats.TotalClicks, &stats.Bounces, &stats.Unsubscribes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT status, COUNT(*) FROM email_queue WHERE campaign_id = ? GROUP BY status`,
+		campaignID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		switch status {
+		case EmailSent:
+			stats.Sent = count
+		case EmailPending:
+			stats.Pending = count
+		case EmailFailed:
+			stats.Failed = count
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(`SELECT url, clicks FROM campaign_links WHERE campaign_id = ? ORDER BY clicks DESC, url`,
+		campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link CampaignLink
+		if err := rows.Scan(&link.URL, &link.Clicks); err != nil {
+			return nil, err
+		}
+		stats.Links = append(stats.Links, link)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// NewsletterRepositoryInterface defines the contract for newsletter lists,
+// subscribers, campaigns and campaign statistics
+type NewsletterRepositoryInterface interface {
+	CreateList(list *NewsletterList) error
+	FindLists() ([]*NewsletterList, error)
+	FindListByID(id int64) (*NewsletterList, error)
+	FindListBySlug(slug string) (*NewsletterList, error)
+	DeleteList(id int64) (bool, error)
+	CreateSubscriber(sub *Subscriber) error
+	UpdateSubscriber(sub *Subscriber) error
+	FindSubscriber(listID int64, email string) (*Subscriber, error)
+	FindSubscriberByToken(token string) (*Subscriber, error)
+	FindSubscribersByEmail(email string) ([]*Subscriber, error)
+	FindSubscribers(listID int64, status string, limit, offset int) ([]*Subscriber, error)
+	FindConfirmedSubscribers(listID int64) ([]*Subscriber, error)
+	CreateCampaign(campaign *Campaign) error
+	UpdateCampaign(campaign *Campaign) error
+	FindCampaignByID(id int64) (*Campaign, error)
+	FindCampaigns(limit, offset int) ([]*Campaign, error)
+	FindDueCampaigns(now time.Time) ([]*Campaign, error)
+	DeleteCampaign(id int64) (bool, error)
+	AddRecipient(campaignID, subscriberID int64) error
+	RecordOpen(campaignID int64, token string) (bool, error)
+	RecordClick(campaignID int64, token, url string) (bool, error)
+	RecordBounce(campaignID, subscriberID int64) error
+	RecordUnsubscribe(campaignID, subscriberID int64) error
+	FindLatestRecipient(subscriberIDs []int64) (campaignID, subscriberID int64, err error)
+	FindCampaignStats(campaignID int64) (*CampaignStats, error)
+}
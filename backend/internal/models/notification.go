@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Notification kinds
+const (
+	NotificationNewPost      = "new_post"      // a followed author, tag or series got a published post
+	NotificationCommentReply = "comment_reply" // someone replied to the user's comment
+	NotificationNewComment   = "new_comment"   // someone commented on the user's post
+)
+
+// Notification is an entry in a user's inbox
+type Notification struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Kind       string     `json:"kind" db:"kind"`
+	PostID     int64      `json:"post_id" db:"post_id"`
+	PostTitle  string     `json:"post_title" db:"post_title"`
+	PostSlug   string     `json:"post_slug" db:"post_slug"`
+	CommentID  int64      `json:"comment_id,omitempty" db:"comment_id"`   // the reply or comment, for comment_reply and new_comment
+	SourceType string     `json:"source_type,omitempty" db:"source_type"` // the follow behind a new_post
+	SourceID   int64      `json:"source_id,omitempty" db:"source_id"`
+	ReadAt     *time.Time `json:"read_at" db:"read_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+const notificationColumns = `n.id, n.user_id, n.kind, n.post_id, p.title, COALESCE(p.slug, ''), n.comment_id,
+	n.source_type, n.source_id, n.read_at, n.created_at`
+
+func scanNotification(row rowScanner) (*Notification, error) {
+	notification := &Notification{}
+	var readAt sql.NullTime
+	err := row.Scan(
+		&notification.ID, &notification.UserID, &notification.Kind, &notification.PostID,
+		&notification.PostTitle, &notification.PostSlug, &notification.CommentID,
+		&notification.SourceType, &notification.SourceID, &readAt, &notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	notification.ReadAt = nullTimePtr(readAt)
+	return notification, nil
+}
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// CreateMany stores the notifications and sets their IDs. A notification
+// the user already has is kept as it is, so storing one twice is harmless.
+func (r *NotificationRepository) CreateMany(notifications []*Notification) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, notification := range notifications {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO notifications
+		                        (user_id, kind, post_id, comment_id, source_type, source_id)
+		                        VALUES (?, ?, ?, ?, ?, ?)`,
+			notification.UserID, notification.Kind, notification.PostID, notification.CommentID,
+			notification.SourceType, notification.SourceID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`SELECT id, created_at FROM notifications
+		                   WHERE user_id = ? AND kind = ? AND post_id = ? AND comment_id = ?`,
+			notification.UserID, notification.Kind, notification.PostID, notification.CommentID).
+			Scan(&notification.ID, &notification.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindByUser lists the user's notifications, newest first
+func (r *NotificationRepository) FindByUser(userID int64, unreadOnly bool, limit, offset int) ([]*Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications n
+	          JOIN posts p ON p.id = n.post_id
+	          WHERE n.user_id = ?`
+	if unreadOnly {
+		query += " AND n.read_at IS NULL"
+	}
+	query += " ORDER BY n.id DESC LIMIT ? OFFSET ?"
+
+	return r.queryNotifications(query, userID, limit, offset)
+}
+
+// FindSince returns the user's notifications of a kind created after
+// since, oldest first
+func (r *NotificationRepository) FindSince(userID int64, kind string, since time.Time, limit int) ([]*Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications n
+	          JOIN posts p ON p.id = n.post_id
+	          WHERE n.user_id = ? AND n.kind = ? AND n.created_at > ?
+	          ORDER BY n.id LIMIT ?`
+	return r.queryNotifications(query, userID, kind, since.UTC(), limit)
+}
+
+func (r *NotificationRepository) CountUnread(userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID).
+		Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications read. It reports false
+// when the user has no such notification.
+func (r *NotificationRepository) MarkRead(userID, id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+	                          WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// MarkAllRead marks every unread notification of the user read and
+// returns how many there were
+func (r *NotificationRepository) MarkAllRead(userID int64) (int64, error) {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL`,
+		userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *NotificationRepository) queryNotifications(query string, args ...interface{}) ([]*Notification, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// NotificationRepositoryInterface defines the contract for users'
+// notification inboxes
+type NotificationRepositoryInterface interface {
+	CreateMany(notifications []*Notification) error
+	FindByUser(userID int64, unreadOnly bool, limit, offset int) ([]*Notification, error)
+	FindSince(userID int64, kind string, since time.Time, limit int) ([]*Notification, error)
+	CountUnread(userID int64) (int, error)
+	MarkRead(userID, id int64) (bool, error)
+	MarkAllRead(userID int64) (int64, error)
+}
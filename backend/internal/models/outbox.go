@@ -0,0 +1,317 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is an event waiting to be delivered to observers. It is written
+// in the same transaction as the change it describes, so an event exists if
+// and only if the change was committed.
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	EventType     EventType       `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	PostID        int64           `json:"post_id"`
+	ActorID       int64           `json:"actor_id,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// DeadLetter is an event that exhausted its delivery attempts
+type DeadLetter struct {
+	ID              int64           `json:"id"`
+	EventID         int64           `json:"event_id"`
+	EventType       EventType       `json:"event_type"`
+	SchemaVersion   int             `json:"schema_version"`
+	PostID          int64           `json:"post_id"`
+	ActorID         int64           `json:"actor_id,omitempty"`
+	CorrelationID   string          `json:"correlation_id,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+	Attempts        int             `json:"attempts"`
+	LastError       string          `json:"last_error"`
+	FailedObservers string          `json:"failed_observers"` // comma-separated observer names
+	CreatedAt       time.Time       `json:"created_at"`
+	DeadAt          time.Time       `json:"dead_at"`
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// appendOutboxEvent records an event using the caller's transaction. The
+// payload is stored at the current EventSchemaVersion.
+func appendOutboxEvent(q execer, postID int64, data EventPayload, meta EventMeta) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO outbox (event_type, schema_version, post_id, actor_id, correlation_id, payload, next_attempt_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = q.Exec(query, string(data.EventType()), EventSchemaVersion, postID, meta.ActorID, meta.CorrelationID,
+		string(payload), time.Now().UTC())
+	return err
+}
+
+type OutboxRepository struct {
+	db     *sql.DB
+	signal chan struct{} // wakes the dispatcher when events are committed
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db, signal: make(chan struct{}, 1)}
+}
+
+// Signal fires after events are committed; it may also fire spuriously
+func (r *OutboxRepository) Signal() <-chan struct{} {
+	return r.signal
+}
+
+// commit commits a transaction that appended outbox events, then signals
+// that they can be delivered. Repositories without an outbox just commit.
+func (r *OutboxRepository) commit(tx *sql.Tx) error {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if r != nil {
+		r.notify()
+	}
+	return nil
+}
+
+func (r *OutboxRepository) notify() {
+	select {
+	case r.signal <- struct{}{}:
+	default:
+	}
+}
+
+const outboxColumns = `o.id, o.event_type, o.schema_version, o.post_id, o.actor_id, o.correlation_id, o.payload,
+	o.attempts, o.next_attempt_at, o.last_error, o.created_at`
+
+// FindDue returns events ready for delivery in insertion order. An event is
+// held back while an earlier event for the same post is still pending, which
+// keeps delivery ordered per post even across retries.
+func (r *OutboxRepository) FindDue(now time.Time, limit int) ([]*OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox o
+	          WHERE o.completed_at IS NULL AND o.next_attempt_at <= ?
+	            AND NOT EXISTS (SELECT 1 FROM outbox e WHERE e.post_id = o.post_id AND e.id < o.id AND e.completed_at IS NULL)
+	          ORDER BY o.id LIMIT ?`
+	return r.queryEvents(query, now.UTC(), limit)
+}
+
+// FindPending lists every undelivered event, oldest first
+func (r *OutboxRepository) FindPending(limit, offset int) ([]*OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox o WHERE o.completed_at IS NULL ORDER BY o.id LIMIT ? OFFSET ?`
+	return r.queryEvents(query, limit, offset)
+}
+
+func (r *OutboxRepository) CountPending() (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM outbox WHERE completed_at IS NULL`).Scan(&n)
+	return n, err
+}
+
+// FindAfter returns the events recorded after afterID in ID order, whether
+// delivered or not. SQLite serialises writers, so IDs are assigned in
+// commit order and a reader following them by ID misses nothing; only a
+// replayed dead letter comes back under an ID that was already passed.
+func (r *OutboxRepository) FindAfter(afterID int64, limit int) ([]*OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox o WHERE o.id > ? ORDER BY o.id LIMIT ?`
+	return r.queryEvents(query, afterID, limit)
+}
+
+// LatestID returns the ID of the most recent event, or 0 if there is none
+func (r *OutboxRepository) LatestID() (int64, error) {
+	var id int64
+	err := r.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM outbox`).Scan(&id)
+	return id, err
+}
+
+// FindDelivered returns the observers that already received the event
+func (r *OutboxRepository) FindDelivered(eventID int64) (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT observer FROM outbox_deliveries WHERE event_id = ?`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delivered := make(map[string]bool)
+	for rows.Next() {
+		var observer string
+		if err := rows.Scan(&observer); err != nil {
+			return nil, err
+		}
+		delivered[observer] = true
+	}
+
+	return delivered, rows.Err()
+}
+
+func (r *OutboxRepository) MarkDelivered(eventID int64, observer string) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO outbox_deliveries (event_id, observer) VALUES (?, ?)`, eventID, observer)
+	return err
+}
+
+// Complete marks an event delivered once every observer has received it.
+// The event is kept until PruneCompleted so replicas following the outbox
+// can still read it.
+func (r *OutboxRepository) Complete(eventID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM outbox_deliveries WHERE event_id = ?`, eventID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE outbox SET completed_at = ? WHERE id = ?`, time.Now().UTC(), eventID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PruneCompleted deletes the events delivered before the given time
+func (r *OutboxRepository) PruneCompleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM outbox WHERE completed_at IS NOT NULL AND completed_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ScheduleRetry records a failed attempt and when to try again
+func (r *OutboxRepository) ScheduleRetry(eventID int64, attempts int, next time.Time, lastError string) error {
+	query := `UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`
+	_, err := r.db.Exec(query, attempts, next.UTC(), lastError, eventID)
+	return err
+}
+
+// MoveToDeadLetter parks an event that will not be retried. Delivery records
+// are kept so a replay only reaches the observers that failed.
+func (r *OutboxRepository) MoveToDeadLetter(event *OutboxEvent, failedObservers, lastError string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO outbox_dead_letters
+	          (event_id, event_type, schema_version, post_id, actor_id, correlation_id, payload,
+	           attempts, last_error, failed_observers, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, event.ID, string(event.EventType), event.SchemaVersion, event.PostID, event.ActorID,
+		event.CorrelationID, string(event.Payload), event.Attempts, lastError, failedObservers, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM outbox WHERE id = ?`, event.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *OutboxRepository) FindDeadLetters(limit, offset int) ([]*DeadLetter, error) {
+	query := `SELECT id, event_id, event_type, schema_version, post_id, actor_id, correlation_id, payload,
+	                 attempts, last_error, failed_observers, created_at, dead_at
+	          FROM outbox_dead_letters ORDER BY id DESC LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		letter := &DeadLetter{}
+		var payload string
+		err := rows.Scan(&letter.ID, &letter.EventID, &letter.EventType, &letter.SchemaVersion, &letter.PostID,
+			&letter.ActorID, &letter.CorrelationID, &payload, &letter.Attempts, &letter.LastError, &letter.FailedObservers, &letter.CreatedAt, &letter.DeadAt)
+		if err != nil {
+			return nil, err
+		}
+		letter.Payload = rawPayload(payload)
+		letters = append(letters, letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// ReplayDeadLetter puts a dead event back in the outbox under its original
+// ID with a fresh attempt budget. It returns false if the letter does not exist.
+func (r *OutboxRepository) ReplayDeadLetter(id int64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO outbox (id, event_type, schema_version, post_id, actor_id, correlation_id, payload,
+	                              attempts, next_attempt_at, last_error, created_at)
+	          SELECT event_id, event_type, schema_version, post_id, actor_id, correlation_id, payload,
+	                 0, ?, '', created_at
+	          FROM outbox_dead_letters WHERE id = ?`
+	result, err := tx.Exec(query, time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM outbox_dead_letters WHERE id = ?`, id); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	r.notify()
+	return true, nil
+}
+
+func (r *OutboxRepository) queryEvents(query string, args ...interface{}) ([]*OutboxEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*OutboxEvent
+	for rows.Next() {
+		event := &OutboxEvent{}
+		var payload string
+		err := rows.Scan(&event.ID, &event.EventType, &event.SchemaVersion, &event.PostID, &event.ActorID,
+			&event.CorrelationID, &payload, &event.Attempts, &event.NextAttemptAt, &event.LastError, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = rawPayload(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// rawPayload keeps a stored payload as-is, quoting it when it is not valid
+// JSON so a corrupt event can still be listed
+func rawPayload(payload string) json.RawMessage {
+	if json.Valid([]byte(payload)) {
+		return json.RawMessage(payload)
+	}
+	quoted, _ := json.Marshal(payload)
+	return quoted
+}
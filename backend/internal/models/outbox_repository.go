@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// OutboxRepositoryInterface defines the contract for reading and settling
+// outbox events. Events are appended by the repositories that make the changes.
+type OutboxRepositoryInterface interface {
+	Signal() <-chan struct{}
+	FindDue(now time.Time, limit int) ([]*OutboxEvent, error)
+	FindPending(limit, offset int) ([]*OutboxEvent, error)
+	CountPending() (int, error)
+	FindAfter(afterID int64, limit int) ([]*OutboxEvent, error)
+	LatestID() (int64, error)
+	FindDelivered(eventID int64) (map[string]bool, error)
+	MarkDelivered(eventID int64, observer string) error
+	Complete(eventID int64) error
+	PruneCompleted(before time.Time) (int64, error)
+	ScheduleRetry(eventID int64, attempts int, next time.Time, lastError string) error
+	MoveToDeadLetter(event *OutboxEvent, failedObservers, lastError string) error
+	FindDeadLetters(limit, offset int) ([]*DeadLetter, error)
+	ReplayDeadLetter(id int64) (bool, error)
+}
@@ -2,33 +2,173 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
+
+	"blog-platform/pkg/slug"
+)
+
+// Content formats
+const (
+	FormatMarkdown  = "markdown"
+	FormatHTML      = "html"
+	FormatPlainText = "plaintext"
 )
 
 type Post struct {
 	ID        int64     `json:"id" db:"id"`
 	Title     string    `json:"title" db:"title"`
 	Content   string    `json:"content" db:"content"`
-	Type      string    `json:"type" db:"type"` // article, tutorial, review
+	Format    string    `json:"format" db:"format"` // markdown, html, plaintext
+	Type      string    `json:"type" db:"type"`     // article, tutorial, review
 	AuthorID  int64     `json:"author_id" db:"author_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	Status    string    `json:"status" db:"status"` // draft, published, archived
+	Slug      string    `json:"slug" db:"slug"`
+
+	// Locale is the BCP 47 language tag of the post, e.g. en or pt-BR.
+	// Posts that translate each other share a TranslationGroup, named after
+	// the ID of the post the group started from.
+	Locale           string `json:"locale" db:"locale"`
+	TranslationGroup *int64 `json:"translation_group,omitempty" db:"translation_group"`
+
+	// CoverImage is the featured image shown with the post, as a URL
+	CoverImage string  `json:"cover_image,omitempty" db:"cover_image"`
+	SEO        PostSEO `json:"seo"`
+
+	// Scheduled status transitions, cleared once the scheduler applies them
+	PublishAt   *time.Time `json:"publish_at,omitempty" db:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty" db:"unpublish_at"`
+}
+
+// PostSEO overrides the search engine and social card metadata of a post.
+// Empty fields are derived from the post itself when the metadata is built.
+type PostSEO struct {
+	MetaTitle       string `json:"meta_title,omitempty" db:"meta_title"`
+	MetaDescription string `json:"meta_description,omitempty" db:"meta_description"`
+	CanonicalURL    string `json:"canonical_url,omitempty" db:"canonical_url"`
+	SocialImage     string `json:"social_image,omitempty" db:"social_image"` // Open Graph and Twitter card image
+	NoIndex         bool   `json:"noindex" db:"noindex"`
+}
+
+// PostModification is the last change of a published post, enough to list
+// it in a sitemap without loading its content
+type PostModification struct {
+	ID               int64     `json:"id" db:"id"`
+	Slug             string    `json:"slug" db:"slug"`
+	Locale           string    `json:"locale" db:"locale"`
+	TranslationGroup int64     `json:"translation_group" db:"translation_group"` // 0 when untranslated
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PostTranslation identifies one language version of a post
+type PostTranslation struct {
+	ID               int64  `json:"id" db:"id"`
+	TranslationGroup int64  `json:"translation_group" db:"translation_group"`
+	Locale           string `json:"locale" db:"locale"`
+	Title            string `json:"title" db:"title"`
+	Slug             string `json:"slug" db:"slug"`
+	Status           string `json:"status" db:"status"`
+}
+
+// postColumns is the column list selected for a post, qualified with the
+// "p" alias so it can be reused in joins
+const postColumns = `p.id, p.title, p.content, p.format, p.type, p.author_id, p.created_at, p.updated_at, p.status,
+	COALESCE(p.slug, ''), p.publish_at, p.unpublish_at, p.locale, p.translation_group, p.cover_image,
+	p.meta_title, p.meta_description, p.canonical_url, p.social_image, p.noindex`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost reads a row selected with postColumns into a Post
+func scanPost(row rowScanner) (*Post, error) {
+	post := &Post{}
+	var publishAt, unpublishAt sql.NullTime
+	var translationGroup sql.NullInt64
+	err := row.Scan(
+		&post.ID, &post.Title, &post.Content, &post.Format, &post.Type,
+		&post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.Status,
+		&post.Slug, &publishAt, &unpublishAt, &post.Locale, &translationGroup, &post.CoverImage,
+		&post.SEO.MetaTitle, &post.SEO.MetaDescription, &post.SEO.CanonicalURL, &post.SEO.SocialImage, &post.SEO.NoIndex,
+	)
+	if err != nil {
+		return nil, err
+	}
+	post.PublishAt = nullTimePtr(publishAt)
+	post.UnpublishAt = nullTimePtr(unpublishAt)
+	if translationGroup.Valid {
+		post.TranslationGroup = &translationGroup.Int64
+	}
+	return post, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// utcTimePtr normalises optional times to UTC so they compare correctly as
+// stored text
+func utcTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// scanPosts reads every row selected with postColumns
+func scanPosts(rows *sql.Rows) ([]*Post, error) {
+	defer rows.Close()
+
+	var posts []*Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
 }
 
 type PostRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	outbox *OutboxRepository
 }
 
-func NewPostRepository(db *sql.DB) *PostRepository {
-	return &PostRepository{db: db}
+// NewPostRepository creates a repository recording its events in outbox
+func NewPostRepository(db *sql.DB, outbox *OutboxRepository) *PostRepository {
+	return &PostRepository{db: db, outbox: outbox}
 }
 
-func (r *PostRepository) Create(post *Post) error {
-	query := `INSERT INTO posts (title, content, type, author_id, status) 
-	          VALUES (?, ?, ?, ?, ?)`
+// Create inserts the post with its tags and categories and records a
+// post_created event in the outbox within the same transaction
+func (r *PostRepository) Create(post *Post, taxonomy PostTaxonomy, meta EventMeta) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// An empty slug is assigned below, once the ID is known
+	var postSlug interface{}
+	if post.Slug != "" {
+		postSlug = post.Slug
+	}
 
-	result, err := r.db.Exec(query, post.Title, post.Content, post.Type, post.AuthorID, post.Status)
+	query := `INSERT INTO posts (title, content, format, type, author_id, status, slug, publish_at, unpublish_at,
+	          locale, translation_group, cover_image, meta_title, meta_description, canonical_url, social_image, noindex)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.Exec(query, post.Title, post.Content, post.Format, post.Type, post.AuthorID, post.Status, postSlug,
+		utcTimePtr(post.PublishAt), utcTimePtr(post.UnpublishAt), post.Locale, post.TranslationGroup, post.CoverImage,
+		post.SEO.MetaTitle, post.SEO.MetaDescription, post.SEO.CanonicalURL, post.SEO.SocialImage, post.SEO.NoIndex)
 	if err != nil {
 		return err
 	}
@@ -40,23 +180,40 @@ func (r *PostRepository) Create(post *Post) error {
 	}
 	post.ID = id
 
+	if post.Slug == "" {
+		if post.Slug, err = uniqueSlug(tx, fallbackSlug(id)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE posts SET slug = ? WHERE id = ?`, post.Slug, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := setPostTaxonomy(tx, post.ID, taxonomy); err != nil {
+		return err
+	}
+
 	// Fetch the created_at and updated_at timestamps
 	selectQuery := `SELECT created_at, updated_at FROM posts WHERE id = ?`
-	err = r.db.QueryRow(selectQuery, post.ID).Scan(&post.CreatedAt, &post.UpdatedAt)
+	if err := tx.QueryRow(selectQuery, post.ID).Scan(&post.CreatedAt, &post.UpdatedAt); err != nil {
+		return err
+	}
 
-	return err
+	if err := appendOutboxEvent(tx, post.ID, PostCreated{Post: post}, meta); err != nil {
+		return err
+	}
+
+	if err := startTranslationGroup(tx, post, meta); err != nil {
+		return err
+	}
+
+	return r.outbox.commit(tx)
 }
 
 func (r *PostRepository) FindByID(id int64) (*Post, error) {
-	query := `SELECT id, title, content, type, author_id, created_at, updated_at, status 
-	          FROM posts WHERE id = ?`
-
-	post := &Post{}
-	err := r.db.QueryRow(query, id).Scan(
-		&post.ID, &post.Title, &post.Content, &post.Type,
-		&post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.Status,
-	)
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.id = ?`
 
+	post, err := scanPost(r.db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -65,63 +222,509 @@ func (r *PostRepository) FindByID(id int64) (*Post, error) {
 }
 
 func (r *PostRepository) FindAll(status, contentType string, limit, offset int) ([]*Post, error) {
-	query := `SELECT id, title, content, type, author_id, created_at, updated_at, status 
-	          FROM posts WHERE 1=1`
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE 1=1`
 	var queryParams []interface{}
 
 	if status != "" {
-		query += " AND status = ?"
+		query += " AND p.status = ?"
 		queryParams = append(queryParams, status)
 	}
 
 	if contentType != "" {
-		query += " AND type = ?"
+		query += " AND p.type = ?"
 		queryParams = append(queryParams, contentType)
 	}
 
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	query += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
 	queryParams = append(queryParams, limit, offset)
 
 	rows, err := r.db.Query(query, queryParams...)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanPosts(rows)
+}
+
+// FindAllInLocales lists posts like FindAll, keeping one version of each
+// translated post: the one whose locale comes first in locales. Posts in
+// other locales are left out.
+func (r *PostRepository) FindAllInLocales(locales []string, status, contentType string, limit, offset int) ([]*Post, error) {
+	// rank orders a post's locale by preference, lower first
+	rank := func(alias string) string {
+		expr := "CASE " + alias + ".locale"
+		for range locales {
+			expr += " WHEN ? THEN ?"
+		}
+		return expr + fmt.Sprintf(" ELSE %d END", len(locales))
+	}
+	var rankArgs []interface{}
+	for i, locale := range locales {
+		rankArgs = append(rankArgs, locale, i)
+	}
+
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.locale IN (` + placeholders(len(locales)) + `)`
+	var queryParams []interface{}
+	for _, locale := range locales {
+		queryParams = append(queryParams, locale)
+	}
+
+	if status != "" {
+		query += " AND p.status = ?"
+		queryParams = append(queryParams, status)
+	}
+
+	if contentType != "" {
+		query += " AND p.type = ?"
+		queryParams = append(queryParams, contentType)
+	}
+
+	query += ` AND (p.translation_group IS NULL OR NOT EXISTS (
+	               SELECT 1 FROM posts t WHERE t.translation_group = p.translation_group AND t.id != p.id`
+	if status != "" {
+		query += " AND t.status = ?"
+		queryParams = append(queryParams, status)
+	}
+	query += " AND " + rank("t") + " < " + rank("p") + "))"
+	queryParams = append(queryParams, rankArgs...)
+	queryParams = append(queryParams, rankArgs...)
+
+	query += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// FindTranslations returns the posts of each translation group, keyed by
+// group
+func (r *PostRepository) FindTranslations(groups []int64) (map[int64][]*PostTranslation, error) {
+	translations := make(map[int64][]*PostTranslation)
+	if len(groups) == 0 {
+		return translations, nil
+	}
+
+	query := `SELECT id, translation_group, locale, title, COALESCE(slug, ''), status FROM posts
+	          WHERE translation_group IN (` + placeholders(len(groups)) + `) ORDER BY locale, id`
+
+	rows, err := r.db.Query(query, int64Args(groups)...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var posts []*Post
 	for rows.Next() {
-		post := &Post{}
-		err := rows.Scan(
-			&post.ID, &post.Title, &post.Content, &post.Type,
-			&post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.Status,
-		)
-		if err != nil {
+		t := &PostTranslation{}
+		if err := rows.Scan(&t.ID, &t.TranslationGroup, &t.Locale, &t.Title, &t.Slug, &t.Status); err != nil {
 			return nil, err
 		}
-		posts = append(posts, post)
+		translations[t.TranslationGroup] = append(translations[t.TranslationGroup], t)
+	}
+
+	return translations, rows.Err()
+}
+
+// AssignDefaultLocale gives posts written before locales existed the
+// site's default locale
+func (r *PostRepository) AssignDefaultLocale(locale string) (int64, error) {
+	result, err := r.db.Exec(`UPDATE posts SET locale = ? WHERE locale = ''`, locale)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// FindByAuthor lists an author's posts, newest first
+func (r *PostRepository) FindByAuthor(authorID int64, status string, limit, offset int) ([]*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.author_id = ?`
+	queryParams := []interface{}{authorID}
+
+	if status != "" {
+		query += " AND p.status = ?"
+		queryParams = append(queryParams, status)
+	}
+
+	query += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// FindPublishedModifications lists the last change of every published post
+// that search engines may index, oldest post first
+func (r *PostRepository) FindPublishedModifications() ([]*PostModification, error) {
+	rows, err := r.db.Query(`SELECT id, COALESCE(slug, ''), locale, COALESCE(translation_group, 0), updated_at
+	                         FROM posts WHERE status = 'published' AND noindex = 0 ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var modifications []*PostModification
+	for rows.Next() {
+		m := &PostModification{}
+		if err := rows.Scan(&m.ID, &m.Slug, &m.Locale, &m.TranslationGroup, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		modifications = append(modifications, m)
+	}
+
+	return modifications, rows.Err()
+}
+
+// FindTrending returns published posts in the window's ranking by
+// time-decayed views, highest first
+func (r *PostRepository) FindTrending(window, contentType string, limit, offset int) ([]*Post, error) {
+	return r.findRanked("r.score", window, contentType, limit, offset)
+}
+
+// FindPopular returns published posts in the window's ranking by views
+// within the window, highest first
+func (r *PostRepository) FindPopular(window, contentType string, limit, offset int) ([]*Post, error) {
+	return r.findRanked("r.views", window, contentType, limit, offset)
+}
+
+func (r *PostRepository) findRanked(orderBy, window, contentType string, limit, offset int) ([]*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM post_rankings r JOIN posts p ON p.id = r.post_id
+	          WHERE r.period = ? AND p.status = 'published'`
+	queryParams := []interface{}{window}
+
+	if contentType != "" {
+		query += " AND p.type = ?"
+		queryParams = append(queryParams, contentType)
+	}
+
+	query += " ORDER BY " + orderBy + " DESC, p.id DESC LIMIT ? OFFSET ?"
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// FindBySlug returns the post currently using the slug
+func (r *PostRepository) FindBySlug(slug string) (*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.slug = ?`
+
+	post, err := scanPost(r.db.QueryRow(query, slug))
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
 
-	return posts, nil
+	return post, err
+}
+
+// FindSlugRedirect returns the ID of the post that used to have the slug,
+// or 0 if the slug was never retired
+func (r *PostRepository) FindSlugRedirect(slug string) (int64, error) {
+	var postID int64
+	err := r.db.QueryRow(`SELECT post_id FROM post_slug_redirects WHERE old_slug = ?`, slug).Scan(&postID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return postID, err
 }
 
-func (r *PostRepository) Update(post *Post) error {
-	query := `UPDATE posts SET title = ?, content = ?, type = ?, status = ?, updated_at = CURRENT_TIMESTAMP
+// Update saves the post with its tags and categories and records a
+// post_updated event. When the slug changes, the previous slug is kept in
+// post_slug_redirects so old permalinks keep resolving.
+func (r *PostRepository) Update(post *Post, taxonomy PostTaxonomy, meta EventMeta) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, post.ID))
+	if err != nil {
+		return err
+	}
+	oldSlug := before.Slug
+
+	query := `UPDATE posts SET title = ?, content = ?, format = ?, type = ?, status = ?, slug = ?,
+	          publish_at = ?, unpublish_at = ?, locale = ?, translation_group = ?, cover_image = ?, meta_title = ?,
+	          meta_description = ?, canonical_url = ?, social_image = ?, noindex = ?, updated_at = CURRENT_TIMESTAMP
 	          WHERE id = ?`
 
-	_, err := r.db.Exec(query, post.Title, post.Content, post.Type, post.Status, post.ID)
+	_, err = tx.Exec(query, post.Title, post.Content, post.Format, post.Type, post.Status, post.Slug,
+		utcTimePtr(post.PublishAt), utcTimePtr(post.UnpublishAt), post.Locale, post.TranslationGroup, post.CoverImage,
+		post.SEO.MetaTitle, post.SEO.MetaDescription, post.SEO.CanonicalURL, post.SEO.SocialImage, post.SEO.NoIndex, post.ID)
+	if err != nil {
+		return err
+	}
+
+	if oldSlug != "" && oldSlug != post.Slug {
+		if err := recordSlugChange(tx, post.ID, oldSlug, post.Slug); err != nil {
+			return err
+		}
+	}
+
+	associations, err := setPostTaxonomy(tx, post.ID, taxonomy)
 	if err != nil {
 		return err
 	}
 
 	// Fetch the updated timestamp
 	selectQuery := `SELECT updated_at FROM posts WHERE id = ?`
-	err = r.db.QueryRow(selectQuery, post.ID).Scan(&post.UpdatedAt)
+	if err := tx.QueryRow(selectQuery, post.ID).Scan(&post.UpdatedAt); err != nil {
+		return err
+	}
 
+	changes := postChanges(before, post)
+	for name, change := range associations {
+		changes[name] = change
+	}
+	updated := PostUpdated{Post: post, Changes: changes}
+	if err := appendOutboxEvent(tx, post.ID, updated, meta); err != nil {
+		return err
+	}
+
+	if int64Value(before.TranslationGroup) != int64Value(post.TranslationGroup) {
+		if err := startTranslationGroup(tx, post, meta); err != nil {
+			return err
+		}
+	}
+
+	return r.outbox.commit(tx)
+}
+
+// startTranslationGroup adds the post a group is named after to the group
+// when the saved post is the first to join it, recording a post_updated
+// event for that post
+func startTranslationGroup(tx *sql.Tx, post *Post, meta EventMeta) error {
+	if post.TranslationGroup == nil || *post.TranslationGroup == post.ID {
+		return nil
+	}
+	group := *post.TranslationGroup
+
+	before, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, group))
+	if err != nil {
+		return err
+	}
+	if before.TranslationGroup != nil {
+		return nil
+	}
+
+	_, err = tx.Exec(`UPDATE posts SET translation_group = id, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, group)
+	if err != nil {
+		return err
+	}
+
+	source, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, group))
+	if err != nil {
+		return err
+	}
+
+	updated := PostUpdated{Post: source, Changes: postChanges(before, source)}
+	return appendOutboxEvent(tx, source.ID, updated, meta)
+}
+
+// recordSlugChange points the old slug at the post and drops any redirect
+// for the new slug, which only a post taking back one of its own retired
+// slugs can be using
+func recordSlugChange(tx *sql.Tx, postID int64, oldSlug, newSlug string) error {
+	if _, err := tx.Exec(`DELETE FROM post_slug_redirects WHERE old_slug = ?`, newSlug); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`INSERT OR REPLACE INTO post_slug_redirects (old_slug, post_id) VALUES (?, ?)`, oldSlug, postID)
 	return err
 }
 
-func (r *PostRepository) Delete(id int64) error {
+// PublishDue publishes every draft whose publish_at has passed and returns
+// the posts it changed. Each row is updated conditionally, so when several
+// processes race only one of them sees a given post transition.
+func (r *PostRepository) PublishDue(now time.Time) ([]*Post, error) {
+	return r.transitionDue(`publish_at`, `draft`, `published`, now)
+}
+
+// UnpublishDue archives every published post whose unpublish_at has passed
+func (r *PostRepository) UnpublishDue(now time.Time) ([]*Post, error) {
+	return r.transitionDue(`unpublish_at`, `published`, `archived`, now)
+}
+
+func (r *PostRepository) transitionDue(column, from, to string, now time.Time) ([]*Post, error) {
+	now = now.UTC()
+
+	rows, err := r.db.Query(`SELECT id FROM posts WHERE status = ? AND `+column+` <= ?`, from, now)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var changed []*Post
+	for _, id := range ids {
+		post, err := r.transitionOne(id, column, from, to, now)
+		if err != nil {
+			return changed, err
+		}
+		if post != nil {
+			changed = append(changed, post)
+		}
+	}
+
+	return changed, nil
+}
+
+// transitionOne applies a single scheduled transition together with the
+// post_updated event a manual status change would record. It returns nil if
+// the post no longer qualifies.
+func (r *PostRepository) transitionOne(id int64, column, from, to string, now time.Time) (*Post, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`UPDATE posts SET status = ?, `+column+` = NULL, updated_at = CURRENT_TIMESTAMP
+	                        WHERE id = ? AND status = ? AND `+column+` <= ?`, to, id, from, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, nil // another process got there first
+	}
+
+	post, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	// Scheduled transitions are system actions, so the event has no actor
+	updated := PostUpdated{Post: post, Changes: postChanges(before, post)}
+	if err := appendOutboxEvent(tx, post.ID, updated, EventMeta{}); err != nil {
+		return nil, err
+	}
+
+	return post, r.outbox.commit(tx)
+}
+
+// Delete removes the post and records a post_deleted event carrying the
+// post as it was before deletion
+func (r *PostRepository) Delete(id int64, meta EventMeta) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	post, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM posts WHERE id = ?`
-	_, err := r.db.Exec(query, id)
-	return err
+	if _, err := tx.Exec(query, id); err != nil {
+		return err
+	}
+
+	if err := appendOutboxEvent(tx, id, PostDeleted{Post: post}, meta); err != nil {
+		return err
+	}
+
+	return r.outbox.commit(tx)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// fallbackSlug is the slug of a post whose title yields none, e.g. one
+// made only of unsupported characters
+func fallbackSlug(postID int64) string {
+	return fmt.Sprintf("post-%d", postID)
+}
+
+// uniqueSlug returns base, or base with a numeric suffix, so that no post
+// has the slug yet. Retired slugs stay taken so their redirects keep
+// pointing at the post that used them.
+func uniqueSlug(q queryRower, base string) (string, error) {
+	return slug.Unique(base, func(candidate string) (bool, error) {
+		var n int
+		err := q.QueryRow(`SELECT (SELECT COUNT(*) FROM posts WHERE slug = ?)
+		                        + (SELECT COUNT(*) FROM post_slug_redirects WHERE old_slug = ?)`,
+			candidate, candidate).Scan(&n)
+		return n > 0, err
+	})
+}
+
+// backfillPostSlugs assigns slugs to posts created before slugs existed
+func backfillPostSlugs(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, title FROM posts WHERE slug IS NULL OR slug = ''`)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		id    int64
+		title string
+	}
+	var posts []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title); err != nil {
+			rows.Close()
+			return err
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		base := slug.Make(p.title)
+		if base == "" {
+			base = fallbackSlug(p.id)
+		}
+
+		unique, err := uniqueSlug(db, base)
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.Exec(`UPDATE posts SET slug = ? WHERE id = ?`, unique, p.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
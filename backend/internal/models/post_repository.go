@@ -1,11 +1,24 @@
 package models
 
+import "time"
+
 // PostRepositoryInterface defines the contract for post repository operations
 // This interface enables the Proxy pattern by allowing different implementations
 type PostRepositoryInterface interface {
-	Create(post *Post) error
+	Create(post *Post, taxonomy PostTaxonomy, meta EventMeta) error
 	FindByID(id int64) (*Post, error)
+	FindBySlug(slug string) (*Post, error)
+	FindSlugRedirect(slug string) (int64, error)
 	FindAll(status, contentType string, limit, offset int) ([]*Post, error)
-	Update(post *Post) error
-	Delete(id int64) error
+	FindAllInLocales(locales []string, status, contentType string, limit, offset int) ([]*Post, error)
+	FindTranslations(groups []int64) (map[int64][]*PostTranslation, error)
+	FindByAuthor(authorID int64, status string, limit, offset int) ([]*Post, error)
+	FindPublishedModifications() ([]*PostModification, error)
+	FindTrending(window, contentType string, limit, offset int) ([]*Post, error)
+	FindPopular(window, contentType string, limit, offset int) ([]*Post, error)
+	Update(post *Post, taxonomy PostTaxonomy, meta EventMeta) error
+	Delete(id int64, meta EventMeta) error
+	PublishDue(now time.Time) ([]*Post, error)
+	UnpublishDue(now time.Time) ([]*Post, error)
+	AssignDefaultLocale(locale string) (int64, error)
 }
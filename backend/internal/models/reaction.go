@@ -0,0 +1,189 @@
+package models
+
+import "database/sql"
+
+// ReactionRepository stores readers' reactions and bookmarks. Each change
+// updates a counter row in the same transaction, so counts are read without
+// scanning the reactions themselves.
+type ReactionRepository struct {
+	db *sql.DB
+}
+
+func NewReactionRepository(db *sql.DB) *ReactionRepository {
+	return &ReactionRepository{db: db}
+}
+
+// AddReaction records the reader's reaction to a post. It reports false
+// when the reader had already reacted so.
+func (r *ReactionRepository) AddReaction(postID int64, reader, reaction string) (bool, error) {
+	return r.change(
+		`INSERT OR IGNORE INTO reactions (post_id, reader, reaction) VALUES (?, ?, ?)`,
+		[]interface{}{postID, reader, reaction},
+		`INSERT INTO reaction_counts (post_id, reaction, count) VALUES (?, ?, 1)
+		 ON CONFLICT(post_id, reaction) DO UPDATE SET count = count + 1`,
+		[]interface{}{postID, reaction},
+	)
+}
+
+// RemoveReaction withdraws the reader's reaction. It reports false when
+// there was none.
+func (r *ReactionRepository) RemoveReaction(postID int64, reader, reaction string) (bool, error) {
+	return r.change(
+		`DELETE FROM reactions WHERE post_id = ? AND reader = ? AND reaction = ?`,
+		[]interface{}{postID, reader, reaction},
+		`UPDATE reaction_counts SET count = MAX(count - 1, 0) WHERE post_id = ? AND reaction = ?`,
+		[]interface{}{postID, reaction},
+	)
+}
+
+// AddBookmark bookmarks a post for the reader. It reports false when the
+// post was already bookmarked.
+func (r *ReactionRepository) AddBookmark(postID int64, reader string) (bool, error) {
+	return r.change(
+		`INSERT OR IGNORE INTO bookmarks (reader, post_id) VALUES (?, ?)`,
+		[]interface{}{reader, postID},
+		`INSERT INTO bookmark_counts (post_id, count) VALUES (?, 1)
+		 ON CONFLICT(post_id) DO UPDATE SET count = count + 1`,
+		[]interface{}{postID},
+	)
+}
+
+// RemoveBookmark removes the reader's bookmark. It reports false when
+// there was none.
+func (r *ReactionRepository) RemoveBookmark(postID int64, reader string) (bool, error) {
+	return r.change(
+		`DELETE FROM bookmarks WHERE reader = ? AND post_id = ?`,
+		[]interface{}{reader, postID},
+		`UPDATE bookmark_counts SET count = MAX(count - 1, 0) WHERE post_id = ?`,
+		[]interface{}{postID},
+	)
+}
+
+// change runs a statement and, when it affected a row, the counter update
+// that goes with it
+func (r *ReactionRepository) change(query string, args []interface{}, counter string, counterArgs []interface{}) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(counter, counterArgs...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// FindReaderReactions returns the reactions the reader gave a post
+func (r *ReactionRepository) FindReaderReactions(postID int64, reader string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT reaction FROM reactions WHERE post_id = ? AND reader = ? ORDER BY created_at, reaction`,
+		postID, reader)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []string
+	for rows.Next() {
+		var reaction string
+		if err := rows.Scan(&reaction); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// IsBookmarked reports whether the reader bookmarked the post
+func (r *ReactionRepository) IsBookmarked(postID int64, reader string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM bookmarks WHERE reader = ? AND post_id = ?)`, reader, postID).
+		Scan(&exists)
+	return exists, err
+}
+
+// CountReactionsByPostIDs returns the non-zero reaction counts of each
+// post, keyed by post and reaction
+func (r *ReactionRepository) CountReactionsByPostIDs(postIDs []int64) (map[int64]map[string]int, error) {
+	counts := make(map[int64]map[string]int)
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `SELECT post_id, reaction, count FROM reaction_counts
+	          WHERE count > 0 AND post_id IN (` + placeholders(len(postIDs)) + `)`
+
+	rows, err := r.db.Query(query, int64Args(postIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var reaction string
+		var count int
+		if err := rows.Scan(&postID, &reaction, &count); err != nil {
+			return nil, err
+		}
+		if counts[postID] == nil {
+			counts[postID] = make(map[string]int)
+		}
+		counts[postID][reaction] = count
+	}
+	return counts, rows.Err()
+}
+
+// CountBookmarksByPostIDs returns how many readers bookmarked each post
+func (r *ReactionRepository) CountBookmarksByPostIDs(postIDs []int64) (map[int64]int, error) {
+	counts := make(map[int64]int)
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `SELECT post_id, count FROM bookmark_counts WHERE post_id IN (` + placeholders(len(postIDs)) + `)`
+
+	rows, err := r.db.Query(query, int64Args(postIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var count int
+		if err := rows.Scan(&postID, &count); err != nil {
+			return nil, err
+		}
+		counts[postID] = count
+	}
+	return counts, rows.Err()
+}
+
+// FindBookmarkedPosts returns the published posts the reader bookmarked,
+// most recently bookmarked first
+func (r *ReactionRepository) FindBookmarkedPosts(reader string, limit, offset int) ([]*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p
+	          JOIN bookmarks b ON b.post_id = p.id
+	          WHERE b.reader = ? AND p.status = 'published'
+	          ORDER BY b.created_at DESC, b.rowid DESC LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, reader, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
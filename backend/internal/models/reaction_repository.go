@@ -0,0 +1,15 @@
+package models
+
+// ReactionRepositoryInterface defines the contract for readers' reactions,
+// their bookmarks and the counters kept for both
+type ReactionRepositoryInterface interface {
+	AddReaction(postID int64, reader, reaction string) (bool, error)
+	RemoveReaction(postID int64, reader, reaction string) (bool, error)
+	AddBookmark(postID int64, reader string) (bool, error)
+	RemoveBookmark(postID int64, reader string) (bool, error)
+	FindReaderReactions(postID int64, reader string) ([]string, error)
+	IsBookmarked(postID int64, reader string) (bool, error)
+	CountReactionsByPostIDs(postIDs []int64) (map[int64]map[string]int, error)
+	CountBookmarksByPostIDs(postIDs []int64) (map[int64]int, error)
+	FindBookmarkedPosts(reader string, limit, offset int) ([]*Post, error)
+}
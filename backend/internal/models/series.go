@@ -0,0 +1,241 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Series is an ordered sequence of posts, such as a multi-part tutorial
+type Series struct {
+	ID          int64     `json:"id" db:"id"`
+	Title       string    `json:"title" db:"title"`
+	Slug        string    `json:"slug" db:"slug"`
+	Description string    `json:"description" db:"description"`
+	AuthorID    int64     `json:"author_id" db:"author_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SeriesPost is one part of a series
+type SeriesPost struct {
+	SeriesID int64  `json:"series_id" db:"series_id"`
+	PostID   int64  `json:"post_id" db:"post_id"`
+	Position int    `json:"position" db:"position"` // from 1
+	Title    string `json:"title" db:"title"`
+	Slug     string `json:"slug" db:"slug"`
+	Status   string `json:"status" db:"status"`
+}
+
+const seriesColumns = `id, title, slug, description, author_id, created_at, updated_at`
+
+func scanSeries(row rowScanner) (*Series, error) {
+	series := &Series{}
+	err := row.Scan(&series.ID, &series.Title, &series.Slug, &series.Description, &series.AuthorID,
+		&series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+type SeriesRepository struct {
+	db *sql.DB
+}
+
+func NewSeriesRepository(db *sql.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+func (r *SeriesRepository) Create(series *Series) error {
+	result, err := r.db.Exec(`INSERT INTO series (title, slug, description, author_id) VALUES (?, ?, ?, ?)`,
+		series.Title, series.Slug, series.Description, series.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	series.ID = id
+
+	return r.db.QueryRow(`SELECT created_at, updated_at FROM series WHERE id = ?`, id).
+		Scan(&series.CreatedAt, &series.UpdatedAt)
+}
+
+func (r *SeriesRepository) FindByID(id int64) (*Series, error) {
+	return r.findOne(`SELECT `+seriesColumns+` FROM series WHERE id = ?`, id)
+}
+
+func (r *SeriesRepository) FindBySlug(slug string) (*Series, error) {
+	return r.findOne(`SELECT `+seriesColumns+` FROM series WHERE slug = ?`, slug)
+}
+
+func (r *SeriesRepository) findOne(query string, arg interface{}) (*Series, error) {
+	series, err := scanSeries(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return series, err
+}
+
+// FindAll lists series, most recently changed first
+func (r *SeriesRepository) FindAll(limit, offset int) ([]*Series, error) {
+	rows, err := r.db.Query(`SELECT `+seriesColumns+` FROM series ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?`,
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []*Series
+	for rows.Next() {
+		item, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, item)
+	}
+	return series, rows.Err()
+}
+
+func (r *SeriesRepository) Update(series *Series) error {
+	_, err := r.db.Exec(`UPDATE series SET title = ?, slug = ?, description = ?, updated_at = CURRENT_TIMESTAMP
+	                     WHERE id = ?`, series.Title, series.Slug, series.Description, series.ID)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRow(`SELECT updated_at FROM series WHERE id = ?`, series.ID).Scan(&series.UpdatedAt)
+}
+
+// Delete removes a series; its posts remain as standalone posts
+func (r *SeriesRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM series WHERE id = ?`, id)
+	return err
+}
+
+// SetPosts replaces the parts of a series with postIDs, in order
+func (r *SeriesRepository) SetPosts(seriesID int64, postIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM series_posts WHERE series_id = ?`, seriesID); err != nil {
+		return err
+	}
+	for i, postID := range postIDs {
+		_, err := tx.Exec(`INSERT INTO series_posts (series_id, post_id, position) VALUES (?, ?, ?)`,
+			seriesID, postID, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE series SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, seriesID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindPosts returns the parts of a series in order
+func (r *SeriesRepository) FindPosts(seriesID int64) ([]*SeriesPost, error) {
+	parts, err := r.FindPostsBySeriesIDs([]int64{seriesID})
+	if err != nil {
+		return nil, err
+	}
+	return parts[seriesID], nil
+}
+
+// FindPostsBySeriesIDs returns the parts of each series in order, keyed by
+// series
+func (r *SeriesRepository) FindPostsBySeriesIDs(seriesIDs []int64) (map[int64][]*SeriesPost, error) {
+	parts := make(map[int64][]*SeriesPost)
+	if len(seriesIDs) == 0 {
+		return parts, nil
+	}
+
+	query := `SELECT sp.series_id, sp.post_id, sp.position, p.title, COALESCE(p.slug, ''), p.status
+	          FROM series_posts sp JOIN posts p ON p.id = sp.post_id
+	          WHERE sp.series_id IN (` + placeholders(len(seriesIDs)) + `)
+	          ORDER BY sp.series_id, sp.position`
+
+	rows, err := r.db.Query(query, int64Args(seriesIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		part := &SeriesPost{}
+		if err := rows.Scan(&part.SeriesID, &part.PostID, &part.Position, &part.Title, &part.Slug, &part.Status); err != nil {
+			return nil, err
+		}
+		parts[part.SeriesID] = append(parts[part.SeriesID], part)
+	}
+	return parts, rows.Err()
+}
+
+// FindPostsInSeries returns the posts of a series in order, newest part
+// first when newestFirst is set
+func (r *SeriesRepository) FindPostsInSeries(seriesID int64, status string, newestFirst bool, limit, offset int) ([]*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p
+	          JOIN series_posts sp ON sp.post_id = p.id
+	          WHERE sp.series_id = ?`
+	queryParams := []interface{}{seriesID}
+
+	if status != "" {
+		query += " AND p.status = ?"
+		queryParams = append(queryParams, status)
+	}
+
+	if newestFirst {
+		query += " ORDER BY sp.position DESC LIMIT ? OFFSET ?"
+	} else {
+		query += " ORDER BY sp.position LIMIT ? OFFSET ?"
+	}
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// FindByPostIDs returns the series each post belongs to, keyed by post.
+// Posts outside any series are absent.
+func (r *SeriesRepository) FindByPostIDs(postIDs []int64) (map[int64]*Series, error) {
+	bySeries := make(map[int64]*Series)
+	byPost := make(map[int64]*Series)
+	if len(postIDs) == 0 {
+		return byPost, nil
+	}
+
+	query := `SELECT sp.post_id, s.id, s.title, s.slug, s.description, s.author_id, s.created_at, s.updated_at
+	          FROM series_posts sp JOIN series s ON s.id = sp.series_id
+	          WHERE sp.post_id IN (` + placeholders(len(postIDs)) + `)`
+
+	rows, err := r.db.Query(query, int64Args(postIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		series := &Series{}
+		if err := rows.Scan(&postID, &series.ID, &series.Title, &series.Slug, &series.Description,
+			&series.AuthorID, &series.CreatedAt, &series.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if existing, ok := bySeries[series.ID]; ok {
+			series = existing
+		}
+		bySeries[series.ID] = series
+		byPost[postID] = series
+	}
+	return byPost, rows.Err()
+}
@@ -0,0 +1,17 @@
+package models
+
+// SeriesRepositoryInterface defines the contract for series and the order
+// of their posts
+type SeriesRepositoryInterface interface {
+	Create(series *Series) error
+	FindByID(id int64) (*Series, error)
+	FindBySlug(slug string) (*Series, error)
+	FindAll(limit, offset int) ([]*Series, error)
+	Update(series *Series) error
+	Delete(id int64) error
+	SetPosts(seriesID int64, postIDs []int64) error
+	FindPosts(seriesID int64) ([]*SeriesPost, error)
+	FindPostsBySeriesIDs(seriesIDs []int64) (map[int64][]*SeriesPost, error)
+	FindPostsInSeries(seriesID int64, status string, newestFirst bool, limit, offset int) ([]*Post, error)
+	FindByPostIDs(postIDs []int64) (map[int64]*Series, error)
+}
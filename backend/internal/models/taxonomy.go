@@ -0,0 +1,456 @@
+package models
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Tag struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	PostCount int       `json:"post_count" db:"-"` // only populated by FindAllTags
+}
+
+type Category struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Slug        string    `json:"slug" db:"slug"`
+	Description string    `json:"description" db:"description"`
+	ParentID    *int64    `json:"parent_id" db:"parent_id"` // nil for top-level categories
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	PostCount   int       `json:"post_count" db:"-"` // only populated by FindAllCategories
+}
+
+// PostTaxonomy is the set of tags and categories saved with a post. A nil
+// list leaves the corresponding association untouched; an empty one clears it.
+type PostTaxonomy struct {
+	TagIDs      []int64
+	CategoryIDs []int64
+}
+
+type TaxonomyRepository struct {
+	db *sql.DB
+}
+
+func NewTaxonomyRepository(db *sql.DB) *TaxonomyRepository {
+	return &TaxonomyRepository{db: db}
+}
+
+// Tags
+
+func (r *TaxonomyRepository) CreateTag(tag *Tag) error {
+	result, err := r.db.Exec(`INSERT INTO tags (name, slug) VALUES (?, ?)`, tag.Name, tag.Slug)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tag.ID = id
+
+	return r.db.QueryRow(`SELECT created_at FROM tags WHERE id = ?`, id).Scan(&tag.CreatedAt)
+}
+
+func (r *TaxonomyRepository) FindTagByID(id int64) (*Tag, error) {
+	return r.findTag(`SELECT id, name, slug, created_at FROM tags WHERE id = ?`, id)
+}
+
+func (r *TaxonomyRepository) FindTagBySlug(slug string) (*Tag, error) {
+	return r.findTag(`SELECT id, name, slug, created_at FROM tags WHERE slug = ?`, slug)
+}
+
+func (r *TaxonomyRepository) findTag(query string, arg interface{}) (*Tag, error) {
+	tag := &Tag{}
+	err := r.db.QueryRow(query, arg).Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// FindAllTags returns every tag together with the number of posts using it
+func (r *TaxonomyRepository) FindAllTags() ([]*Tag, error) {
+	query := `SELECT t.id, t.name, t.slug, t.created_at, COUNT(pt.post_id)
+	          FROM tags t LEFT JOIN post_tags pt ON pt.tag_id = t.id
+	          GROUP BY t.id ORDER BY t.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		tag := &Tag{}
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.CreatedAt, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *TaxonomyRepository) UpdateTag(tag *Tag) error {
+	_, err := r.db.Exec(`UPDATE tags SET name = ?, slug = ? WHERE id = ?`, tag.Name, tag.Slug, tag.ID)
+	return err
+}
+
+func (r *TaxonomyRepository) DeleteTag(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM tags WHERE id = ?`, id)
+	return err
+}
+
+// MergeTags moves every post association from the source tag to the target
+// tag and then removes the source tag
+func (r *TaxonomyRepository) MergeTags(sourceID, targetID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO post_tags (post_id, tag_id)
+	                  SELECT post_id, ? FROM post_tags WHERE tag_id = ?`, targetID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, sourceID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindTagsByPostIDs loads the tags of several posts in a single query
+func (r *TaxonomyRepository) FindTagsByPostIDs(postIDs []int64) (map[int64][]*Tag, error) {
+	result := make(map[int64][]*Tag)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT pt.post_id, t.id, t.name, t.slug, t.created_at
+	          FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+	          WHERE pt.post_id IN (` + placeholders(len(postIDs)) + `) ORDER BY t.name`
+
+	rows, err := r.db.Query(query, int64Args(postIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		tag := &Tag{}
+		if err := rows.Scan(&postID, &tag.ID, &tag.Name, &tag.Slug, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		result[postID] = append(result[postID], tag)
+	}
+
+	return result, rows.Err()
+}
+
+// FindPostsByTag lists posts carrying the tag, newest first
+func (r *TaxonomyRepository) FindPostsByTag(tagID int64, status string, limit, offset int) ([]*Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p
+	          JOIN post_tags pt ON pt.post_id = p.id
+	          WHERE pt.tag_id = ?`
+	queryParams := []interface{}{tagID}
+
+	if status != "" {
+		query += " AND p.status = ?"
+		queryParams = append(queryParams, status)
+	}
+
+	query += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// Categories
+
+func (r *TaxonomyRepository) CreateCategory(category *Category) error {
+	query := `INSERT INTO categories (name, slug, description, parent_id) VALUES (?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, category.Name, category.Slug, category.Description, nullableID(category.ParentID))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	category.ID = id
+
+	return r.db.QueryRow(`SELECT created_at FROM categories WHERE id = ?`, id).Scan(&category.CreatedAt)
+}
+
+func (r *TaxonomyRepository) FindCategoryByID(id int64) (*Category, error) {
+	return r.findCategory(`SELECT id, name, slug, description, parent_id, created_at FROM categories WHERE id = ?`, id)
+}
+
+func (r *TaxonomyRepository) FindCategoryBySlug(slug string) (*Category, error) {
+	return r.findCategory(`SELECT id, name, slug, description, parent_id, created_at FROM categories WHERE slug = ?`, slug)
+}
+
+func (r *TaxonomyRepository) findCategory(query string, arg interface{}) (*Category, error) {
+	category, err := scanCategory(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return category, err
+}
+
+// FindAllCategories returns every category with the number of posts
+// assigned directly to it
+func (r *TaxonomyRepository) FindAllCategories() ([]*Category, error) {
+	query := `SELECT c.id, c.name, c.slug, c.description, c.parent_id, c.created_at, COUNT(pc.post_id)
+	          FROM categories c LEFT JOIN post_categories pc ON pc.category_id = c.id
+	          GROUP BY c.id ORDER BY c.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		category := &Category{}
+		var parentID sql.NullInt64
+		dest := append(categoryFields(category, &parentID), &category.PostCount)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		setParentID(category, parentID)
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *TaxonomyRepository) UpdateCategory(category *Category) error {
+	query := `UPDATE categories SET name = ?, slug = ?, description = ?, parent_id = ? WHERE id = ?`
+	_, err := r.db.Exec(query, category.Name, category.Slug, category.Description, nullableID(category.ParentID), category.ID)
+	return err
+}
+
+// DeleteCategory removes a category and attaches its children to its parent
+func (r *TaxonomyRepository) DeleteCategory(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = ?)
+	                  WHERE parent_id = ?`, id, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM categories WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindCategoriesByPostIDs loads the categories of several posts in a single query
+func (r *TaxonomyRepository) FindCategoriesByPostIDs(postIDs []int64) (map[int64][]*Category, error) {
+	result := make(map[int64][]*Category)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT pc.post_id, c.id, c.name, c.slug, c.description, c.parent_id, c.created_at
+	          FROM post_categories pc JOIN categories c ON c.id = pc.category_id
+	          WHERE pc.post_id IN (` + placeholders(len(postIDs)) + `) ORDER BY c.name`
+
+	rows, err := r.db.Query(query, int64Args(postIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		category := &Category{}
+		var parentID sql.NullInt64
+		dest := append([]interface{}{&postID}, categoryFields(category, &parentID)...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		setParentID(category, parentID)
+		result[postID] = append(result[postID], category)
+	}
+
+	return result, rows.Err()
+}
+
+// FindPostsByCategory lists posts in the category, newest first. When
+// includeDescendants is set, posts in any subcategory are included as well.
+func (r *TaxonomyRepository) FindPostsByCategory(categoryID int64, includeDescendants bool, status string, limit, offset int) ([]*Post, error) {
+	var query string
+	if includeDescendants {
+		query = `WITH RECURSIVE tree(id) AS (
+		             SELECT ? UNION SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		         )
+		         SELECT ` + postColumns + ` FROM posts p
+		         WHERE p.id IN (SELECT post_id FROM post_categories WHERE category_id IN (SELECT id FROM tree))`
+	} else {
+		query = `SELECT ` + postColumns + ` FROM posts p
+		         WHERE p.id IN (SELECT post_id FROM post_categories WHERE category_id = ?)`
+	}
+	queryParams := []interface{}{categoryID}
+
+	if status != "" {
+		query += " AND p.status = ?"
+		queryParams = append(queryParams, status)
+	}
+
+	query += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
+	queryParams = append(queryParams, limit, offset)
+
+	rows, err := r.db.Query(query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPosts(rows)
+}
+
+// setPostTaxonomy replaces the tags and categories of a post within the
+// caller's transaction. It returns the associations that changed, keyed
+// "tags" and "categories" like the fields of a post_updated change set.
+func setPostTaxonomy(tx *sql.Tx, postID int64, taxonomy PostTaxonomy) (map[string]FieldChange, error) {
+	changes := make(map[string]FieldChange)
+
+	if taxonomy.TagIDs != nil {
+		if err := replaceAssociations(tx, `post_tags`, `tag_id`, postID, taxonomy.TagIDs, "tags", changes); err != nil {
+			return nil, err
+		}
+	}
+
+	if taxonomy.CategoryIDs != nil {
+		if err := replaceAssociations(tx, `post_categories`, `category_id`, postID, taxonomy.CategoryIDs, "categories", changes); err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+// replaceAssociations rewrites the rows of a post join table, recording
+// the sorted IDs before and after under name if they differ
+func replaceAssociations(tx *sql.Tx, table, column string, postID int64, ids []int64, name string, changes map[string]FieldChange) error {
+	before, err := associatedIDs(tx, table, column, postID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM `+table+` WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO `+table+` (post_id, `+column+`) VALUES (?, ?)`, postID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	after, err := associatedIDs(tx, table, column, postID)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(before, after) {
+		changes[name] = FieldChange{Before: before, After: after}
+	}
+
+	return nil
+}
+
+// associatedIDs returns the sorted IDs a post is linked to in a join table,
+// never nil so an empty set encodes as []
+func associatedIDs(tx *sql.Tx, table, column string, postID int64) ([]int64, error) {
+	rows, err := tx.Query(`SELECT `+column+` FROM `+table+` WHERE post_id = ? ORDER BY `+column, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// categoryFields returns the scan destinations for the category columns
+// selected as "id, name, slug, description, parent_id, created_at"
+func categoryFields(category *Category, parentID *sql.NullInt64) []interface{} {
+	return []interface{}{
+		&category.ID, &category.Name, &category.Slug, &category.Description, parentID, &category.CreatedAt,
+	}
+}
+
+func setParentID(category *Category, parentID sql.NullInt64) {
+	if parentID.Valid {
+		id := parentID.Int64
+		category.ParentID = &id
+	}
+}
+
+func scanCategory(row rowScanner) (*Category, error) {
+	category := &Category{}
+	var parentID sql.NullInt64
+	if err := row.Scan(categoryFields(category, &parentID)...); err != nil {
+		return nil, err
+	}
+	setParentID(category, parentID)
+	return category, nil
+}
+
+func nullableID(id *int64) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+// placeholders returns "?, ?, ?" with n question marks
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
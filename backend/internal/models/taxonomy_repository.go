@@ -0,0 +1,24 @@
+package models
+
+// TaxonomyRepositoryInterface defines the contract for tag and category
+// storage, including their many-to-many associations with posts
+type TaxonomyRepositoryInterface interface {
+	CreateTag(tag *Tag) error
+	FindTagByID(id int64) (*Tag, error)
+	FindTagBySlug(slug string) (*Tag, error)
+	FindAllTags() ([]*Tag, error)
+	UpdateTag(tag *Tag) error
+	DeleteTag(id int64) error
+	MergeTags(sourceID, targetID int64) error
+	FindTagsByPostIDs(postIDs []int64) (map[int64][]*Tag, error)
+	FindPostsByTag(tagID int64, status string, limit, offset int) ([]*Post, error)
+
+	CreateCategory(category *Category) error
+	FindCategoryByID(id int64) (*Category, error)
+	FindCategoryBySlug(slug string) (*Category, error)
+	FindAllCategories() ([]*Category, error)
+	UpdateCategory(category *Category) error
+	DeleteCategory(id int64) error
+	FindCategoriesByPostIDs(postIDs []int64) (map[int64][]*Category, error)
+	FindPostsByCategory(categoryID int64, includeDescendants bool, status string, limit, offset int) ([]*Post, error)
+}
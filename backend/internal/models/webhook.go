@@ -0,0 +1,248 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint subscribed to post lifecycle events
+type Webhook struct {
+	ID        int64     `json:"id" db:"id"`
+	URL       string    `json:"url" db:"url"`
+	Events    []string  `json:"events" db:"events"` // empty means every event
+	Secret    string    `json:"-" db:"secret"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of the given type
+func (w *Webhook) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent (or to be sent) to one webhook. A
+// redelivery is a new delivery with its own delivery ID.
+type WebhookDelivery struct {
+	ID             int64           `json:"id" db:"id"`
+	WebhookID      int64           `json:"webhook_id" db:"webhook_id"`
+	DeliveryID     string          `json:"delivery_id" db:"delivery_id"`
+	EventID        int64           `json:"event_id,omitempty" db:"event_id"` // 0 for redeliveries
+	EventType      string          `json:"event_type" db:"event_type"`
+	PostID         int64           `json:"post_id" db:"post_id"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"` // pending, succeeded, failed
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	ResponseStatus int             `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   string          `json:"response_body,omitempty" db:"response_body"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+const webhookColumns = `id, url, events, secret, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	webhook := &Webhook{}
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.Active,
+		&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = splitEvents(events)
+	return webhook, nil
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+	return strings.Split(events, ",")
+}
+
+const deliveryColumns = `id, webhook_id, delivery_id, event_id, event_type, post_id, payload, status, attempts,
+	next_attempt_at, response_status, response_body, last_error, created_at, delivered_at`
+
+func scanDelivery(row rowScanner) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	var payload string
+	var deliveredAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.DeliveryID, &delivery.EventID, &delivery.EventType,
+		&delivery.PostID, &payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+		&delivery.ResponseStatus, &delivery.ResponseBody, &delivery.LastError, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = rawPayload(payload)
+	delivery.DeliveredAt = nullTimePtr(deliveredAt)
+	return delivery, nil
+}
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(webhook *Webhook) error {
+	query := `INSERT INTO webhooks (url, events, secret, active) VALUES (?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, webhook.URL, strings.Join(webhook.Events, ","), webhook.Secret, webhook.Active)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.ID = id
+
+	selectQuery := `SELECT created_at, updated_at FROM webhooks WHERE id = ?`
+	return r.db.QueryRow(selectQuery, id).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+func (r *WebhookRepository) FindByID(id int64) (*Webhook, error) {
+	webhook, err := scanWebhook(r.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return webhook, err
+}
+
+// FindAll lists webhooks in creation order; activeOnly skips disabled ones
+func (r *WebhookRepository) FindAll(activeOnly bool) ([]*Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks`
+	if activeOnly {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepository) Update(webhook *Webhook) error {
+	query := `UPDATE webhooks SET url = ?, events = ?, secret = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ?`
+
+	_, err := r.db.Exec(query, webhook.URL, strings.Join(webhook.Events, ","), webhook.Secret, webhook.Active, webhook.ID)
+	return err
+}
+
+// Delete removes the webhook together with its delivery log
+func (r *WebhookRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	return err
+}
+
+// CreateDelivery stores a pending delivery. It reports false, storing
+// nothing, when the webhook already has a delivery of the same event.
+func (r *WebhookRepository) CreateDelivery(delivery *WebhookDelivery) (bool, error) {
+	query := `INSERT OR IGNORE INTO webhook_deliveries
+	          (webhook_id, delivery_id, event_id, event_type, post_id, payload, status, next_attempt_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, delivery.WebhookID, delivery.DeliveryID, delivery.EventID, delivery.EventType,
+		delivery.PostID, string(delivery.Payload), delivery.Status, delivery.NextAttemptAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if delivery.ID, err = result.LastInsertId(); err != nil {
+		return false, err
+	}
+	return true, r.db.QueryRow(`SELECT created_at FROM webhook_deliveries WHERE id = ?`, delivery.ID).Scan(&delivery.CreatedAt)
+}
+
+func (r *WebhookRepository) FindDelivery(id int64) (*WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// FindDeliveries returns the delivery log of a webhook, newest first
+func (r *WebhookRepository) FindDeliveries(webhookID int64, limit, offset int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+	          WHERE webhook_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`
+	return r.queryDeliveries(query, webhookID, limit, offset)
+}
+
+// FindDueDeliveries returns pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepository) FindDueDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+	          WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`
+	return r.queryDeliveries(query, DeliveryPending, now.UTC(), limit)
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (r *WebhookRepository) UpdateDelivery(delivery *WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries
+	          SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?, response_body = ?,
+	              last_error = ?, delivered_at = ?
+	          WHERE id = ?`
+
+	_, err := r.db.Exec(query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(),
+		delivery.ResponseStatus, delivery.ResponseBody, delivery.LastError, utcTimePtr(delivery.DeliveredAt), delivery.ID)
+	return err
+}
+
+func (r *WebhookRepository) queryDeliveries(query string, args ...interface{}) ([]*WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// WebhookRepositoryInterface defines the contract for webhook subscriptions
+// and their delivery log
+type WebhookRepositoryInterface interface {
+	Create(webhook *Webhook) error
+	FindByID(id int64) (*Webhook, error)
+	FindAll(activeOnly bool) ([]*Webhook, error)
+	Update(webhook *Webhook) error
+	Delete(id int64) error
+	CreateDelivery(delivery *WebhookDelivery) (bool, error)
+	FindDelivery(id int64) (*WebhookDelivery, error)
+	FindDeliveries(webhookID int64, limit, offset int) ([]*WebhookDelivery, error)
+	FindDueDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error)
+	UpdateDelivery(delivery *WebhookDelivery) error
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/hyperloglog"
+)
+
+// dayLayout is the format of the days stats are aggregated by (UTC)
+const dayLayout = "2006-01-02"
+
+// maxStatsDays bounds the range of a stats query
+const maxStatsDays = 366
+
+var ErrInvalidStatsRange = errors.New("from and to must be YYYY-MM-DD dates, from before to, at most 366 days apart")
+
+// StatsQuery selects a post or author and a range of days. Empty dates
+// default to the 30 days ending today.
+type StatsQuery struct {
+	ID   int64
+	From string
+	To   string
+}
+
+type DailyStatsViewModel struct {
+	Day            string `json:"day"`
+	Views          int64  `json:"views"`
+	UniqueVisitors uint64 `json:"unique_visitors"`
+	Comments       int64  `json:"comments"`
+}
+
+type PostStatsViewModel struct {
+	PostID         int64                 `json:"post_id"`
+	Title          string                `json:"title,omitempty"`
+	From           string                `json:"from,omitempty"`
+	To             string                `json:"to,omitempty"`
+	Views          int64                 `json:"views"`
+	UniqueVisitors uint64                `json:"unique_visitors"`
+	Comments       int64                 `json:"comments"`
+	Daily          []DailyStatsViewModel `json:"daily,omitempty"`
+}
+
+// AuthorStatsViewModel totals an author's posts. A reader of several posts
+// counts once in UniqueVisitors.
+type AuthorStatsViewModel struct {
+	AuthorID       int64                 `json:"author_id"`
+	From           string                `json:"from"`
+	To             string                `json:"to"`
+	Views          int64                 `json:"views"`
+	UniqueVisitors uint64                `json:"unique_visitors"`
+	Comments       int64                 `json:"comments"`
+	Daily          []DailyStatsViewModel `json:"daily"`
+	Posts          []PostStatsViewModel  `json:"posts"`
+}
+
+// AnalyticsService reports the traffic recorded by the ViewTracker. Recent
+// views appear once the tracker has flushed them.
+type AnalyticsService struct {
+	analyticsRepo models.AnalyticsRepositoryInterface
+	postRepo      models.PostRepositoryInterface
+}
+
+func NewAnalyticsService(analyticsRepo models.AnalyticsRepositoryInterface, postRepo models.PostRepositoryInterface) *AnalyticsService {
+	return &AnalyticsService{analyticsRepo: analyticsRepo, postRepo: postRepo}
+}
+
+func (s *AnalyticsService) GetPostStats(query StatsQuery) (*PostStatsViewModel, error) {
+	from, to, err := statsRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.FindByID(query.ID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, ErrPostNotFound
+	}
+
+	stats, err := s.analyticsRepo.FindPostStats(post.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	total := summarize(stats)
+	return &PostStatsViewModel{
+		PostID:         post.ID,
+		Title:          post.Title,
+		From:           from,
+		To:             to,
+		Views:          total.Views,
+		UniqueVisitors: total.UniqueVisitors,
+		Comments:       total.Comments,
+		Daily:          dailySeries(stats, from, to),
+	}, nil
+}
+
+func (s *AnalyticsService) GetAuthorStats(query StatsQuery) (*AuthorStatsViewModel, error) {
+	from, to, err := statsRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.analyticsRepo.FindAuthorStats(query.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	total := summarize(stats)
+	result := &AuthorStatsViewModel{
+		AuthorID:       query.ID,
+		From:           from,
+		To:             to,
+		Views:          total.Views,
+		UniqueVisitors: total.UniqueVisitors,
+		Comments:       total.Comments,
+		Daily:          dailySeries(stats, from, to),
+		Posts:          []PostStatsViewModel{},
+	}
+
+	byPost := make(map[int64][]*models.DailyPostStats)
+	var order []int64
+	for _, day := range stats {
+		if _, ok := byPost[day.PostID]; !ok {
+			order = append(order, day.PostID)
+		}
+		byPost[day.PostID] = append(byPost[day.PostID], day)
+	}
+
+	for _, postID := range order {
+		post, err := s.postRepo.FindByID(postID)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := summarize(byPost[postID])
+		summary.PostID = postID
+		if post != nil {
+			summary.Title = post.Title
+		}
+		result.Posts = append(result.Posts, summary)
+	}
+
+	return result, nil
+}
+
+// summarize totals a set of daily stats, merging visitor sketches
+func summarize(stats []*models.DailyPostStats) PostStatsViewModel {
+	var total PostStatsViewModel
+	visitors := hyperloglog.New()
+	for _, day := range stats {
+		total.Views += day.Views
+		total.Comments += day.Comments
+		visitors.Merge(day.Visitors)
+	}
+	total.UniqueVisitors = visitors.Count()
+	return total
+}
+
+// dailySeries returns one entry per day in the range, including days
+// without traffic, combining the posts of each day
+func dailySeries(stats []*models.DailyPostStats, from, to string) []DailyStatsViewModel {
+	byDay := make(map[string][]*models.DailyPostStats)
+	for _, day := range stats {
+		byDay[day.Day] = append(byDay[day.Day], day)
+	}
+
+	start, _ := time.Parse(dayLayout, from)
+	end, _ := time.Parse(dayLayout, to)
+
+	series := []DailyStatsViewModel{}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dayLayout)
+		total := summarize(byDay[key])
+		series = append(series, DailyStatsViewModel{
+			Day:            key,
+			Views:          total.Views,
+			UniqueVisitors: total.UniqueVisitors,
+			Comments:       total.Comments,
+		})
+	}
+
+	return series
+}
+
+// statsRange validates the query dates, applying the 30-day default
+func statsRange(query StatsQuery) (from, to string, err error) {
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if query.To != "" {
+		if end, err = time.Parse(dayLayout, query.To); err != nil {
+			return "", "", ErrInvalidStatsRange
+		}
+	}
+
+	start := end.AddDate(0, 0, -29)
+	if query.From != "" {
+		if start, err = time.Parse(dayLayout, query.From); err != nil {
+			return "", "", ErrInvalidStatsRange
+		}
+	}
+
+	if start.After(end) || end.Sub(start) >= maxStatsDays*24*time.Hour {
+		return "", "", ErrInvalidStatsRange
+	}
+
+	return start.Format(dayLayout), end.Format(dayLayout), nil
+}
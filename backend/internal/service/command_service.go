@@ -2,35 +2,97 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"blog-platform/internal/models"
+	"blog-platform/pkg/slug"
 )
 
 type CreatePostCommand struct {
-	Title    string `json:"title"`
-	Content  string `json:"content"`
-	Type     string `json:"type"`
-	AuthorID int64  `json:"author_id"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Format      string   `json:"format"` // markdown (default), html or plaintext
+	Locale      string   `json:"locale"` // defaults to the site's default locale
+	Type        string   `json:"type"`
+	AuthorID    int64    `json:"author_id"`
+	Tags        []string `json:"tags"`         // tag names, created if missing
+	CategoryIDs []int64  `json:"category_ids"` // existing category IDs
+	CoverImage  string   `json:"cover_image"`
+
+	// TranslationOf links the post as a translation of an existing post
+	TranslationOf int64 `json:"translation_of"`
+
+	// SEO overrides the metadata derived from the post
+	SEO models.PostSEO `json:"seo"`
+
+	// Optional schedule; the post stays a draft until PublishAt
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+
+	// Meta is recorded with the post_created event; the actor defaults to the author
+	Meta models.EventMeta `json:"-"`
 }
 
+// UpdatePostCommand changes the non-empty fields of a post. Tags and
+// CategoryIDs are left alone when omitted; an empty list clears them.
+// Changing the title regenerates the slug unless Slug is given explicitly.
+// CancelSchedule drops any pending publish or unpublish time. CoverImage and
+// SEO replace the stored values whenever present, so they can be cleared.
+// TranslationOf moves the post into another post's translation group; 0
+// unlinks it from its group.
 type UpdatePostCommand struct {
-	ID      int64  `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Type    string `json:"type"`
-	Status  string `json:"status"`
+	ID          int64    `json:"id"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Content     string   `json:"content"`
+	Format      string   `json:"format"`
+	Locale      string   `json:"locale"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Tags        []string `json:"tags"`
+	CategoryIDs []int64  `json:"category_ids"`
+
+	PublishAt      *time.Time `json:"publish_at"`
+	UnpublishAt    *time.Time `json:"unpublish_at"`
+	CancelSchedule bool       `json:"cancel_schedule"`
+
+	CoverImage    *string         `json:"cover_image"`
+	SEO           *models.PostSEO `json:"seo"`
+	TranslationOf *int64          `json:"translation_of"`
+
+	Meta models.EventMeta `json:"-"`
 }
 
 type DeletePostCommand struct {
-	ID int64 `json:"id"`
+	ID   int64            `json:"id"`
+	Meta models.EventMeta `json:"-"`
 }
 
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrInvalidSchedule = errors.New("unpublish_at must be after publish_at")
+	ErrInvalidFormat   = errors.New("format must be one of markdown, html, plaintext")
+	ErrInvalidURL      = errors.New("cover_image, seo.social_image and seo.canonical_url must be http(s) URLs or absolute paths")
+
+	ErrTranslationNotFound = errors.New("translation_of must be the ID of an existing post")
+	ErrTranslationExists   = errors.New("the post's translation group already has a post in its locale")
+)
+
 type CommandService struct {
-	postRepo models.PostRepositoryInterface
+	postRepo     models.PostRepositoryInterface
+	taxonomyRepo models.TaxonomyRepositoryInterface
+	locales      LocaleConfig
 }
 
-func NewCommandService(postRepo models.PostRepositoryInterface) *CommandService {
-	return &CommandService{postRepo: postRepo}
+func NewCommandService(
+	postRepo models.PostRepositoryInterface,
+	taxonomyRepo models.TaxonomyRepositoryInterface,
+	locales LocaleConfig,
+) *CommandService {
+	return &CommandService{postRepo: postRepo, taxonomyRepo: taxonomyRepo, locales: locales}
 }
 
 func (s *CommandService) CreatePost(cmd CreatePostCommand) (*models.Post, error) {
@@ -38,15 +100,72 @@ func (s *CommandService) CreatePost(cmd CreatePostCommand) (*models.Post, error)
 		return nil, errors.New("title and content are required")
 	}
 
+	if err := validateCategories(s.taxonomyRepo, cmd.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	if err := validateSchedule(cmd.PublishAt, cmd.UnpublishAt); err != nil {
+		return nil, err
+	}
+
+	format := cmd.Format
+	if format == "" {
+		format = models.FormatMarkdown
+	}
+	if err := validateFormat(format); err != nil {
+		return nil, err
+	}
+
+	if err := validateURLs(cmd.CoverImage, cmd.SEO.SocialImage, cmd.SEO.CanonicalURL); err != nil {
+		return nil, err
+	}
+
+	locale, err := s.locales.Normalize(cmd.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	var translationGroup *int64
+	if cmd.TranslationOf != 0 {
+		if translationGroup, err = s.translationGroup(cmd.TranslationOf, 0, locale); err != nil {
+			return nil, err
+		}
+	}
+
+	postSlug, err := s.uniqueSlug(cmd.Title, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	taxonomy, err := s.resolveTaxonomy(cmd.Tags, cmd.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	post := &models.Post{
 		Title:    cmd.Title,
 		Content:  cmd.Content,
+		Format:   format,
 		Type:     cmd.Type,
 		AuthorID: cmd.AuthorID,
 		Status:   "draft",
+		Slug:     postSlug,
+
+		Locale:           locale,
+		TranslationGroup: translationGroup,
+		CoverImage:       cmd.CoverImage,
+		SEO:              cmd.SEO,
+
+		PublishAt:   cmd.PublishAt,
+		UnpublishAt: cmd.UnpublishAt,
 	}
 
-	err := s.postRepo.Create(post)
+	meta := cmd.Meta
+	if meta.ActorID == 0 {
+		meta.ActorID = cmd.AuthorID
+	}
+
+	err = s.postRepo.Create(post, taxonomy, meta)
 	if err != nil {
 		return nil, err
 	}
@@ -55,32 +174,108 @@ func (s *CommandService) CreatePost(cmd CreatePostCommand) (*models.Post, error)
 }
 
 func (s *CommandService) UpdatePost(cmd UpdatePostCommand) (*models.Post, error) {
-	post, err := s.postRepo.FindByID(cmd.ID)
+	current, err := s.postRepo.FindByID(cmd.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	if post == nil {
-		return nil, errors.New("post not found")
+	if current == nil {
+		return nil, ErrPostNotFound
 	}
 
+	// The repository may return a cached post shared with readers, so the
+	// changes are made on a copy that is only visible once saved
+	updated := *current
+	post := &updated
+
+	titleChanged := cmd.Title != "" && cmd.Title != post.Title
 	if cmd.Title != "" {
 		post.Title = cmd.Title
 	}
 
+	if cmd.Slug != "" || titleChanged {
+		source := cmd.Slug
+		if source == "" {
+			source = post.Title
+		}
+		if post.Slug, err = s.uniqueSlug(source, post.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	if cmd.Content != "" {
 		post.Content = cmd.Content
 	}
 
+	if cmd.Format != "" {
+		if err := validateFormat(cmd.Format); err != nil {
+			return nil, err
+		}
+		post.Format = cmd.Format
+	}
+
 	if cmd.Type != "" {
 		post.Type = cmd.Type
 	}
 
+	if cmd.Locale != "" {
+		if post.Locale, err = s.locales.Normalize(cmd.Locale); err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.CoverImage != nil {
+		post.CoverImage = *cmd.CoverImage
+	}
+	if cmd.SEO != nil {
+		post.SEO = *cmd.SEO
+	}
+	if err := validateURLs(post.CoverImage, post.SEO.SocialImage, post.SEO.CanonicalURL); err != nil {
+		return nil, err
+	}
+
 	if cmd.Status != "" {
 		post.Status = cmd.Status
 	}
 
-	err = s.postRepo.Update(post)
+	if cmd.CancelSchedule {
+		post.PublishAt = nil
+		post.UnpublishAt = nil
+	}
+	if cmd.PublishAt != nil {
+		post.PublishAt = cmd.PublishAt
+	}
+	if cmd.UnpublishAt != nil {
+		post.UnpublishAt = cmd.UnpublishAt
+	}
+	if err := validateSchedule(post.PublishAt, post.UnpublishAt); err != nil {
+		return nil, err
+	}
+
+	if err := validateCategories(s.taxonomyRepo, cmd.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cmd.TranslationOf != nil && *cmd.TranslationOf == 0:
+		post.TranslationGroup = nil
+	case cmd.TranslationOf != nil:
+		if post.TranslationGroup, err = s.translationGroup(*cmd.TranslationOf, post.ID, post.Locale); err != nil {
+			return nil, err
+		}
+	case post.TranslationGroup != nil && cmd.Locale != "":
+		// A new locale must not clash with another translation
+		if err := s.checkTranslationLocale(*post.TranslationGroup, post.ID, post.Locale); err != nil {
+			return nil, err
+		}
+	}
+
+	taxonomy, err := s.resolveTaxonomy(cmd.Tags, cmd.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.postRepo.Update(post, taxonomy, cmd.Meta)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +283,131 @@ func (s *CommandService) UpdatePost(cmd UpdatePostCommand) (*models.Post, error)
 	return post, nil
 }
 
+func validateSchedule(publishAt, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case models.FormatMarkdown, models.FormatHTML, models.FormatPlainText:
+		return nil
+	}
+	return ErrInvalidFormat
+}
+
+// validateURLs accepts empty values, http(s) URLs and paths on this site
+// such as uploaded media
+func validateURLs(values ...string) error {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "//") {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidURL
+		}
+	}
+	return nil
+}
+
+// translationGroup returns the translation group of the post sourceID for
+// a post in locale to join, or a new group named after the source when it
+// has none. The repository adds the source to a new group in the same
+// transaction that saves the joining post. postID is the post joining, or
+// 0 for a new post.
+func (s *CommandService) translationGroup(sourceID, postID int64, locale string) (*int64, error) {
+	if sourceID == postID {
+		return nil, ErrTranslationNotFound
+	}
+	source, err := s.postRepo.FindByID(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrTranslationNotFound
+	}
+
+	if source.TranslationGroup != nil {
+		if err := s.checkTranslationLocale(*source.TranslationGroup, postID, locale); err != nil {
+			return nil, err
+		}
+		return source.TranslationGroup, nil
+	}
+
+	if source.Locale == locale {
+		return nil, ErrTranslationExists
+	}
+	group := source.ID
+	return &group, nil
+}
+
+// checkTranslationLocale ensures no post of the group other than postID is
+// written in locale
+func (s *CommandService) checkTranslationLocale(group, postID int64, locale string) error {
+	translations, err := s.postRepo.FindTranslations([]int64{group})
+	if err != nil {
+		return err
+	}
+	for _, translation := range translations[group] {
+		if translation.ID != postID && translation.Locale == locale {
+			return ErrTranslationExists
+		}
+	}
+	return nil
+}
+
+// uniqueSlug derives a slug from text that no other post is using or used
+// to use. postID is the post being saved, or 0 for a new post. Text made
+// only of unsupported characters gives "post-<id>"; for a new post the slug
+// is then left empty for the repository to assign once the ID is known.
+func (s *CommandService) uniqueSlug(text string, postID int64) (string, error) {
+	base := slug.Make(text)
+	if base == "" {
+		if postID == 0 {
+			return "", nil
+		}
+		base = fmt.Sprintf("post-%d", postID)
+	}
+
+	return slug.Unique(base, func(candidate string) (bool, error) {
+		existing, err := s.postRepo.FindBySlug(candidate)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil {
+			return existing.ID != postID, nil
+		}
+
+		// A retired slug keeps redirecting to its post, which may take it back
+		redirectID, err := s.postRepo.FindSlugRedirect(candidate)
+		if err != nil {
+			return false, err
+		}
+		return redirectID != 0 && redirectID != postID, nil
+	})
+}
+
+// resolveTaxonomy turns tag names into tag IDs, creating missing tags, so
+// the tags and categories can be saved together with the post. A nil list
+// leaves the corresponding association untouched.
+func (s *CommandService) resolveTaxonomy(tags []string, categoryIDs []int64) (models.PostTaxonomy, error) {
+	taxonomy := models.PostTaxonomy{CategoryIDs: categoryIDs}
+	if tags != nil {
+		tagIDs, err := resolveTags(s.taxonomyRepo, tags)
+		if err != nil {
+			return models.PostTaxonomy{}, err
+		}
+		taxonomy.TagIDs = tagIDs
+	}
+	return taxonomy, nil
+}
+
 func (s *CommandService) DeletePost(cmd DeletePostCommand) error {
 	post, err := s.postRepo.FindByID(cmd.ID)
 	if err != nil {
@@ -95,8 +415,8 @@ func (s *CommandService) DeletePost(cmd DeletePostCommand) error {
 	}
 
 	if post == nil {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 
-	return s.postRepo.Delete(cmd.ID)
+	return s.postRepo.Delete(cmd.ID, cmd.Meta)
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"blog-platform/internal/models"
+)
+
+// newTestCommandService wires a command service to an in-memory database
+// with English and French posts
+func newTestCommandService(t *testing.T) (*CommandService, *models.OutboxRepository) {
+	t.Helper()
+	db := newTestDB(t)
+	outboxRepo := models.NewOutboxRepository(db)
+	locales, err := NewLocaleConfig("en,fr", "")
+	if err != nil {
+		t.Fatalf("NewLocaleConfig: %v", err)
+	}
+	postRepo := models.NewPostRepository(db, outboxRepo)
+	return NewCommandService(postRepo, models.NewTaxonomyRepository(db), locales), outboxRepo
+}
+
+func TestCreateTranslationStartsGroupWithPost(t *testing.T) {
+	commands, outboxRepo := newTestCommandService(t)
+
+	source, err := commands.CreatePost(CreatePostCommand{Title: "Hello", Content: "Body", Locale: "en"})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	// A second English version is rejected before anything is written
+	_, err = commands.CreatePost(CreatePostCommand{Title: "Hi", Content: "Body", Locale: "en", TranslationOf: source.ID})
+	if !errors.Is(err, ErrTranslationExists) {
+		t.Fatalf("CreatePost in the source's locale = %v, want ErrTranslationExists", err)
+	}
+	if updates := updatedEvents(t, outboxRepo, source.ID); len(updates) != 0 {
+		t.Fatalf("%d post_updated events for the source after a rejected translation, want 0", len(updates))
+	}
+
+	translation, err := commands.CreatePost(CreatePostCommand{Title: "Bonjour", Content: "Corps", Locale: "fr", TranslationOf: source.ID})
+	if err != nil {
+		t.Fatalf("CreatePost translation: %v", err)
+	}
+	if translation.TranslationGroup == nil || *translation.TranslationGroup != source.ID {
+		t.Fatalf("translation group = %v, want %d", translation.TranslationGroup, source.ID)
+	}
+
+	updates := updatedEvents(t, outboxRepo, source.ID)
+	if len(updates) != 1 {
+		t.Fatalf("%d post_updated events for the source, want 1", len(updates))
+	}
+	joined := updates[0].Data.(models.PostUpdated)
+	if joined.Post.TranslationGroup == nil || *joined.Post.TranslationGroup != source.ID {
+		t.Errorf("source group = %v, want %d", joined.Post.TranslationGroup, source.ID)
+	}
+	if _, ok := joined.Changes["translation_group"]; !ok {
+		t.Errorf("changes = %v, want translation_group", joined.Changes)
+	}
+
+	// Recorded together with the translation, right after its post_created
+	events, err := outboxRepo.FindAfter(0, 10)
+	if err != nil {
+		t.Fatalf("FindAfter: %v", err)
+	}
+	if len(events) != 3 || events[1].PostID != translation.ID || events[2].ID != updates[0].ID {
+		t.Errorf("outbox holds %d events, want the source's update right after the translation's creation", len(events))
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+const maxCommentLength = 5000
+
+var (
+	ErrCommentNotFound       = errors.New("comment not found")
+	ErrCommentsClosed        = errors.New("comments are only accepted on published posts")
+	ErrInvalidCommentParent  = errors.New("parent comment must be an approved comment on the same post")
+	ErrInvalidCommentStatus  = errors.New("status must be one of pending, approved, spam, removed")
+	ErrInvalidCommentContent = errors.New("comment content is required and must be at most 5000 characters")
+)
+
+type CreateCommentCommand struct {
+	PostID     int64  `json:"post_id"`
+	ParentID   *int64 `json:"parent_id"`
+	AuthorID   int64  `json:"author_id"`
+	AuthorName string `json:"author_name"`
+	Content    string `json:"content"`
+
+	// Meta is recorded with the comment_created event; the actor defaults to the author
+	Meta models.EventMeta `json:"-"`
+}
+
+type ModerateCommentCommand struct {
+	ID     int64            `json:"id"`
+	Status string           `json:"status"`
+	Meta   models.EventMeta `json:"-"`
+}
+
+// ListCommentsQuery selects the comments of a post. Empty Statuses means
+// approved comments only, which is what readers see.
+type ListCommentsQuery struct {
+	PostID   int64
+	Statuses []string
+}
+
+type CommentViewModel struct {
+	ID         int64               `json:"id"`
+	PostID     int64               `json:"post_id"`
+	ParentID   *int64              `json:"parent_id,omitempty"`
+	AuthorID   int64               `json:"author_id"`
+	AuthorName string              `json:"author_name"`
+	Content    string              `json:"content"`
+	Status     string              `json:"status"`
+	CreatedAt  time.Time           `json:"created_at"`
+	Replies    []*CommentViewModel `json:"replies"`
+}
+
+// CommentService handles comment submission, threading and moderation.
+// The repository records comment events in the outbox for observers.
+type CommentService struct {
+	commentRepo models.CommentRepositoryInterface
+	postRepo    models.PostRepositoryInterface
+}
+
+func NewCommentService(commentRepo models.CommentRepositoryInterface, postRepo models.PostRepositoryInterface) *CommentService {
+	return &CommentService{
+		commentRepo: commentRepo,
+		postRepo:    postRepo,
+	}
+}
+
+// CreateComment stores a new comment awaiting moderation
+func (s *CommentService) CreateComment(cmd CreateCommentCommand) (*CommentViewModel, error) {
+	content := strings.TrimSpace(cmd.Content)
+	if content == "" || len(content) > maxCommentLength {
+		return nil, ErrInvalidCommentContent
+	}
+
+	post, err := s.postRepo.FindByID(cmd.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, ErrPostNotFound
+	}
+	if post.Status != "published" {
+		return nil, ErrCommentsClosed
+	}
+
+	if cmd.ParentID != nil {
+		parent, err := s.commentRepo.FindByID(*cmd.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.PostID != post.ID || parent.Status != models.CommentApproved {
+			return nil, ErrInvalidCommentParent
+		}
+	}
+
+	authorName := strings.TrimSpace(cmd.AuthorName)
+	if authorName == "" {
+		authorName = "Anonymous"
+	}
+
+	comment := &models.Comment{
+		PostID:     post.ID,
+		ParentID:   cmd.ParentID,
+		AuthorID:   cmd.AuthorID,
+		AuthorName: authorName,
+		Content:    content,
+		Status:     models.CommentPending,
+	}
+	meta := cmd.Meta
+	if meta.ActorID == 0 {
+		meta.ActorID = cmd.AuthorID
+	}
+	if err := s.commentRepo.Create(comment, meta); err != nil {
+		return nil, err
+	}
+
+	return toCommentViewModel(comment), nil
+}
+
+// ModerateComment moves a comment to a new moderation state
+func (s *CommentService) ModerateComment(cmd ModerateCommentCommand) (*CommentViewModel, error) {
+	if !validCommentStatus(cmd.Status) {
+		return nil, ErrInvalidCommentStatus
+	}
+
+	comment, err := s.commentRepo.FindByID(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return nil, ErrCommentNotFound
+	}
+
+	if comment.Status == cmd.Status {
+		return toCommentViewModel(comment), nil
+	}
+
+	if err := s.commentRepo.UpdateStatus(comment.ID, cmd.Status, cmd.Meta); err != nil {
+		return nil, err
+	}
+	comment.Status = cmd.Status
+
+	return toCommentViewModel(comment), nil
+}
+
+// ListComments returns the comments of a post as threads. Replies whose
+// parent is not visible in the selected states are shown at the top level.
+func (s *CommentService) ListComments(query ListCommentsQuery) ([]*CommentViewModel, error) {
+	statuses := query.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.CommentApproved}
+	}
+	for _, status := range statuses {
+		if !validCommentStatus(status) {
+			return nil, ErrInvalidCommentStatus
+		}
+	}
+
+	comments, err := s.commentRepo.FindByPost(query.PostID, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*CommentViewModel, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = toCommentViewModel(comment)
+	}
+
+	threads := []*CommentViewModel{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID != nil {
+			if parent, ok := nodes[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		threads = append(threads, node)
+	}
+
+	return threads, nil
+}
+
+// ListModerationQueue lists comments in one state across all posts, oldest first
+func (s *CommentService) ListModerationQueue(status string, limit, offset int) ([]*CommentViewModel, error) {
+	if !validCommentStatus(status) {
+		return nil, ErrInvalidCommentStatus
+	}
+
+	comments, err := s.commentRepo.FindByStatus(status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]*CommentViewModel, len(comments))
+	for i, comment := range comments {
+		viewModels[i] = toCommentViewModel(comment)
+	}
+
+	return viewModels, nil
+}
+
+func validCommentStatus(status string) bool {
+	switch status {
+	case models.CommentPending, models.CommentApproved, models.CommentSpam, models.CommentRemoved:
+		return true
+	}
+	return false
+}
+
+func toCommentViewModel(comment *models.Comment) *CommentViewModel {
+	return &CommentViewModel{
+		ID:         comment.ID,
+		PostID:     comment.PostID,
+		ParentID:   comment.ParentID,
+		AuthorID:   comment.AuthorID,
+		AuthorName: comment.AuthorName,
+		Content:    comment.Content,
+		Status:     comment.Status,
+		CreatedAt:  comment.CreatedAt,
+		Replies:    []*CommentViewModel{},
+	}
+}
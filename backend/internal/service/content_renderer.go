@@ -0,0 +1,228 @@
+package service
+
+import (
+	"html"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/markdown"
+	"blog-platform/pkg/richtext"
+	"blog-platform/pkg/sanitize"
+)
+
+// TOCEntry is a heading in a post's table of contents. ID is the anchor
+// of the heading in content_html; deeper headings nest under it.
+type TOCEntry struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Level    int        `json:"level"`
+	Children []TOCEntry `json:"children,omitempty"`
+}
+
+const (
+	excerptLength  = 280
+	wordsPerMinute = 200
+)
+
+// RenderedContent is post content ready to be shown, with the text
+// statistics listings display
+type RenderedContent struct {
+	HTML      string
+	TOC       []TOCEntry
+	Excerpt   string // first paragraph as plain text, shortened to excerptLength
+	WordCount int
+	Image     string // src of the first image, for social cards
+}
+
+// ReadingTime estimates the minutes needed to read the content, at least
+// one for any text
+func (c RenderedContent) ReadingTime() int {
+	if c.WordCount == 0 {
+		return 0
+	}
+	return (c.WordCount + wordsPerMinute - 1) / wordsPerMinute
+}
+
+type renderedContent struct {
+	format   string
+	content  string
+	rendered RenderedContent
+	lastUsed time.Time
+}
+
+// ContentRenderer turns post content into HTML that is safe to embed in a
+// page. Markdown is rendered first; whatever HTML results, or was written
+// directly, then passes through an allow-list sanitizer, so scripts, event
+// handlers and javascript: URLs never reach readers. The sanitized tree is
+// then enriched: fenced code is highlighted, headings get anchors for the
+// table of contents, and links to allow-listed media sites become
+// sandboxed players. Rendered output is cached per post and dropped when
+// the post's update or delete event arrives.
+type ContentRenderer struct {
+	policy  *sanitize.Policy
+	maxSize int
+
+	mu    sync.Mutex
+	cache map[int64]*renderedContent
+}
+
+func NewContentRenderer(maxSize int) *ContentRenderer {
+	return &ContentRenderer{
+		policy:  sanitize.UGC().Transform(richtext.HighlightCode).Transform(richtext.EmbedLinks),
+		maxSize: maxSize,
+		cache:   make(map[int64]*renderedContent),
+	}
+}
+
+func (r *ContentRenderer) Name() string {
+	return "content_renderer"
+}
+
+// Render returns the HTML and table of contents of content written in
+// format. Plain text has no headings, so its table of contents is empty.
+func (r *ContentRenderer) Render(format, content string) RenderedContent {
+	var source string
+	switch format {
+	case models.FormatMarkdown:
+		source = markdown.Render(content)
+	case models.FormatHTML:
+		source = content
+	default:
+		return RenderedContent{
+			HTML:      plainTextHTML(content),
+			TOC:       []TOCEntry{},
+			Excerpt:   truncateWords(firstParagraph(content), excerptLength),
+			WordCount: len(strings.Fields(content)),
+		}
+	}
+
+	root := r.policy.Tree(source)
+	headings := richtext.AnchorHeadings(root)
+	rendered := RenderedContent{
+		HTML:    sanitize.Render(root),
+		TOC:     buildTOC(headings),
+		Excerpt: truncateWords(richtext.FirstParagraph(root), excerptLength),
+		Image:   richtext.FirstImage(root),
+	}
+	text := sanitize.Text(rendered.HTML)
+	rendered.WordCount = len(strings.Fields(text))
+	if rendered.Excerpt == "" {
+		// No paragraphs, e.g. only a list or a table
+		rendered.Excerpt = truncateWords(text, excerptLength)
+	}
+	return rendered
+}
+
+// buildTOC nests each heading under the closest preceding heading of a
+// higher level
+func buildTOC(headings []richtext.Heading) []TOCEntry {
+	entries := []TOCEntry{}
+	for i := 0; i < len(headings); {
+		heading := headings[i]
+		end := i + 1
+		for end < len(headings) && headings[end].Level > heading.Level {
+			end++
+		}
+
+		entry := TOCEntry{ID: heading.ID, Title: heading.Text, Level: heading.Level}
+		if end > i+1 {
+			entry.Children = buildTOC(headings[i+1 : end])
+		}
+		entries = append(entries, entry)
+		i = end
+	}
+	return entries
+}
+
+// RenderPost renders a post's content, from the cache when the post has
+// not changed since it was rendered
+func (r *ContentRenderer) RenderPost(post *models.Post) RenderedContent {
+	r.mu.Lock()
+	if entry, ok := r.cache[post.ID]; ok && entry.format == post.Format && entry.content == post.Content {
+		entry.lastUsed = time.Now()
+		r.mu.Unlock()
+		return entry.rendered
+	}
+	r.mu.Unlock()
+
+	rendered := r.Render(post.Format, post.Content)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cache[post.ID]; !ok && len(r.cache) >= r.maxSize {
+		r.evictOldest()
+	}
+	r.cache[post.ID] = &renderedContent{
+		format:   post.Format,
+		content:  post.Content,
+		rendered: rendered,
+		lastUsed: time.Now(),
+	}
+	return rendered
+}
+
+// evictOldest drops the least recently used entry; the caller holds mu
+func (r *ContentRenderer) evictOldest() {
+	var oldestID int64
+	var oldest time.Time
+	for id, entry := range r.cache {
+		if oldest.IsZero() || entry.lastUsed.Before(oldest) {
+			oldestID, oldest = id, entry.lastUsed
+		}
+	}
+	delete(r.cache, oldestID)
+}
+
+// Update drops the cached HTML of updated and deleted posts
+func (r *ContentRenderer) Update(event PostEvent) error {
+	switch event.Data.(type) {
+	case models.PostUpdated, models.PostDeleted:
+		r.mu.Lock()
+		delete(r.cache, event.PostID)
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// firstParagraph returns the first non-blank paragraph of plain text
+func firstParagraph(content string) string {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if text := strings.Join(strings.Fields(paragraph), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// truncateWords shortens text to about max characters, cutting at a word
+// boundary
+func truncateWords(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	cut := string([]rune(text)[:max])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ".,;:!? ") + "…"
+}
+
+// plainTextHTML turns plain text into HTML paragraphs
+func plainTextHTML(content string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
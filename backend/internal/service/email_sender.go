@@ -0,0 +1,148 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/mailer"
+)
+
+// emailLease is the lease name shared by every replica running a sender
+const emailLease = "email_sender"
+
+// EmailSender hands queued emails to the mailer. Failures are retried with
+// exponential backoff, and sends are spaced out to stay under a per-minute
+// rate so a big fan-out does not trip the mail provider's limits. It also
+// queues weekly digests as they fall due.
+type EmailSender struct {
+	*leasedWorker
+	emailService *EmailService
+	mailer       mailer.Mailer
+	spacing      time.Duration
+	batchSize    int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	lastSend     time.Time
+}
+
+// NewEmailSender creates a sender polling every interval and sending at
+// most ratePerMinute emails a minute
+func NewEmailSender(
+	emailService *EmailService,
+	leaseRepo models.LeaseRepositoryInterface,
+	m mailer.Mailer,
+	interval time.Duration,
+	ratePerMinute int,
+) *EmailSender {
+	if ratePerMinute <= 0 {
+		ratePerMinute = 60
+	}
+	// Spacing alone keeps a batch to about a minute. Slow sends add to
+	// that, so sendDue also stops once half the lease is used.
+	batchSize := 20
+	if ratePerMinute < batchSize {
+		batchSize = ratePerMinute
+	}
+
+	s := &EmailSender{
+		emailService: emailService,
+		mailer:       m,
+		spacing:      time.Minute / time.Duration(ratePerMinute),
+		batchSize:    batchSize,
+		maxAttempts:  5,
+		baseBackoff:  30 * time.Second,
+		maxBackoff:   time.Hour,
+	}
+	// Polls every interval and wakes early whenever emails are queued. The
+	// lease outlasts half of it plus a send, which the SMTP mailer bounds
+	// with mailer.SMTPTimeout.
+	s.leasedWorker = newLeasedWorker(emailLease, leaseRepo, interval, 2*time.Minute, emailService.Queued(), s.sendDue)
+	return s
+}
+
+// sendDue queues due digests and sends a batch of due emails, stopping
+// once the run has used half the lease; the rest stay due for the next run
+func (s *EmailSender) sendDue(now time.Time) {
+	start := time.Now()
+	if err := s.emailService.QueueDigests(now); err != nil {
+		log.Printf("Error queuing digests: %v", err)
+	}
+
+	repo := s.emailService.emailRepo
+	emails, err := repo.FindDue(now, s.batchSize)
+	if err != nil {
+		log.Printf("Error loading queued emails: %v", err)
+		return
+	}
+
+	for _, email := range emails {
+		if !s.wait() || s.overrun(start) {
+			return
+		}
+
+		s.attempt(email)
+		if err := repo.Update(email); err != nil {
+			log.Printf("Error saving email %d: %v", email.ID, err)
+		}
+	}
+}
+
+// attempt sends one email and records the outcome on it. Emails the
+// recipient no longer wants are cancelled instead.
+func (s *EmailSender) attempt(email *models.QueuedEmail) {
+	now := time.Now().UTC()
+
+	wanted, err := s.emailService.wanted(email)
+	if err == nil && !wanted {
+		email.Status = models.EmailCancelled
+		return
+	}
+	if err == nil {
+		err = s.mailer.Send(&mailer.Message{
+			From:    s.emailService.config.From,
+			To:      email.Recipient,
+			Subject: email.Subject,
+			Text:    email.Text,
+			HTML:    email.HTML,
+			Headers: email.Headers,
+		})
+		s.lastSend = time.Now()
+	}
+
+	email.Attempts++
+	if err == nil {
+		email.Status = models.EmailSent
+		email.LastError = ""
+		email.SentAt = &now
+		return
+	}
+
+	email.LastError = err.Error()
+	if email.Attempts >= s.maxAttempts {
+		email.Status = models.EmailFailed
+		log.Printf("Email %d to %s failed after %d attempts: %v", email.ID, email.Recipient, email.Attempts, err)
+	} else {
+		email.NextAttemptAt = now.Add(exponentialBackoff(s.baseBackoff, s.maxBackoff, email.Attempts))
+	}
+}
+
+// wait holds the next send until the rate allows it. It returns false if
+// the sender is stopped meanwhile.
+func (s *EmailSender) wait() bool {
+	delay := time.Until(s.lastSend.Add(s.spacing))
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopping():
+		return false
+	}
+}
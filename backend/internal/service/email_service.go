@@ -0,0 +1,438 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// digestPeriod is how often digests are sent
+const digestPeriod = 7 * 24 * time.Hour
+
+// digestSize caps the posts listed in one digest
+const digestSize = 20
+
+//go:embed emails
+var emailTemplates embed.FS
+
+var (
+	htmlEmails = htmltemplate.Must(htmltemplate.ParseFS(emailTemplates, "emails/*.html"))
+	textEmails = texttemplate.Must(texttemplate.ParseFS(emailTemplates, "emails/*.txt"))
+)
+
+var (
+	ErrInvalidEmail            = errors.New("email must be a valid address")
+	ErrInvalidUnsubscribeToken = errors.New("unsubscribe link is invalid")
+	ErrUnknownEmailList        = errors.New("list must be one of new_post, comment_reply, digest, all")
+)
+
+// MailConfig configures outgoing email
+type MailConfig struct {
+	From           string // sender, e.g. "Blog <noreply@example.com>"
+	UnsubscribeURL string // public URL of the unsubscribe endpoint
+}
+
+// UpdateEmailPreferencesCommand changes the fields that are set
+type UpdateEmailPreferencesCommand struct {
+	UserID         int64   `json:"-"`
+	Email          *string `json:"email"`
+	NewPosts       *bool   `json:"new_posts"`
+	CommentReplies *bool   `json:"comment_replies"`
+	WeeklyDigest   *bool   `json:"weekly_digest"`
+}
+
+// emailPost is a post as email templates show it
+type emailPost struct {
+	Title       string
+	URL         string
+	Excerpt     string
+	ReadingTime int
+}
+
+// emailData is what email templates are rendered with
+type emailData struct {
+	Site              SiteConfig
+	Subject           string
+	Source            string // why a new post was sent, e.g. "in a series you follow"
+	Post              emailPost
+	Reply             *models.Comment
+	Posts             []emailPost
+	UnsubscribeURL    string // from this kind of email
+	UnsubscribeAllURL string
+}
+
+// EmailService turns notifications into emails according to each user's
+// preferences and queues them for the EmailSender
+type EmailService struct {
+	emailRepo        models.EmailRepositoryInterface
+	notificationRepo models.NotificationRepositoryInterface
+	queryService     *QueryService
+	site             SiteConfig
+	config           MailConfig
+	queued           chan struct{}
+}
+
+func NewEmailService(
+	emailRepo models.EmailRepositoryInterface,
+	notificationRepo models.NotificationRepositoryInterface,
+	queryService *QueryService,
+	site SiteConfig,
+	config MailConfig,
+) *EmailService {
+	return &EmailService{
+		emailRepo:        emailRepo,
+		notificationRepo: notificationRepo,
+		queryService:     queryService,
+		site:             site,
+		config:           config,
+		queued:           make(chan struct{}, 1),
+	}
+}
+
+// Queued fires after emails are queued; it may also fire spuriously
+func (s *EmailService) Queued() <-chan struct{} {
+	return s.queued
+}
+
+// GetPreferences returns the user's preferences, or the defaults for a
+// user who never saved any
+func (s *EmailService) GetPreferences(userID int64) (*models.EmailPreferences, error) {
+	if userID <= 0 {
+		return nil, ErrAccountRequired
+	}
+
+	prefs, err := s.emailRepo.FindPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = &models.EmailPreferences{UserID: userID, NewPosts: true, CommentReplies: true}
+	}
+	return prefs, nil
+}
+
+func (s *EmailService) UpdatePreferences(cmd UpdateEmailPreferencesCommand) (*models.EmailPreferences, error) {
+	prefs, err := s.GetPreferences(cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Email != nil {
+		email := strings.TrimSpace(*cmd.Email)
+		if email != "" {
+			address, err := mail.ParseAddress(email)
+			if err != nil || address.Address != email {
+				return nil, ErrInvalidEmail
+			}
+		}
+		prefs.Email = email
+	}
+	if cmd.NewPosts != nil {
+		prefs.NewPosts = *cmd.NewPosts
+	}
+	if cmd.CommentReplies != nil {
+		prefs.CommentReplies = *cmd.CommentReplies
+	}
+	if cmd.WeeklyDigest != nil {
+		prefs.WeeklyDigest = *cmd.WeeklyDigest
+	}
+	if prefs.WeeklyDigest && prefs.LastDigestAt == nil {
+		// The first digest covers the week after signing up
+		now := time.Now().UTC()
+		prefs.LastDigestAt = &now
+	}
+
+	if prefs.UnsubscribeToken == "" {
+		if prefs.UnsubscribeToken, err = randomHex(24); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.emailRepo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// Unsubscribe turns off one kind of email, or all of them for "all" or an
+// empty list, for the owner of the token. It needs no login, so it works
+// from the link in an email and from one-click unsubscribe in mail clients.
+func (s *EmailService) Unsubscribe(token, list string) (*models.EmailPreferences, error) {
+	if token == "" {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	prefs, err := s.emailRepo.FindPreferencesByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	switch list {
+	case models.EmailNewPost:
+		prefs.NewPosts = false
+	case models.EmailCommentReply:
+		prefs.CommentReplies = false
+	case models.EmailDigest:
+		prefs.WeeklyDigest = false
+	case "", "all":
+		prefs.NewPosts, prefs.CommentReplies, prefs.WeeklyDigest = false, false, false
+	default:
+		return nil, ErrUnknownEmailList
+	}
+
+	if err := s.emailRepo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// ListEmails lists queued and sent emails, newest first
+func (s *EmailService) ListEmails(status string, limit, offset int) ([]*models.QueuedEmail, error) {
+	emails, err := s.emailRepo.FindAll(status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if emails == nil {
+		emails = []*models.QueuedEmail{}
+	}
+	return emails, nil
+}
+
+// QueueNewPost emails the recipients of new_post notifications about one
+// post who want such email. Queuing the same notification again does
+// nothing.
+func (s *EmailService) QueueNewPost(postID int64, notifications []*models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	post, err := s.emailPost(postID)
+	if err != nil || post == nil {
+		return err
+	}
+
+	queued := false
+	for _, notification := range notifications {
+		prefs, err := s.emailRepo.FindPreferences(notification.UserID)
+		if err != nil {
+			return err
+		}
+		if prefs == nil || !prefs.Wants(models.EmailNewPost) {
+			continue
+		}
+
+		data := emailData{
+			Subject: "New post: " + post.Title,
+			Source:  followSource(notification.SourceType),
+			Post:    *post,
+		}
+		added, err := s.queue(prefs, models.EmailNewPost, fmt.Sprintf("notification:%d", notification.ID), data)
+		if err != nil {
+			return err
+		}
+		queued = queued || added
+	}
+
+	if queued {
+		s.signal()
+	}
+	return nil
+}
+
+// QueueReply emails the recipient of a comment_reply notification if they
+// want such email
+func (s *EmailService) QueueReply(notification *models.Notification, reply *models.Comment) error {
+	prefs, err := s.emailRepo.FindPreferences(notification.UserID)
+	if err != nil {
+		return err
+	}
+	if prefs == nil || !prefs.Wants(models.EmailCommentReply) {
+		return nil
+	}
+
+	post, err := s.emailPost(notification.PostID)
+	if err != nil || post == nil {
+		return err
+	}
+
+	data := emailData{
+		Subject: fmt.Sprintf("%s replied to your comment on %q", reply.AuthorName, post.Title),
+		Post:    *post,
+		Reply:   reply,
+	}
+	queued, err := s.queue(prefs, models.EmailCommentReply, fmt.Sprintf("notification:%d", notification.ID), data)
+	if err != nil {
+		return err
+	}
+
+	if queued {
+		s.signal()
+	}
+	return nil
+}
+
+// QueueDigests queues a digest for every user who wants one and had none
+// for a week. It lists the posts they were notified of since their last
+// digest, or at most a week back; users with nothing new get no email that
+// week.
+func (s *EmailService) QueueDigests(now time.Time) error {
+	now = now.UTC()
+	recipients, err := s.emailRepo.FindDigestRecipients(now.Add(-digestPeriod))
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	for _, prefs := range recipients {
+		since := now.Add(-digestPeriod)
+		if prefs.LastDigestAt != nil && prefs.LastDigestAt.After(since) {
+			since = *prefs.LastDigestAt
+		}
+
+		notifications, err := s.notificationRepo.FindSince(prefs.UserID, models.NotificationNewPost, since, digestSize)
+		if err != nil {
+			return err
+		}
+
+		if len(notifications) > 0 {
+			data := emailData{Subject: "Your week on " + s.site.Title}
+			for _, notification := range notifications {
+				post, err := s.emailPost(notification.PostID)
+				if err != nil {
+					return err
+				}
+				if post != nil {
+					data.Posts = append(data.Posts, *post)
+				}
+			}
+
+			if len(data.Posts) > 0 {
+				key := fmt.Sprintf("digest:%d:%s", prefs.UserID, now.Format("2006-01-02"))
+				added, err := s.queue(prefs, models.EmailDigest, key, data)
+				if err != nil {
+					return err
+				}
+				queued = queued || added
+			}
+		}
+
+		if err := s.emailRepo.MarkDigestSent(prefs.UserID, now); err != nil {
+			return err
+		}
+	}
+
+	if queued {
+		s.signal()
+	}
+	return nil
+}
+
+// wanted reports whether the recipient of a queued email still wants it
+func (s *EmailService) wanted(email *models.QueuedEmail) (bool, error) {
+	if email.SubscriberID != 0 {
+		// Newsletter email is cancelled when its subscriber leaves the list
+		return true, nil
+	}
+
+	prefs, err := s.emailRepo.FindPreferences(email.UserID)
+	if err != nil {
+		return false, err
+	}
+	return prefs != nil && prefs.Wants(email.Kind) && prefs.Email == email.Recipient, nil
+}
+
+// queue renders the email of the given kind (named after its templates)
+// and stores it for sending
+func (s *EmailService) queue(prefs *models.EmailPreferences, kind, dedupeKey string, data emailData) (bool, error) {
+	data.Site = s.site
+	data.UnsubscribeURL = s.unsubscribeURL(prefs.UnsubscribeToken, kind)
+	data.UnsubscribeAllURL = s.unsubscribeURL(prefs.UnsubscribeToken, "all")
+
+	text, html, err := renderEmail(kind, data)
+	if err != nil {
+		return false, err
+	}
+
+	return s.emailRepo.Enqueue(&models.QueuedEmail{
+		UserID:    prefs.UserID,
+		Kind:      kind,
+		DedupeKey: dedupeKey,
+		Recipient: prefs.Email,
+		Subject:   data.Subject,
+		Text:      text,
+		HTML:      html,
+		Headers: map[string]string{
+			// RFC 8058 one-click unsubscribe
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+		Status:        models.EmailPending,
+		NextAttemptAt: time.Now().UTC(),
+	})
+}
+
+// renderEmail renders the text and HTML templates named after the kind
+func renderEmail(kind string, data interface{}) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textEmails.ExecuteTemplate(&textBuf, kind, data); err != nil {
+		return "", "", err
+	}
+	if err := htmlEmails.ExecuteTemplate(&htmlBuf, kind, data); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+// emailPost loads a published post for an email; it is nil once the post
+// is gone or unpublished
+func (s *EmailService) emailPost(postID int64) (*emailPost, error) {
+	post, err := s.queryService.GetPost(GetPostQuery{ID: postID})
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || post.Status != "published" {
+		return nil, nil
+	}
+
+	return &emailPost{
+		Title:       post.Title,
+		URL:         s.site.PostURL(post.Slug),
+		Excerpt:     post.Excerpt,
+		ReadingTime: post.ReadingTime,
+	}, nil
+}
+
+func (s *EmailService) unsubscribeURL(token, list string) string {
+	return s.config.UnsubscribeURL + "?" + url.Values{"token": {token}, "list": {list}}.Encode()
+}
+
+func (s *EmailService) signal() {
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
+
+// followSource explains which follow a new post notification came from
+func followSource(sourceType string) string {
+	switch sourceType {
+	case models.FollowAuthor:
+		return "by an author you follow"
+	case models.FollowSeries:
+		return "in a series you follow"
+	case models.FollowTag:
+		return "with a tag you follow"
+	default:
+		return ""
+	}
+}
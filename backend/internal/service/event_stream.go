@@ -0,0 +1,273 @@
+package service
+
+import (
+	"errors"
+	"sync"
+
+	"blog-platform/internal/models"
+)
+
+var ErrStreamClosed = errors.New("event stream is shutting down")
+
+// StreamFilter selects the events a stream client receives. Empty fields
+// match everything.
+type StreamFilter struct {
+	EventTypes []string
+	PostIDs    []int64
+}
+
+// Matches reports whether the filter lets the event through
+func (f StreamFilter) Matches(event PostEvent) bool {
+	if len(f.EventTypes) > 0 {
+		found := false
+		for _, eventType := range f.EventTypes {
+			if eventType == string(event.EventType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.PostIDs) > 0 {
+		for _, postID := range f.PostIDs {
+			if postID == event.PostID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// StreamSubscription is one connected client. Events arrive on C; when C
+// is closed the client should disconnect, and Lagged tells whether that is
+// because it fell too far behind.
+type StreamSubscription struct {
+	C      <-chan PostEvent
+	events chan PostEvent
+	filter StreamFilter
+	lagged bool
+}
+
+// Lagged reports whether the subscription was dropped for being too slow.
+// Only meaningful after C is closed.
+func (s *StreamSubscription) Lagged() bool {
+	return s.lagged
+}
+
+// EventStream is an observer that fans post events out to connected
+// clients (Server-Sent Events and WebSocket). It keeps the most recent
+// events so a reconnecting client can resume from its last event ID. Each
+// replica has its own stream, fed by an OutboxFollower rather than the
+// dispatcher, so clients get every event whichever replica they reach.
+//
+// Clients are not authenticated, so only what readers can already see is
+// streamed: events about drafts, scheduled posts and comments awaiting
+// moderation are left out, and posts and comments taken down are announced
+// without their content.
+//
+// Each client has a bounded queue. A client that lets its queue fill up is
+// disconnected rather than allowed to hold up everyone else; it can
+// reconnect and resume from the replay buffer.
+type EventStream struct {
+	mu          sync.Mutex
+	buffer      []PostEvent // ring of the latest events, oldest at start
+	start       int
+	size        int
+	subscribers map[*StreamSubscription]struct{}
+	queueSize   int
+	closed      bool
+}
+
+// NewEventStream creates a stream replaying up to replaySize events and
+// queueing up to queueSize events per client
+func NewEventStream(replaySize, queueSize int) *EventStream {
+	return &EventStream{
+		buffer:      make([]PostEvent, replaySize),
+		subscribers: make(map[*StreamSubscription]struct{}),
+		queueSize:   queueSize,
+	}
+}
+
+func (s *EventStream) Name() string {
+	return "event_stream"
+}
+
+// Update records the event for replay and queues it for every matching
+// client, unless it is not meant for readers
+func (s *EventStream) Update(event PostEvent) error {
+	event, public := publicEvent(event)
+	if !public {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buffer) > 0 {
+		if s.size < len(s.buffer) {
+			s.buffer[(s.start+s.size)%len(s.buffer)] = event
+			s.size++
+		} else {
+			s.buffer[s.start] = event
+			s.start = (s.start + 1) % len(s.buffer)
+		}
+	}
+
+	for sub := range s.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			s.drop(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a client. When lastEventID is non-zero, the buffered
+// events that followed it and match the filter are returned for replay;
+// they precede anything delivered on the subscription.
+func (s *EventStream) Subscribe(filter StreamFilter, lastEventID int64) (*StreamSubscription, []PostEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, nil, ErrStreamClosed
+	}
+
+	events := make(chan PostEvent, s.queueSize)
+	sub := &StreamSubscription{C: events, events: events, filter: filter}
+	s.subscribers[sub] = struct{}{}
+
+	var replay []PostEvent
+	if lastEventID != 0 {
+		replay = s.replayAfter(lastEventID, filter)
+	}
+
+	return sub, replay, nil
+}
+
+// Unsubscribe removes a client; it is safe to call more than once
+func (s *EventStream) Unsubscribe(sub *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(sub)
+}
+
+// Close disconnects every client and refuses new ones, so long-lived
+// connections do not hold up server shutdown
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		s.drop(sub)
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (s *EventStream) ClientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+func (s *EventStream) drop(sub *StreamSubscription) {
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// replayAfter returns the buffered events after lastEventID. The stream
+// is fed in ID order, so those are the events with a higher ID.
+func (s *EventStream) replayAfter(lastEventID int64, filter StreamFilter) []PostEvent {
+	var replay []PostEvent
+	for i := 0; i < s.size; i++ {
+		event := s.buffer[(s.start+i)%len(s.buffer)]
+		if event.ID > lastEventID && filter.Matches(event) {
+			replay = append(replay, event)
+		}
+	}
+
+	return replay
+}
+
+// publicEvent returns the event as readers may see it, or false when
+// readers should not see it at all
+func publicEvent(event PostEvent) (PostEvent, bool) {
+	switch data := event.Data.(type) {
+	case models.PostCreated:
+		return event, isPublished(data.Post)
+	case models.PostUpdated:
+		if isPublished(data.Post) {
+			return event, true
+		}
+		change, changed := data.Changes["status"]
+		if !changed || change.Before != "published" {
+			return event, false
+		}
+		// Unpublished: announce it, but not what changed
+		event.Data = models.PostUpdated{
+			Post:    redactedPost(data.Post),
+			Changes: map[string]models.FieldChange{"status": change},
+		}
+		return event, true
+	case models.PostDeleted:
+		if !isPublished(data.Post) {
+			return event, false
+		}
+		event.Data = models.PostDeleted{Post: redactedPost(data.Post)}
+		return event, true
+	case models.CommentModerated:
+		switch {
+		case data.Comment == nil:
+			return event, false
+		case data.Comment.Status == models.CommentApproved:
+			return event, true
+		case data.PreviousStatus == models.CommentApproved:
+			// Taken down: announce it without the comment
+			data.CommentEvent = redactedComment(data.CommentEvent)
+			event.Data = data
+			return event, true
+		default:
+			return event, false
+		}
+	default:
+		// comment_created: new comments await moderation
+		return event, false
+	}
+}
+
+func isPublished(post *models.Post) bool {
+	return post != nil && post.Status == "published"
+}
+
+// redactedPost keeps only what identifies a post
+func redactedPost(post *models.Post) *models.Post {
+	return &models.Post{ID: post.ID, Slug: post.Slug, Status: post.Status, CreatedAt: post.CreatedAt, UpdatedAt: post.UpdatedAt}
+}
+
+// redactedComment keeps only what identifies a comment
+func redactedComment(event models.CommentEvent) models.CommentEvent {
+	comment := event.Comment
+	return models.CommentEvent{Comment: &models.Comment{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		Status:    comment.Status,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}}
+}
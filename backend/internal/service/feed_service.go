@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/feed"
+)
+
+const feedSize = 20
+
+// FeedQuery selects the posts of a feed. At most one of AuthorID, Type,
+// TagSlug and SeriesSlug is expected; none gives the site-wide feed.
+type FeedQuery struct {
+	AuthorID   int64
+	Type       string
+	TagSlug    string
+	SeriesSlug string
+	Lang       string // site-wide and type feeds only: one version of each post, in this locale or its fallback
+	Full       bool   // include full content, not only summaries
+	FeedURL    string // where the feed is served, for its self link
+}
+
+// FeedService builds syndication feeds of the latest published posts. The
+// result is format independent; pkg/feed encodes it as RSS, Atom or JSON Feed.
+type FeedService struct {
+	queryService *QueryService
+	taxonomyRepo models.TaxonomyRepositoryInterface
+	seriesRepo   models.SeriesRepositoryInterface
+	site         SiteConfig
+}
+
+func NewFeedService(
+	queryService *QueryService,
+	taxonomyRepo models.TaxonomyRepositoryInterface,
+	seriesRepo models.SeriesRepositoryInterface,
+	site SiteConfig,
+) *FeedService {
+	return &FeedService{queryService: queryService, taxonomyRepo: taxonomyRepo, seriesRepo: seriesRepo, site: site}
+}
+
+// GetFeed returns the newest published posts matching the query
+func (s *FeedService) GetFeed(query FeedQuery) (*feed.Feed, error) {
+	result := &feed.Feed{
+		Title:       s.site.Title,
+		Description: s.site.Description,
+		Link:        s.site.URL,
+		FeedURL:     query.FeedURL,
+		Author:      s.site.Title,
+	}
+
+	var posts []PostViewModel
+	var err error
+	switch {
+	case query.AuthorID != 0:
+		result.Title = fmt.Sprintf("%s: posts by author %d", s.site.Title, query.AuthorID)
+		posts, err = s.queryService.ListPostsByAuthor(ListPostsByAuthorQuery{
+			AuthorID: query.AuthorID, Status: "published", Limit: feedSize,
+		})
+	case query.TagSlug != "":
+		var tag *models.Tag
+		if tag, err = s.taxonomyRepo.FindTagBySlug(query.TagSlug); err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			return nil, ErrTagNotFound
+		}
+		result.Title = fmt.Sprintf("%s: %s", s.site.Title, tag.Name)
+		posts, err = s.queryService.ListPostsByTag(ListPostsByTagQuery{
+			TagSlug: query.TagSlug, Status: "published", Limit: feedSize,
+		})
+	case query.SeriesSlug != "":
+		var series *models.Series
+		if series, err = s.seriesRepo.FindBySlug(query.SeriesSlug); err != nil {
+			return nil, err
+		}
+		if series == nil {
+			return nil, ErrSeriesNotFound
+		}
+		result.Title = fmt.Sprintf("%s: %s", s.site.Title, series.Title)
+		if series.Description != "" {
+			result.Description = series.Description
+		}
+		// Newest part first, like every other feed
+		posts, err = s.queryService.ListPostsInSeries(ListPostsInSeriesQuery{
+			SeriesSlug: query.SeriesSlug, Status: "published", NewestFirst: true, Limit: feedSize,
+		})
+	case query.Type != "":
+		result.Title = fmt.Sprintf("%s: %ss", s.site.Title, query.Type)
+		result.Language = s.feedLanguage(query.Lang)
+		posts, err = s.queryService.ListPosts(ListPostsQuery{
+			Status: "published", Type: query.Type, Lang: query.Lang, Limit: feedSize,
+		})
+	default:
+		result.Language = s.feedLanguage(query.Lang)
+		posts, err = s.queryService.ListPosts(ListPostsQuery{Status: "published", Lang: query.Lang, Limit: feedSize})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		if post.UpdatedAt.After(result.Updated) {
+			result.Updated = post.UpdatedAt
+		}
+		result.Items = append(result.Items, s.feedItem(post, query.Full))
+	}
+
+	return result, nil
+}
+
+// feedLanguage is the locale a feed asked for with lang is written in
+func (s *FeedService) feedLanguage(lang string) string {
+	if locales := s.site.Locales.Negotiate(lang, ""); len(locales) > 0 {
+		return locales[0]
+	}
+	return ""
+}
+
+func (s *FeedService) feedItem(post PostViewModel, full bool) feed.Item {
+	item := feed.Item{
+		ID:        s.itemID(post.ID),
+		Title:     post.Title,
+		Link:      s.site.PostURL(post.Slug),
+		Summary:   post.Excerpt,
+		Language:  post.Locale,
+		Author:    fmt.Sprintf("Author %d", post.AuthorID),
+		Published: post.CreatedAt,
+		Updated:   post.UpdatedAt,
+	}
+	if full {
+		item.ContentHTML = post.ContentHTML
+	}
+	for _, tag := range post.Tags {
+		item.Tags = append(item.Tags, tag.Name)
+	}
+	for _, translation := range post.Translations {
+		if translation.Status == "published" {
+			item.Alternates = append(item.Alternates, feed.Alternate{
+				Language: translation.Locale,
+				Link:     s.site.PostURL(translation.Slug),
+			})
+		}
+	}
+	return item
+}
+
+// itemID identifies a post's feed item. It keeps the ID-based form item
+// links used to have, so it survives slug changes and readers never see a
+// post they already read as a new item.
+func (s *FeedService) itemID(postID int64) string {
+	return fmt.Sprintf("%s/posts/%d", s.site.URL, postID)
+}
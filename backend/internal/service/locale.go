@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+var ErrUnsupportedLocale = errors.New("locale is not one of the site's locales")
+
+// LocaleConfig lists the languages posts are written in and how a reader's
+// preferences fall back when no post matches them exactly
+type LocaleConfig struct {
+	Default   string   // used when nothing the reader asked for is available
+	Supported []string // canonical BCP 47 tags, Default first
+
+	// Fallbacks names, per locale, the locale to try next before the
+	// locale's parent (de-AT falls back to de) and finally Default
+	Fallbacks map[string]string
+}
+
+// NewLocaleConfig parses a comma separated list of locales, the first being
+// the default, and fallbacks written as "from=to" pairs, e.g. "pt-BR=pt-PT"
+func NewLocaleConfig(locales, fallbacks string) (LocaleConfig, error) {
+	config := LocaleConfig{Fallbacks: make(map[string]string)}
+	for _, value := range strings.Split(locales, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		locale, err := canonicalLocale(value)
+		if err != nil {
+			return LocaleConfig{}, err
+		}
+		config.Supported = append(config.Supported, locale)
+	}
+	if len(config.Supported) == 0 {
+		config.Supported = []string{"en"}
+	}
+	config.Default = config.Supported[0]
+
+	for _, pair := range strings.Split(fallbacks, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok {
+			return LocaleConfig{}, errors.New("locale fallbacks must be written as from=to")
+		}
+		fromLocale, err := canonicalLocale(from)
+		if err != nil {
+			return LocaleConfig{}, err
+		}
+		toLocale, err := canonicalLocale(to)
+		if err != nil {
+			return LocaleConfig{}, err
+		}
+		config.Fallbacks[fromLocale] = toLocale
+	}
+
+	return config, nil
+}
+
+// Normalize returns the canonical form of a supported locale, or the
+// default for an empty one
+func (c LocaleConfig) Normalize(locale string) (string, error) {
+	if locale == "" {
+		return c.Default, nil
+	}
+	canonical, err := canonicalLocale(locale)
+	if err != nil || !c.supports(canonical) {
+		return "", ErrUnsupportedLocale
+	}
+	return canonical, nil
+}
+
+// Negotiate turns an explicit ?lang= choice, or else an Accept-Language
+// header, into the supported locales to try in order. Each requested
+// locale is followed by its fallbacks and the list ends with the default.
+// Nil means the reader expressed no preference, or asked for every
+// language with "*", and content should not be filtered.
+func (c LocaleConfig) Negotiate(lang, acceptLanguage string) []string {
+	var requested []string
+	switch {
+	case lang == "*":
+		return nil
+	case lang != "":
+		requested = []string{lang}
+	case acceptLanguage != "":
+		tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+		if err != nil {
+			return nil
+		}
+		for _, tag := range tags {
+			requested = append(requested, tag.String())
+		}
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		if !seen[locale] && c.supports(locale) {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+	for _, value := range requested {
+		if value == "*" || value == "und" {
+			continue
+		}
+		tag, err := language.Parse(value)
+		if err != nil {
+			continue
+		}
+		for _, locale := range c.fallbackChain(tag) {
+			add(locale)
+		}
+	}
+	add(c.Default)
+
+	return chain
+}
+
+// fallbackChain lists a locale, its configured fallbacks and its parents
+func (c LocaleConfig) fallbackChain(tag language.Tag) []string {
+	var chain []string
+	for !tag.IsRoot() {
+		locale := tag.String()
+		chain = append(chain, locale)
+		for next, ok := c.Fallbacks[locale]; ok && len(chain) < 10; next, ok = c.Fallbacks[next] {
+			chain = append(chain, next)
+		}
+		tag = tag.Parent()
+	}
+	return chain
+}
+
+func (c LocaleConfig) supports(locale string) bool {
+	for _, supported := range c.Supported {
+		if supported == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalLocale validates a BCP 47 tag and returns it in canonical case,
+// e.g. pt-br becomes pt-BR
+func canonicalLocale(value string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", ErrUnsupportedLocale
+	}
+	return tag.String(), nil
+}
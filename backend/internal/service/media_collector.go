@@ -0,0 +1,41 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// mediaCollectorLease is the lease name shared by every replica running the collector
+const mediaCollectorLease = "media_collector"
+
+// MediaCollector periodically deletes uploads that no post references. The
+// grace period spares files uploaded for a post that has not been saved
+// yet.
+type MediaCollector struct {
+	*leasedWorker
+	mediaService *MediaService
+	grace        time.Duration
+}
+
+func NewMediaCollector(
+	mediaService *MediaService,
+	leaseRepo models.LeaseRepositoryInterface,
+	interval, grace time.Duration,
+) *MediaCollector {
+	c := &MediaCollector{mediaService: mediaService, grace: grace}
+	c.leasedWorker = newLeasedWorker(mediaCollectorLease, leaseRepo, interval, 2*interval, nil, c.collect)
+	return c
+}
+
+// collect removes media unreferenced and older than the grace period
+func (c *MediaCollector) collect(now time.Time) {
+	removed, err := c.mediaService.CollectGarbage(now.Add(-c.grace))
+	if err != nil {
+		log.Printf("Error collecting unused media: %v", err)
+	}
+	if removed > 0 {
+		log.Printf("Media collector removed %d unused files", removed)
+	}
+}
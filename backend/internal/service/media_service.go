@@ -0,0 +1,463 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/imaging"
+	"blog-platform/pkg/storage"
+)
+
+var (
+	ErrMediaNotFound        = errors.New("media not found")
+	ErrMediaTooLarge        = errors.New("file exceeds the upload size limit")
+	ErrUnsupportedMediaType = errors.New("unsupported file type; expected an image, PDF, MP4, WebM or MP3 file")
+	ErrMediaInUse           = errors.New("media is referenced by posts")
+)
+
+// mediaTypes maps the accepted content types, as sniffed from the file's
+// first bytes, to the extension files are stored under
+var mediaTypes = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/gif":       "gif",
+	"image/webp":      "webp",
+	"application/pdf": "pdf",
+	"video/mp4":       "mp4",
+	"video/webm":      "webm",
+	"audio/mpeg":      "mp3",
+}
+
+// mediaVariants are the resized copies made of JPEG, PNG and GIF uploads
+// larger than the variant
+var mediaVariants = []struct {
+	name          string
+	width, height int
+}{
+	{"thumbnail", 320, 320},
+	{"medium", 1024, 1024},
+}
+
+// maxImagePixels bounds the images decoded for variants, so a small file
+// claiming huge dimensions cannot exhaust memory
+const maxImagePixels = 40_000_000
+
+// mediaKey matches the storage key of an original or a variant. Posts
+// reference media by URL, and every URL ends in its key.
+var mediaKey = regexp.MustCompile(`([0-9a-f]{64})(?:-[a-z]+)?\.[a-z0-9]+`)
+
+var exactMediaKey = regexp.MustCompile(`^` + mediaKey.String() + `$`)
+
+type UploadMediaCommand struct {
+	Filename   string
+	Data       io.Reader
+	UploaderID int64
+}
+
+type MediaVariantViewModel struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+type MediaViewModel struct {
+	ID          int64                   `json:"id"`
+	Hash        string                  `json:"hash"` // SHA-256 of the file
+	URL         string                  `json:"url"`
+	Filename    string                  `json:"filename"`
+	ContentType string                  `json:"content_type"`
+	Size        int64                   `json:"size"`
+	Width       int                     `json:"width,omitempty"`
+	Height      int                     `json:"height,omitempty"`
+	UploaderID  int64                   `json:"uploader_id"`
+	CreatedAt   time.Time               `json:"created_at"`
+	Variants    []MediaVariantViewModel `json:"variants"`
+	PostIDs     []int64                 `json:"post_ids,omitempty"` // posts referencing the file
+}
+
+// MediaObject is a stored file opened for reading
+type MediaObject struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+	Hash        string
+	CreatedAt   time.Time
+}
+
+// MediaService stores uploads and keeps track of which posts use them.
+// Files are named by their SHA-256, so identical uploads share one record
+// and one set of objects, and their URLs never change meaning. As an
+// observer of post events it records the media each post's content and images link
+// to; media nothing links to is removed by the MediaCollector.
+type MediaService struct {
+	mediaRepo models.MediaRepositoryInterface
+	storage   storage.Storage
+	baseURL   string
+	maxSize   int64
+}
+
+// NewMediaService serves stored files under baseURL, e.g. "/media" or a
+// CDN origin, and rejects uploads larger than maxSize bytes
+func NewMediaService(mediaRepo models.MediaRepositoryInterface, store storage.Storage, baseURL string, maxSize int64) *MediaService {
+	return &MediaService{
+		mediaRepo: mediaRepo,
+		storage:   store,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		maxSize:   maxSize,
+	}
+}
+
+func (s *MediaService) Name() string {
+	return "media_references"
+}
+
+// MaxSize is the largest accepted upload in bytes
+func (s *MediaService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload stores a file. The type is sniffed from the content; the
+// uploaded name and declared type are not trusted. When the same bytes were
+// uploaded before, the existing media is returned and created is false.
+func (s *MediaService) Upload(cmd UploadMediaCommand) (media *MediaViewModel, created bool, err error) {
+	data, err := io.ReadAll(io.LimitReader(cmd.Data, s.maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, false, ErrMediaTooLarge
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, ok := mediaTypes[contentType]
+	if !ok || len(data) == 0 {
+		return nil, false, ErrUnsupportedMediaType
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := s.mediaRepo.FindByHash(hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return s.toViewModel(existing), false, nil
+	}
+
+	record := &models.Media{
+		Hash:        hash,
+		StorageKey:  hash + "." + ext,
+		Filename:    uploadName(cmd.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		UploaderID:  cmd.UploaderID,
+	}
+
+	if err := s.addImageVariants(record, data); err != nil {
+		return nil, false, err
+	}
+	if err := s.storage.Put(record.StorageKey, bytes.NewReader(data)); err != nil {
+		return nil, false, err
+	}
+
+	// Another upload of the same file may have won the race; its record
+	// and objects are identical, so either is fine
+	created, err = s.mediaRepo.Create(record)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.toViewModel(record), created, nil
+}
+
+// uploadName keeps the base name of an uploaded file for display
+func uploadName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// addImageVariants records the dimensions of JPEG, PNG and GIF images and
+// stores a resized copy for each variant smaller than the image. Other
+// files, and images that fail to decode, are stored without variants.
+func (s *MediaService) addImageVariants(media *models.Media, data []byte) error {
+	switch media.ContentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	media.Width, media.Height = config.Width, config.Height
+	if config.Width*config.Height > maxImagePixels {
+		return nil
+	}
+
+	var img image.Image
+	for _, variant := range mediaVariants {
+		if media.Width <= variant.width && media.Height <= variant.height {
+			continue
+		}
+		if img == nil {
+			if img, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+				log.Printf("Skipping variants of media %s: %v", media.Hash, err)
+				return nil
+			}
+		}
+
+		resized := imaging.Fit(img, variant.width, variant.height)
+
+		var encoded bytes.Buffer
+		contentType, ext := "image/png", "png"
+		if media.ContentType == "image/jpeg" {
+			contentType, ext = "image/jpeg", "jpg"
+			err = jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&encoded, resized)
+		}
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s-%s.%s", media.Hash, variant.name, ext)
+		size := int64(encoded.Len())
+		if err := s.storage.Put(key, &encoded); err != nil {
+			return err
+		}
+		media.Variants = append(media.Variants, models.MediaVariant{
+			Name:        variant.name,
+			StorageKey:  key,
+			ContentType: contentType,
+			Size:        size,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+		})
+	}
+	return nil
+}
+
+// GetMedia returns media with the posts referencing it
+func (s *MediaService) GetMedia(id int64) (*MediaViewModel, error) {
+	media, err := s.mediaRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if media == nil {
+		return nil, ErrMediaNotFound
+	}
+
+	vm := s.toViewModel(media)
+	if vm.PostIDs, err = s.mediaRepo.FindReferencingPostIDs(id); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// ListMedia returns media newest first
+func (s *MediaService) ListMedia(limit, offset int) ([]*MediaViewModel, error) {
+	media, err := s.mediaRepo.FindAll(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]*MediaViewModel, len(media))
+	for i, item := range media {
+		viewModels[i] = s.toViewModel(item)
+	}
+	return viewModels, nil
+}
+
+// DeleteMedia removes media no post references
+func (s *MediaService) DeleteMedia(id int64) error {
+	media, err := s.mediaRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if media == nil {
+		return ErrMediaNotFound
+	}
+
+	postIDs, err := s.mediaRepo.FindReferencingPostIDs(id)
+	if err != nil {
+		return err
+	}
+	if len(postIDs) > 0 {
+		return ErrMediaInUse
+	}
+
+	return s.remove(media)
+}
+
+// remove deletes the record before the objects, so a failure leaves at
+// worst unreachable objects rather than a record without its file
+func (s *MediaService) remove(media *models.Media) error {
+	if err := s.mediaRepo.Delete(media.ID); err != nil {
+		return err
+	}
+
+	keys := []string{media.StorageKey}
+	for _, variant := range media.Variants {
+		keys = append(keys, variant.StorageKey)
+	}
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			log.Printf("Error deleting media object %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// CollectGarbage removes media created before the cutoff that no post
+// references, returning how many were removed
+func (s *MediaService) CollectGarbage(createdBefore time.Time) (int, error) {
+	const batchSize = 100
+	removed := 0
+	for {
+		orphans, err := s.mediaRepo.FindOrphans(createdBefore, batchSize)
+		if err != nil {
+			return removed, err
+		}
+		for _, media := range orphans {
+			if err := s.remove(media); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		if len(orphans) < batchSize {
+			return removed, nil
+		}
+	}
+}
+
+// Open returns a stored original or variant by key
+func (s *MediaService) Open(key string) (*MediaObject, error) {
+	m := exactMediaKey.FindStringSubmatch(key)
+	if m == nil {
+		return nil, ErrMediaNotFound
+	}
+
+	media, err := s.mediaRepo.FindByHash(m[1])
+	if err != nil {
+		return nil, err
+	}
+	if media == nil {
+		return nil, ErrMediaNotFound
+	}
+
+	object := &MediaObject{Hash: media.Hash, CreatedAt: media.CreatedAt}
+	switch {
+	case key == media.StorageKey:
+		object.ContentType, object.Size = media.ContentType, media.Size
+	default:
+		for _, variant := range media.Variants {
+			if key == variant.StorageKey {
+				object.ContentType, object.Size = variant.ContentType, variant.Size
+			}
+		}
+	}
+	if object.ContentType == "" {
+		return nil, ErrMediaNotFound
+	}
+
+	reader, err := s.storage.Open(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	object.ReadCloser = reader
+	return object, nil
+}
+
+// Update records the media a post links to whenever its content or images change.
+// References of deleted posts go with the post.
+func (s *MediaService) Update(event PostEvent) error {
+	var post *models.Post
+	switch data := event.Data.(type) {
+	case models.PostCreated:
+		post = data.Post
+	case models.PostUpdated:
+		_, content := data.Changes["content"]
+		_, cover := data.Changes["cover_image"]
+		_, seo := data.Changes["seo"]
+		if !content && !cover && !seo {
+			return nil
+		}
+		post = data.Post
+	}
+	if post == nil {
+		return nil
+	}
+
+	return s.mediaRepo.SetPostMedia(post.ID, referencedMedia(post.Content, post.CoverImage, post.SEO.SocialImage))
+}
+
+// referencedMedia returns the hashes of the media keys in the texts
+func referencedMedia(texts ...string) []string {
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, text := range texts {
+		for _, m := range mediaKey.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				hashes = append(hashes, m[1])
+			}
+		}
+	}
+	return hashes
+}
+
+func (s *MediaService) toViewModel(media *models.Media) *MediaViewModel {
+	vm := &MediaViewModel{
+		ID:          media.ID,
+		Hash:        media.Hash,
+		URL:         s.baseURL + "/" + media.StorageKey,
+		Filename:    media.Filename,
+		ContentType: media.ContentType,
+		Size:        media.Size,
+		Width:       media.Width,
+		Height:      media.Height,
+		UploaderID:  media.UploaderID,
+		CreatedAt:   media.CreatedAt,
+		Variants:    []MediaVariantViewModel{},
+	}
+	for _, variant := range media.Variants {
+		vm.Variants = append(vm.Variants, MediaVariantViewModel{
+			Name:        variant.Name,
+			URL:         s.baseURL + "/" + variant.StorageKey,
+			ContentType: variant.ContentType,
+			Size:        variant.Size,
+			Width:       variant.Width,
+			Height:      variant.Height,
+		})
+	}
+	return vm
+}
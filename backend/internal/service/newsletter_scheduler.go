@@ -0,0 +1,36 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// newsletterLease is the lease name shared by every replica sending campaigns
+const newsletterLease = "newsletter_scheduler"
+
+// NewsletterScheduler queues scheduled campaigns for their subscribers once
+// they fall due. The EmailSender then delivers them at its rate limit.
+type NewsletterScheduler struct {
+	*leasedWorker
+	newsletterService *NewsletterService
+}
+
+func NewNewsletterScheduler(
+	newsletterService *NewsletterService,
+	leaseRepo models.LeaseRepositoryInterface,
+	interval time.Duration,
+) *NewsletterScheduler {
+	s := &NewsletterScheduler{newsletterService: newsletterService}
+	// Polls every interval and wakes early whenever a campaign is scheduled
+	s.leasedWorker = newLeasedWorker(newsletterLease, leaseRepo, interval, 2*time.Minute, newsletterService.Scheduled(), s.sendDue)
+	return s
+}
+
+// sendDue sends every campaign due at now
+func (s *NewsletterScheduler) sendDue(now time.Time) {
+	if err := s.newsletterService.SendDue(now); err != nil {
+		log.Printf("Error sending campaigns: %v", err)
+	}
+}
@@ -0,0 +1,866 @@
+package service
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/slug"
+)
+
+// maxSoftBounces is how many temporary bounces take an address off its
+// lists; a permanent bounce does so at once
+const maxSoftBounces = 3
+
+// Campaign retries: a campaign that fails to be queued is tried again with
+// exponential backoff, then marked failed
+const (
+	campaignMaxAttempts = 5
+	campaignBaseBackoff = time.Minute
+	campaignMaxBackoff  = time.Hour
+)
+
+// webhookTolerance is how far a bounce or unsubscribe webhook's timestamp
+// may be from now
+const webhookTolerance = 5 * time.Minute
+
+var (
+	ErrNewsletterListNotFound  = errors.New("newsletter list not found")
+	ErrNewsletterListExists    = errors.New("a newsletter list with this slug already exists")
+	ErrNewsletterNameMissing   = errors.New("name is required")
+	ErrCampaignNotFound        = errors.New("campaign not found")
+	ErrCampaignSubjectMissing  = errors.New("subject is required")
+	ErrInvalidCampaignPosts    = errors.New("post_ids must list distinct published posts")
+	ErrCampaignLocked          = errors.New("the campaign is being sent or was sent")
+	ErrCampaignNotScheduled    = errors.New("the campaign is not scheduled")
+	ErrInvalidSubscriberToken  = errors.New("subscription link is invalid")
+	ErrInvalidTrackingLink     = errors.New("tracking link is invalid")
+	ErrInvalidWebhookSignature = errors.New("webhook signature is invalid")
+)
+
+// NewsletterConfig configures newsletter links and webhooks
+type NewsletterConfig struct {
+	URL           string // public URL of the newsletter endpoints, used in links in email
+	WebhookSecret string // signs bounce and unsubscribe webhooks; they are refused without one
+}
+
+type CreateNewsletterListCommand struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+}
+
+type SubscribeCommand struct {
+	ListSlug string `json:"-"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+}
+
+type CreateCampaignCommand struct {
+	ListID  int64   `json:"list_id"`
+	Subject string  `json:"subject"`
+	Intro   string  `json:"intro"`
+	PostIDs []int64 `json:"post_ids"`
+}
+
+// UpdateCampaignCommand changes the fields that are set
+type UpdateCampaignCommand struct {
+	ID      int64   `json:"-"`
+	ListID  *int64  `json:"list_id"`
+	Subject *string `json:"subject"`
+	Intro   *string `json:"intro"`
+	PostIDs []int64 `json:"post_ids"`
+}
+
+// ScheduleCampaignCommand schedules a campaign for SendAt, or right away
+type ScheduleCampaignCommand struct {
+	ID     int64      `json:"-"`
+	SendAt *time.Time `json:"send_at"`
+}
+
+// BounceCommand reports an address the mail provider could not deliver to
+type BounceCommand struct {
+	Email      string `json:"email"`
+	Permanent  bool   `json:"permanent"`
+	CampaignID int64  `json:"campaign_id"` // defaults to the last campaign sent to the address
+}
+
+// WebhookUnsubscribeCommand reports an address that unsubscribed or
+// complained through the mail provider
+type WebhookUnsubscribeCommand struct {
+	Email      string `json:"email"`
+	List       string `json:"list"`        // slug; all lists when empty
+	CampaignID int64  `json:"campaign_id"` // defaults to the last campaign sent to the address
+}
+
+// newsletterData is what newsletter templates are rendered with
+type newsletterData struct {
+	Site           SiteConfig
+	Subject        string
+	List           *models.NewsletterList
+	Intro          string
+	Posts          []emailPost
+	ConfirmURL     string
+	UnsubscribeURL string
+	OpenURL        string
+}
+
+// NewsletterService manages newsletter lists with double opt-in, composes
+// campaigns from published posts and queues them for the EmailSender, and
+// tracks what recipients do with them
+type NewsletterService struct {
+	newsletterRepo models.NewsletterRepositoryInterface
+	postRepo       models.PostRepositoryInterface
+	emails         *EmailService
+	site           SiteConfig
+	config         NewsletterConfig
+	scheduled      chan struct{}
+}
+
+func NewNewsletterService(
+	newsletterRepo models.NewsletterRepositoryInterface,
+	postRepo models.PostRepositoryInterface,
+	emails *EmailService,
+	site SiteConfig,
+	config NewsletterConfig,
+) *NewsletterService {
+	return &NewsletterService{
+		newsletterRepo: newsletterRepo,
+		postRepo:       postRepo,
+		emails:         emails,
+		site:           site,
+		config:         config,
+		scheduled:      make(chan struct{}, 1),
+	}
+}
+
+// Scheduled fires after a campaign is scheduled; it may also fire spuriously
+func (s *NewsletterService) Scheduled() <-chan struct{} {
+	return s.scheduled
+}
+
+func (s *NewsletterService) CreateList(cmd CreateNewsletterListCommand) (*models.NewsletterList, error) {
+	name := strings.TrimSpace(cmd.Name)
+	listSlug := slug.Make(cmd.Slug)
+	if listSlug == "" {
+		listSlug = slug.Make(name)
+	}
+	if name == "" || listSlug == "" {
+		return nil, ErrNewsletterNameMissing
+	}
+
+	existing, err := s.newsletterRepo.FindListBySlug(listSlug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrNewsletterListExists
+	}
+
+	list := &models.NewsletterList{Name: name, Slug: listSlug, Description: cmd.Description}
+	if err := s.newsletterRepo.CreateList(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *NewsletterService) ListLists() ([]*models.NewsletterList, error) {
+	lists, err := s.newsletterRepo.FindLists()
+	if err != nil {
+		return nil, err
+	}
+	if lists == nil {
+		lists = []*models.NewsletterList{}
+	}
+	return lists, nil
+}
+
+func (s *NewsletterService) GetList(listSlug string) (*models.NewsletterList, error) {
+	list, err := s.newsletterRepo.FindListBySlug(listSlug)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, ErrNewsletterListNotFound
+	}
+	return list, nil
+}
+
+// DeleteList removes a list with its subscribers and campaigns
+func (s *NewsletterService) DeleteList(id int64) error {
+	deleted, err := s.newsletterRepo.DeleteList(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNewsletterListNotFound
+	}
+	return nil
+}
+
+// ListSubscribers lists a list's subscribers, newest first, optionally
+// only those in one state
+func (s *NewsletterService) ListSubscribers(listSlug, status string, limit, offset int) ([]*models.Subscriber, error) {
+	list, err := s.GetList(listSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.newsletterRepo.FindSubscribers(list.ID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if subs == nil {
+		subs = []*models.Subscriber{}
+	}
+	return subs, nil
+}
+
+// Subscribe adds an address to a list pending confirmation and emails it a
+// confirmation link. Subscribing an address that is already confirmed does
+// nothing, so the response does not reveal who is subscribed; a pending
+// address gets at most one confirmation email an hour.
+func (s *NewsletterService) Subscribe(cmd SubscribeCommand) error {
+	email, err := normalizeEmail(cmd.Email)
+	if err != nil {
+		return err
+	}
+	list, err := s.GetList(cmd.ListSlug)
+	if err != nil {
+		return err
+	}
+
+	sub, err := s.newsletterRepo.FindSubscriber(list.ID, email)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSpace(cmd.Name)
+
+	switch {
+	case sub == nil:
+		token, err := randomHex(24)
+		if err != nil {
+			return err
+		}
+		sub = &models.Subscriber{ListID: list.ID, Email: email, Name: name, Status: models.SubscriberPending, Token: token}
+		if err := s.newsletterRepo.CreateSubscriber(sub); err != nil {
+			return err
+		}
+	case sub.Status == models.SubscriberConfirmed:
+		return nil
+	default:
+		sub.Status = models.SubscriberPending
+		if name != "" {
+			sub.Name = name
+		}
+		if err := s.newsletterRepo.UpdateSubscriber(sub); err != nil {
+			return err
+		}
+	}
+
+	data := newsletterData{
+		Site:       s.site,
+		Subject:    "Confirm your subscription to " + list.Name,
+		List:       list,
+		ConfirmURL: s.config.URL + "/confirm?" + url.Values{"token": {sub.Token}}.Encode(),
+	}
+	key := fmt.Sprintf("newsletter_confirm:%d:%s", sub.ID, time.Now().UTC().Format("2006010215"))
+	queued, err := s.enqueue(sub, 0, models.EmailNewsletterConfirm, key, data, nil)
+	if err != nil {
+		return err
+	}
+
+	if queued {
+		s.emails.signal()
+	}
+	return nil
+}
+
+// Confirm completes the double opt-in of the subscriber with the token
+func (s *NewsletterService) Confirm(token string) (*models.Subscriber, error) {
+	sub, err := s.subscriberByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sub.Status {
+	case models.SubscriberConfirmed:
+		return sub, nil
+	case models.SubscriberPending:
+		now := time.Now().UTC()
+		sub.Status = models.SubscriberConfirmed
+		sub.ConfirmedAt = &now
+		sub.UnsubscribedAt = nil
+		sub.BounceCount = 0
+		if err := s.newsletterRepo.UpdateSubscriber(sub); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	default:
+		// Left the list since; subscribing again sends a new link
+		return nil, ErrInvalidSubscriberToken
+	}
+}
+
+// Unsubscribe takes the subscriber with the token off their list. It
+// needs no login, so it works from the link in an email and from one-click
+// unsubscribe in mail clients. campaignID, if set, is the campaign the
+// link was in.
+func (s *NewsletterService) Unsubscribe(token string, campaignID int64) (*models.Subscriber, error) {
+	sub, err := s.subscriberByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.leave([]*models.Subscriber{sub}, models.SubscriberUnsubscribed); err != nil {
+		return nil, err
+	}
+	if campaignID != 0 {
+		if err := s.newsletterRepo.RecordUnsubscribe(campaignID, sub.ID); err != nil {
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+func (s *NewsletterService) subscriberByToken(token string) (*models.Subscriber, error) {
+	if token == "" {
+		return nil, ErrInvalidSubscriberToken
+	}
+	sub, err := s.newsletterRepo.FindSubscriberByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrInvalidSubscriberToken
+	}
+	return sub, nil
+}
+
+// leave moves subscribers who were on their list to status and cancels
+// the email still queued for them
+func (s *NewsletterService) leave(subs []*models.Subscriber, status string) error {
+	now := time.Now().UTC()
+	var ids []int64
+	for _, sub := range subs {
+		if sub.Status != models.SubscriberPending && sub.Status != models.SubscriberConfirmed {
+			continue
+		}
+		sub.Status = status
+		if status == models.SubscriberUnsubscribed {
+			sub.UnsubscribedAt = &now
+		}
+		if err := s.newsletterRepo.UpdateSubscriber(sub); err != nil {
+			return err
+		}
+		ids = append(ids, sub.ID)
+	}
+
+	_, err := s.emails.emailRepo.CancelForSubscribers(ids)
+	return err
+}
+
+func (s *NewsletterService) CreateCampaign(cmd CreateCampaignCommand) (*models.Campaign, error) {
+	campaign := &models.Campaign{
+		ListID:  cmd.ListID,
+		Subject: strings.TrimSpace(cmd.Subject),
+		Intro:   strings.TrimSpace(cmd.Intro),
+		PostIDs: cmd.PostIDs,
+		Status:  models.CampaignDraft,
+	}
+	if err := s.validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	if err := s.newsletterRepo.CreateCampaign(campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// UpdateCampaign edits a campaign that has not been sent. A scheduled
+// campaign stays scheduled.
+func (s *NewsletterService) UpdateCampaign(cmd UpdateCampaignCommand) (*models.Campaign, error) {
+	campaign, err := s.editableCampaign(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.ListID != nil {
+		campaign.ListID = *cmd.ListID
+	}
+	if cmd.Subject != nil {
+		campaign.Subject = strings.TrimSpace(*cmd.Subject)
+	}
+	if cmd.Intro != nil {
+		campaign.Intro = strings.TrimSpace(*cmd.Intro)
+	}
+	if cmd.PostIDs != nil {
+		campaign.PostIDs = cmd.PostIDs
+	}
+	if err := s.validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (s *NewsletterService) GetCampaign(id int64) (*models.Campaign, error) {
+	campaign, err := s.newsletterRepo.FindCampaignByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	return campaign, nil
+}
+
+func (s *NewsletterService) ListCampaigns(limit, offset int) ([]*models.Campaign, error) {
+	campaigns, err := s.newsletterRepo.FindCampaigns(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if campaigns == nil {
+		campaigns = []*models.Campaign{}
+	}
+	return campaigns, nil
+}
+
+// DeleteCampaign removes a campaign, along with its statistics if it was
+// sent. A campaign cannot be deleted while it is being sent.
+func (s *NewsletterService) DeleteCampaign(id int64) error {
+	campaign, err := s.GetCampaign(id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status == models.CampaignSending {
+		return ErrCampaignLocked
+	}
+
+	_, err = s.newsletterRepo.DeleteCampaign(id)
+	return err
+}
+
+// ScheduleCampaign queues a campaign to go out at SendAt, or within
+// moments when it is not set. Its posts must still be published.
+func (s *NewsletterService) ScheduleCampaign(cmd ScheduleCampaignCommand) (*models.Campaign, error) {
+	campaign, err := s.editableCampaign(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	sendAt := time.Now().UTC()
+	if cmd.SendAt != nil {
+		sendAt = cmd.SendAt.UTC()
+	}
+	campaign.Status = models.CampaignScheduled
+	campaign.ScheduledAt = &sendAt
+	campaign.Attempts = 0
+	campaign.Error = ""
+	if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.scheduled <- struct{}{}:
+	default:
+	}
+	return campaign, nil
+}
+
+// UnscheduleCampaign turns a scheduled campaign back into a draft
+func (s *NewsletterService) UnscheduleCampaign(id int64) (*models.Campaign, error) {
+	campaign, err := s.GetCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status != models.CampaignScheduled {
+		return nil, ErrCampaignNotScheduled
+	}
+
+	campaign.Status = models.CampaignDraft
+	campaign.ScheduledAt = nil
+	if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// CampaignStats reports how a campaign's email fared
+func (s *NewsletterService) CampaignStats(id int64) (*models.CampaignStats, error) {
+	if _, err := s.GetCampaign(id); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.newsletterRepo.FindCampaignStats(id)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Sent > 0 {
+		stats.OpenRate = float64(stats.Opens) / float64(stats.Sent)
+		stats.ClickRate = float64(stats.Clicks) / float64(stats.Sent)
+	}
+	return stats, nil
+}
+
+func (s *NewsletterService) editableCampaign(id int64) (*models.Campaign, error) {
+	campaign, err := s.GetCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status == models.CampaignSending || campaign.Status == models.CampaignSent {
+		return nil, ErrCampaignLocked
+	}
+	return campaign, nil
+}
+
+func (s *NewsletterService) validateCampaign(campaign *models.Campaign) error {
+	if campaign.Subject == "" {
+		return ErrCampaignSubjectMissing
+	}
+
+	list, err := s.newsletterRepo.FindListByID(campaign.ListID)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return ErrNewsletterListNotFound
+	}
+
+	if len(campaign.PostIDs) == 0 {
+		return ErrInvalidCampaignPosts
+	}
+	seen := make(map[int64]bool, len(campaign.PostIDs))
+	for _, postID := range campaign.PostIDs {
+		if seen[postID] {
+			return ErrInvalidCampaignPosts
+		}
+		seen[postID] = true
+
+		post, err := s.postRepo.FindByID(postID)
+		if err != nil {
+			return err
+		}
+		if post == nil || post.Status != "published" {
+			return ErrInvalidCampaignPosts
+		}
+	}
+	return nil
+}
+
+// SendDue queues every campaign due at now for its list's confirmed
+// subscribers. Queuing is idempotent per subscriber, so a campaign
+// interrupted half-way is finished on the next run without sending twice.
+// A campaign that fails does not hold up the others; it is retried after a
+// backoff and marked failed after campaignMaxAttempts.
+func (s *NewsletterService) SendDue(now time.Time) error {
+	campaigns, err := s.newsletterRepo.FindDueCampaigns(now)
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range campaigns {
+		if campaign.Attempts > 0 {
+			backoff := exponentialBackoff(campaignBaseBackoff, campaignMaxBackoff, campaign.Attempts)
+			if now.Before(campaign.UpdatedAt.Add(backoff)) {
+				continue
+			}
+		}
+
+		if err := s.send(campaign); err != nil {
+			s.recordFailure(campaign, err)
+		}
+	}
+	return nil
+}
+
+// recordFailure logs a failed attempt to queue a campaign and counts it,
+// failing the campaign once it runs out of attempts
+func (s *NewsletterService) recordFailure(campaign *models.Campaign, err error) {
+	log.Printf("Error sending campaign %d: %v", campaign.ID, err)
+
+	campaign.Attempts++
+	campaign.Error = err.Error()
+	if campaign.Attempts >= campaignMaxAttempts {
+		campaign.Status = models.CampaignFailed
+		log.Printf("Campaign %d failed after %d attempts", campaign.ID, campaign.Attempts)
+	}
+	if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+		log.Printf("Error saving campaign %d: %v", campaign.ID, err)
+	}
+}
+
+func (s *NewsletterService) send(campaign *models.Campaign) error {
+	if campaign.Status == models.CampaignScheduled {
+		campaign.Status = models.CampaignSending
+		if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+			return err
+		}
+	}
+
+	list, err := s.newsletterRepo.FindListByID(campaign.ListID)
+	if err != nil || list == nil {
+		// Deleting the list deletes its campaigns too
+		return err
+	}
+
+	// Posts unpublished since the campaign was scheduled are left out
+	var posts []emailPost
+	for _, postID := range campaign.PostIDs {
+		post, err := s.emails.emailPost(postID)
+		if err != nil {
+			return err
+		}
+		if post != nil {
+			posts = append(posts, *post)
+		}
+	}
+	if len(posts) == 0 {
+		campaign.Status = models.CampaignFailed
+		campaign.Error = "none of the campaign's posts is published any more"
+		return s.newsletterRepo.UpdateCampaign(campaign)
+	}
+
+	subs, err := s.newsletterRepo.FindConfirmedSubscribers(list.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		tracked := make([]emailPost, len(posts))
+		for i, post := range posts {
+			tracked[i] = post
+			tracked[i].URL = s.clickURL(campaign.ID, sub.Token, post.URL)
+		}
+
+		data := newsletterData{
+			Site:           s.site,
+			Subject:        campaign.Subject,
+			List:           list,
+			Intro:          campaign.Intro,
+			Posts:          tracked,
+			UnsubscribeURL: s.unsubscribeURL(campaign.ID, sub.Token),
+			OpenURL:        fmt.Sprintf("%s/open/%d/%s", s.config.URL, campaign.ID, sub.Token),
+		}
+		headers := map[string]string{
+			// RFC 8058 one-click unsubscribe
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			// Lets bounce webhooks name the campaign
+			"X-Newsletter-Campaign": strconv.FormatInt(campaign.ID, 10),
+		}
+
+		key := fmt.Sprintf("campaign:%d:%d", campaign.ID, sub.ID)
+		if _, err := s.enqueue(sub, campaign.ID, models.EmailNewsletter, key, data, headers); err != nil {
+			return err
+		}
+		if err := s.newsletterRepo.AddRecipient(campaign.ID, sub.ID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	campaign.Status = models.CampaignSent
+	campaign.SentAt = &now
+	campaign.RecipientCount = len(subs)
+	campaign.Error = ""
+	if err := s.newsletterRepo.UpdateCampaign(campaign); err != nil {
+		return err
+	}
+
+	s.emails.signal()
+	return nil
+}
+
+func (s *NewsletterService) enqueue(
+	sub *models.Subscriber,
+	campaignID int64,
+	kind, dedupeKey string,
+	data newsletterData,
+	headers map[string]string,
+) (bool, error) {
+	text, html, err := renderEmail(kind, data)
+	if err != nil {
+		return false, err
+	}
+
+	return s.emails.emailRepo.Enqueue(&models.QueuedEmail{
+		CampaignID:    campaignID,
+		SubscriberID:  sub.ID,
+		Kind:          kind,
+		DedupeKey:     dedupeKey,
+		Recipient:     sub.Email,
+		Subject:       data.Subject,
+		Text:          text,
+		HTML:          html,
+		Headers:       headers,
+		Status:        models.EmailPending,
+		NextAttemptAt: time.Now().UTC(),
+	})
+}
+
+// TrackOpen counts an open of a campaign's email
+func (s *NewsletterService) TrackOpen(campaignID int64, token string) error {
+	_, err := s.newsletterRepo.RecordOpen(campaignID, token)
+	return err
+}
+
+// TrackClick counts a click on a link in a campaign's email and returns
+// where to send the reader. Only links to the site are followed, so the
+// endpoint cannot be used to redirect anywhere else.
+func (s *NewsletterService) TrackClick(campaignID int64, token, target string) (string, error) {
+	if target != s.site.URL && !strings.HasPrefix(target, s.site.URL+"/") {
+		return "", ErrInvalidTrackingLink
+	}
+
+	if _, err := s.newsletterRepo.RecordClick(campaignID, token, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// VerifyWebhook checks the signature of a bounce or unsubscribe webhook,
+// made the way SignWebhookPayload signs outgoing webhooks
+func (s *NewsletterService) VerifyWebhook(timestamp, signature string, body []byte) error {
+	if s.config.WebhookSecret == "" {
+		return ErrInvalidWebhookSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	expected := SignWebhookPayload(s.config.WebhookSecret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
+// HandleBounce records a bounce for an address on every list. A permanent
+// bounce, or maxSoftBounces temporary ones, takes it off its lists.
+// Unknown addresses are ignored.
+func (s *NewsletterService) HandleBounce(cmd BounceCommand) error {
+	email, err := normalizeEmail(cmd.Email)
+	if err != nil {
+		return err
+	}
+	subs, err := s.newsletterRepo.FindSubscribersByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	var bounced []*models.Subscriber
+	for _, sub := range subs {
+		sub.BounceCount++
+		if cmd.Permanent || sub.BounceCount >= maxSoftBounces {
+			bounced = append(bounced, sub)
+			continue
+		}
+		if err := s.newsletterRepo.UpdateSubscriber(sub); err != nil {
+			return err
+		}
+	}
+	if err := s.leave(bounced, models.SubscriberBounced); err != nil {
+		return err
+	}
+
+	return s.attribute(subs, cmd.CampaignID, s.newsletterRepo.RecordBounce)
+}
+
+// HandleUnsubscribe takes an address off one list, or all of them.
+// Unknown addresses are ignored.
+func (s *NewsletterService) HandleUnsubscribe(cmd WebhookUnsubscribeCommand) error {
+	email, err := normalizeEmail(cmd.Email)
+	if err != nil {
+		return err
+	}
+	subs, err := s.newsletterRepo.FindSubscribersByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	if cmd.List != "" {
+		list, err := s.GetList(cmd.List)
+		if err != nil {
+			return err
+		}
+		var onList []*models.Subscriber
+		for _, sub := range subs {
+			if sub.ListID == list.ID {
+				onList = append(onList, sub)
+			}
+		}
+		subs = onList
+	}
+
+	if err := s.leave(subs, models.SubscriberUnsubscribed); err != nil {
+		return err
+	}
+	return s.attribute(subs, cmd.CampaignID, s.newsletterRepo.RecordUnsubscribe)
+}
+
+// attribute records a webhook event against the campaign it names, or
+// else the last campaign sent to any of the subscribers
+func (s *NewsletterService) attribute(
+	subs []*models.Subscriber,
+	campaignID int64,
+	record func(campaignID, subscriberID int64) error,
+) error {
+	if campaignID != 0 {
+		for _, sub := range subs {
+			if err := record(campaignID, sub.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ids := make([]int64, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	campaignID, subscriberID, err := s.newsletterRepo.FindLatestRecipient(ids)
+	if err != nil || campaignID == 0 {
+		return err
+	}
+	return record(campaignID, subscriberID)
+}
+
+func (s *NewsletterService) clickURL(campaignID int64, token, target string) string {
+	return fmt.Sprintf("%s/click/%d/%s?%s", s.config.URL, campaignID, token, url.Values{"url": {target}}.Encode())
+}
+
+func (s *NewsletterService) unsubscribeURL(campaignID int64, token string) string {
+	values := url.Values{"token": {token}, "campaign": {strconv.FormatInt(campaignID, 10)}}
+	return s.config.URL + "/unsubscribe?" + values.Encode()
+}
+
+// normalizeEmail checks an address and lower-cases it, so that the same
+// address is not subscribed twice in different cases
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
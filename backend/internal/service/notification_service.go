@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+var (
+	ErrAccountRequired      = errors.New("an account is required")
+	ErrInvalidFollowTarget  = errors.New("can only follow an author, tag or series")
+	ErrFollowTargetNotFound = errors.New("the followed tag or series does not exist")
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// FollowCommand follows or unfollows an author, tag or series
+type FollowCommand struct {
+	UserID int64
+	Target models.FollowTarget
+}
+
+type ListNotificationsQuery struct {
+	UserID     int64
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// FollowViewModel is something the user follows, with its name when it
+// has one
+type FollowViewModel struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name,omitempty"` // tag name or series title
+	Slug      string    `json:"slug,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Inbox is a page of the user's notifications and their unread count
+type Inbox struct {
+	UnreadCount   int                    `json:"unread_count"`
+	Notifications []*models.Notification `json:"notifications"`
+}
+
+// NotificationService manages follows and fills users' inboxes when
+// something they follow gets a published post or their comments get
+// replies
+type NotificationService struct {
+	followRepo       models.FollowRepositoryInterface
+	notificationRepo models.NotificationRepositoryInterface
+	postRepo         models.PostRepositoryInterface
+	taxonomyRepo     models.TaxonomyRepositoryInterface
+	seriesRepo       models.SeriesRepositoryInterface
+}
+
+func NewNotificationService(
+	followRepo models.FollowRepositoryInterface,
+	notificationRepo models.NotificationRepositoryInterface,
+	postRepo models.PostRepositoryInterface,
+	taxonomyRepo models.TaxonomyRepositoryInterface,
+	seriesRepo models.SeriesRepositoryInterface,
+) *NotificationService {
+	return &NotificationService{
+		followRepo:       followRepo,
+		notificationRepo: notificationRepo,
+		postRepo:         postRepo,
+		taxonomyRepo:     taxonomyRepo,
+		seriesRepo:       seriesRepo,
+	}
+}
+
+// Follow starts following the target; following it again has no effect
+func (s *NotificationService) Follow(cmd FollowCommand) (*FollowViewModel, error) {
+	if cmd.UserID <= 0 {
+		return nil, ErrAccountRequired
+	}
+	vm, err := s.describe(cmd.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	follow := &models.Follow{UserID: cmd.UserID, Target: cmd.Target}
+	if _, err := s.followRepo.Create(follow); err != nil {
+		return nil, err
+	}
+	vm.CreatedAt = follow.CreatedAt
+	return vm, nil
+}
+
+// Unfollow stops following the target; unfollowing twice has no effect
+func (s *NotificationService) Unfollow(cmd FollowCommand) error {
+	if cmd.UserID <= 0 {
+		return ErrAccountRequired
+	}
+	if !validFollowType(cmd.Target.Type) {
+		return ErrInvalidFollowTarget
+	}
+	_, err := s.followRepo.Delete(cmd.UserID, cmd.Target)
+	return err
+}
+
+// ListFollows lists what the user follows, most recent first. Tags and
+// series deleted since are left out.
+func (s *NotificationService) ListFollows(userID int64) ([]*FollowViewModel, error) {
+	if userID <= 0 {
+		return nil, ErrAccountRequired
+	}
+
+	follows, err := s.followRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]*FollowViewModel, 0, len(follows))
+	for _, follow := range follows {
+		vm, err := s.describe(follow.Target)
+		if errors.Is(err, ErrFollowTargetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vm.CreatedAt = follow.CreatedAt
+		viewModels = append(viewModels, vm)
+	}
+	return viewModels, nil
+}
+
+// ListNotifications returns a page of the user's inbox, newest first
+func (s *NotificationService) ListNotifications(query ListNotificationsQuery) (*Inbox, error) {
+	if query.UserID <= 0 {
+		return nil, ErrAccountRequired
+	}
+
+	notifications, err := s.notificationRepo.FindByUser(query.UserID, query.UnreadOnly, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationRepo.CountUnread(query.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if notifications == nil {
+		notifications = []*models.Notification{}
+	}
+	return &Inbox{UnreadCount: unread, Notifications: notifications}, nil
+}
+
+func (s *NotificationService) UnreadCount(userID int64) (int, error) {
+	if userID <= 0 {
+		return 0, ErrAccountRequired
+	}
+	return s.notificationRepo.CountUnread(userID)
+}
+
+// MarkRead marks one notification read and returns the unread count left
+func (s *NotificationService) MarkRead(userID, id int64) (int, error) {
+	if userID <= 0 {
+		return 0, ErrAccountRequired
+	}
+
+	found, err := s.notificationRepo.MarkRead(userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrNotificationNotFound
+	}
+	return s.notificationRepo.CountUnread(userID)
+}
+
+// MarkAllRead empties the user's unread notifications and returns how
+// many were marked
+func (s *NotificationService) MarkAllRead(userID int64) (int64, error) {
+	if userID <= 0 {
+		return 0, ErrAccountRequired
+	}
+	return s.notificationRepo.MarkAllRead(userID)
+}
+
+// NotifyFollowers notifies the followers of a newly published post's
+// author, series and tags, once per follower. The author is not notified
+// of their own post. Posts no longer published by the time this runs are
+// skipped.
+func (s *NotificationService) NotifyFollowers(postID int64) ([]*models.Notification, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || post.Status != "published" {
+		return nil, nil
+	}
+
+	// In order of precedence when a user follows several of them
+	targets := []models.FollowTarget{{Type: models.FollowAuthor, ID: post.AuthorID}}
+	series, err := s.seriesRepo.FindByPostIDs([]int64{post.ID})
+	if err != nil {
+		return nil, err
+	}
+	if item, ok := series[post.ID]; ok {
+		targets = append(targets, models.FollowTarget{Type: models.FollowSeries, ID: item.ID})
+	}
+	tags, err := s.taxonomyRepo.FindTagsByPostIDs([]int64{post.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags[post.ID] {
+		targets = append(targets, models.FollowTarget{Type: models.FollowTag, ID: tag.ID})
+	}
+
+	follows, err := s.followRepo.FindFollowers(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := make(map[models.FollowTarget]int, len(targets))
+	for i, target := range targets {
+		rank[target] = i
+	}
+	sources := make(map[int64]models.FollowTarget)
+	var userIDs []int64
+	for _, follow := range follows {
+		if follow.UserID == post.AuthorID {
+			continue
+		}
+		source, seen := sources[follow.UserID]
+		if !seen {
+			userIDs = append(userIDs, follow.UserID)
+		}
+		if !seen || rank[follow.Target] < rank[source] {
+			sources[follow.UserID] = follow.Target
+		}
+	}
+
+	notifications := make([]*models.Notification, len(userIDs))
+	for i, userID := range userIDs {
+		source := sources[userID]
+		notifications[i] = &models.Notification{
+			UserID:     userID,
+			Kind:       models.NotificationNewPost,
+			PostID:     post.ID,
+			PostTitle:  post.Title,
+			PostSlug:   post.Slug,
+			SourceType: source.Type,
+			SourceID:   source.ID,
+		}
+	}
+	if err := s.notificationRepo.CreateMany(notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// NotifyReply tells the author of a comment that an approved reply to it
+// arrived. Anonymous authors and people replying to themselves are not
+// notified.
+func (s *NotificationService) NotifyReply(event models.CommentEvent) (*models.Notification, error) {
+	comment := event.Comment
+	if event.ParentAuthorID == 0 || event.ParentAuthorID == comment.AuthorID {
+		return nil, nil
+	}
+
+	post, err := s.postRepo.FindByID(comment.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, nil
+	}
+
+	notification := &models.Notification{
+		UserID:    event.ParentAuthorID,
+		Kind:      models.NotificationCommentReply,
+		PostID:    post.ID,
+		PostTitle: post.Title,
+		PostSlug:  post.Slug,
+		CommentID: comment.ID,
+	}
+	if err := s.notificationRepo.CreateMany([]*models.Notification{notification}); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// NotifyComment tells the author of a post that an approved comment on it
+// arrived. Authors commenting on their own posts are not notified, nor
+// are authors already notified of the comment as a reply to theirs.
+func (s *NotificationService) NotifyComment(event models.CommentEvent) error {
+	comment := event.Comment
+	if event.PostAuthorID == 0 || event.PostAuthorID == comment.AuthorID || event.PostAuthorID == event.ParentAuthorID {
+		return nil
+	}
+
+	post, err := s.postRepo.FindByID(comment.PostID)
+	if err != nil {
+		return err
+	}
+	if post == nil {
+		return nil
+	}
+
+	notification := &models.Notification{
+		UserID:    event.PostAuthorID,
+		Kind:      models.NotificationNewComment,
+		PostID:    post.ID,
+		PostTitle: post.Title,
+		PostSlug:  post.Slug,
+		CommentID: comment.ID,
+	}
+	return s.notificationRepo.CreateMany([]*models.Notification{notification})
+}
+
+// describe checks that the target can be followed and names it
+func (s *NotificationService) describe(target models.FollowTarget) (*FollowViewModel, error) {
+	vm := &FollowViewModel{Type: target.Type, ID: target.ID}
+
+	switch target.Type {
+	case models.FollowAuthor:
+		if target.ID <= 0 {
+			return nil, ErrInvalidFollowTarget
+		}
+	case models.FollowTag:
+		tag, err := s.taxonomyRepo.FindTagByID(target.ID)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			return nil, ErrFollowTargetNotFound
+		}
+		vm.Name, vm.Slug = tag.Name, tag.Slug
+	case models.FollowSeries:
+		series, err := s.seriesRepo.FindByID(target.ID)
+		if err != nil {
+			return nil, err
+		}
+		if series == nil {
+			return nil, ErrFollowTargetNotFound
+		}
+		vm.Name, vm.Slug = series.Title, series.Slug
+	default:
+		return nil, ErrInvalidFollowTarget
+	}
+
+	return vm, nil
+}
+
+func validFollowType(targetType string) bool {
+	switch targetType {
+	case models.FollowAuthor, models.FollowTag, models.FollowSeries:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,61 +1,164 @@
 package service
 
 import (
+	"fmt"
 	"log"
 	"sync"
+	"time"
+
+	"blog-platform/internal/models"
 )
 
+// PostEvent is the envelope delivered to observers and, encoded as JSON, to
+// external consumers. Data holds the models payload type matching
+// EventType (models.PostUpdated for post_updated, etc.). The JSON form is a
+// stable contract: fields are only ever added, and incompatible payload
+// changes bump SchemaVersion.
 type PostEvent struct {
-	EventType string      `json:"event_type"`
-	PostID    int64       `json:"post_id"`
-	Data      interface{} `json:"data"`
+	ID            int64               `json:"id,omitempty"` // outbox event ID, unique and increasing
+	SchemaVersion int                 `json:"schema_version"`
+	EventType     models.EventType    `json:"event_type"`
+	PostID        int64               `json:"post_id"`
+	OccurredAt    time.Time           `json:"occurred_at"`
+	ActorID       int64               `json:"actor_id,omitempty"` // 0 for system actions
+	CorrelationID string              `json:"correlation_id,omitempty"`
+	Data          models.EventPayload `json:"data"`
 }
 
 type Observer interface {
 	Update(event PostEvent) error
 }
 
+// NamedObserver lets an observer choose the stable name under which its
+// deliveries are tracked. Other observers are identified by their type.
+type NamedObserver interface {
+	Observer
+	Name() string
+}
+
+// ObserverName returns the name deliveries to the observer are tracked under
+func ObserverName(observer Observer) string {
+	if named, ok := observer.(NamedObserver); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", observer)
+}
+
+// PostService is the subject observers subscribe to. Every observer gets
+// its own worker pool, so a slow or failing observer never delays the
+// others, and events for the same post reach each observer in order.
 type PostService struct {
-	observers []Observer
-	mu        sync.Mutex
+	subscriptions []*subscription
+	mu            sync.Mutex
 }
 
 func NewPostService() *PostService {
 	return &PostService{
-		observers: make([]Observer, 0),
+		subscriptions: make([]*subscription, 0),
 	}
 }
 
+// Subscribe registers an observer for every event with default options
 func (s *PostService) Subscribe(observer Observer) {
+	s.SubscribeWith(observer, SubscriptionOptions{})
+}
+
+// SubscribeWith registers an observer with an event filter, timeout and
+// worker pool size
+func (s *PostService) SubscribeWith(observer Observer, options SubscriptionOptions) {
+	sub := newSubscription(observer, options)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.observers = append(s.observers, observer)
+	s.subscriptions = append(s.subscriptions, sub)
 }
 
+// Unsubscribe removes an observer after the events already queued for it
+// have been delivered
 func (s *PostService) Unsubscribe(observer Observer) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	for i, obs := range s.observers {
-		if obs == observer {
-			s.observers = append(s.observers[:i], s.observers[i+1:]...)
+	var removed *subscription
+	for i, sub := range s.subscriptions {
+		if sub.observer == observer {
+			removed = sub
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
 			break
 		}
 	}
+	s.mu.Unlock()
+
+	if removed != nil {
+		removed.close()
+	}
 }
 
-func (s *PostService) Notify(event PostEvent) {
+// Close unsubscribes every observer, waiting for queued events
+func (s *PostService) Close() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	subs := s.subscriptions
+	s.subscriptions = nil
+	s.mu.Unlock()
 
-	for _, observer := range s.observers {
-		go func(obs Observer) {
-			if err := obs.Update(event); err != nil {
-				log.Printf("Error notifying observer: %v", err)
-			}
-		}(observer)
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// Notify queues the event for every interested observer and returns
+// without waiting for them. Failures are only logged; use Deliver when the
+// outcome matters.
+func (s *PostService) Notify(event PostEvent) {
+	for _, sub := range s.snapshot() {
+		if sub.accepts(event) {
+			sub.enqueue(event, func(error) {})
+		}
 	}
 }
 
+// Deliver sends the event to every interested observer whose name is not
+// in skip and waits for them. It returns the outcome per observer name.
+func (s *PostService) Deliver(event PostEvent, skip map[string]bool) map[string]error {
+	results := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, sub := range s.snapshot() {
+		if skip[sub.name] || !sub.accepts(event) {
+			continue
+		}
+
+		name := sub.name
+		wg.Add(1)
+		sub.enqueue(event, func(err error) {
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+			wg.Done()
+		})
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Metrics reports delivery statistics for every observer
+func (s *PostService) Metrics() []ObserverMetrics {
+	subs := s.snapshot()
+	metrics := make([]ObserverMetrics, len(subs))
+	for i, sub := range subs {
+		metrics[i] = sub.metrics()
+	}
+	return metrics
+}
+
+// snapshot copies the subscription list so events are queued without
+// holding the lock
+func (s *PostService) snapshot() []*subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*subscription(nil), s.subscriptions...)
+}
+
 // Example Observers
 type SearchIndexObserver struct{}
 
@@ -65,18 +168,71 @@ func (o *SearchIndexObserver) Update(event PostEvent) error {
 	return nil
 }
 
-type NotificationObserver struct{}
+// NotificationObserver fills users' inboxes: followers of a post's author,
+// series and tags when it is published, post authors when a comment on
+// their post is approved, and comment authors when a reply to them is
+// approved. Users who asked for it are emailed of posts and replies.
+type NotificationObserver struct {
+	notifications *NotificationService
+	emails        *EmailService
+}
+
+func NewNotificationObserver(notifications *NotificationService, emails *EmailService) *NotificationObserver {
+	return &NotificationObserver{notifications: notifications, emails: emails}
+}
 
 func (o *NotificationObserver) Update(event PostEvent) error {
-	// In a real implementation, this would send notifications
-	log.Printf("Sending notification for post %d: %v", event.PostID, event.EventType)
+	switch data := event.Data.(type) {
+	case models.PostCreated:
+		if data.Post.Status == "published" {
+			return o.notifyFollowers(event.PostID)
+		}
+	case models.PostUpdated:
+		if _, changed := data.Changes["status"]; changed && data.Post.Status == "published" {
+			return o.notifyFollowers(event.PostID)
+		}
+	case models.CommentModerated:
+		if data.Comment.Status == models.CommentApproved && data.PreviousStatus != models.CommentApproved {
+			if err := o.notifications.NotifyComment(data.CommentEvent); err != nil {
+				return err
+			}
+			notification, err := o.notifications.NotifyReply(data.CommentEvent)
+			if err != nil || notification == nil {
+				return err
+			}
+			return o.emails.QueueReply(notification, data.Comment)
+		}
+	}
 	return nil
 }
 
-type AnalyticsObserver struct{}
+func (o *NotificationObserver) notifyFollowers(postID int64) error {
+	notifications, err := o.notifications.NotifyFollowers(postID)
+	if err != nil {
+		return err
+	}
+	return o.emails.QueueNewPost(postID, notifications)
+}
+
+// AnalyticsObserver feeds engagement events to the view tracker: approved
+// comments count towards the day they were approved, and a deleted post's
+// buffered counts are dropped.
+type AnalyticsObserver struct {
+	tracker *ViewTracker
+}
+
+func NewAnalyticsObserver(tracker *ViewTracker) *AnalyticsObserver {
+	return &AnalyticsObserver{tracker: tracker}
+}
 
 func (o *AnalyticsObserver) Update(event PostEvent) error {
-	// In a real implementation, this would track analytics
-	log.Printf("Tracking analytics for post %d: %v", event.PostID, event.EventType)
+	switch data := event.Data.(type) {
+	case models.CommentModerated:
+		if data.Comment.Status == models.CommentApproved && data.PreviousStatus != models.CommentApproved {
+			o.tracker.TrackComment(event.PostID)
+		}
+	case models.PostDeleted:
+		o.tracker.Forget(event.PostID)
+	}
 	return nil
 }
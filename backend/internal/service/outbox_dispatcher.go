@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// dispatchLease is the lease name shared by every replica running a dispatcher
+const dispatchLease = "outbox_dispatcher"
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// OutboxDispatcher delivers outbox events to the observers subscribed on
+// PostService. Delivery is tracked per observer, so a retry only reaches
+// the observers that failed. Failed events are retried with exponential
+// backoff and moved to the dead-letter table after maxAttempts. Delivered
+// events are kept for retention so every replica's OutboxFollower can read
+// them, then pruned.
+type OutboxDispatcher struct {
+	*leasedWorker
+	outboxRepo  models.OutboxRepositoryInterface
+	postService *PostService
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	retention   time.Duration
+	lastPrune   time.Time
+}
+
+func NewOutboxDispatcher(
+	outboxRepo models.OutboxRepositoryInterface,
+	leaseRepo models.LeaseRepositoryInterface,
+	postService *PostService,
+	interval time.Duration,
+) *OutboxDispatcher {
+	d := &OutboxDispatcher{
+		outboxRepo:  outboxRepo,
+		postService: postService,
+		maxAttempts: 8,
+		baseBackoff: time.Second,
+		maxBackoff:  10 * time.Minute,
+		retention:   time.Hour,
+	}
+	// Polls every interval and wakes early whenever a new event is written
+	d.leasedWorker = newLeasedWorker(dispatchLease, leaseRepo, interval, 30*time.Second, outboxRepo.Signal(), d.dispatch)
+	return d
+}
+
+// dispatch delivers every due event, pruning old delivered events once a
+// minute
+func (d *OutboxDispatcher) dispatch(now time.Time) {
+	if now.Sub(d.lastPrune) >= time.Minute {
+		d.lastPrune = now
+		if _, err := d.outboxRepo.PruneCompleted(now.Add(-d.retention)); err != nil {
+			log.Printf("Error pruning delivered outbox events: %v", err)
+		}
+	}
+
+	events, err := d.outboxRepo.FindDue(now, 100)
+	if err != nil {
+		log.Printf("Error loading outbox events: %v", err)
+		return
+	}
+
+	// FindDue returns at most one event per post, so the batch can be
+	// delivered concurrently without reordering any post's events
+	var wg sync.WaitGroup
+	for _, event := range events {
+		wg.Add(1)
+		go func(event *models.OutboxEvent) {
+			defer wg.Done()
+			if err := d.deliver(event, now); err != nil {
+				log.Printf("Error settling outbox event %d: %v", event.ID, err)
+			}
+		}(event)
+	}
+	wg.Wait()
+}
+
+// deliver hands one event to every observer that has not received it yet
+// and records the outcome
+func (d *OutboxDispatcher) deliver(event *models.OutboxEvent, now time.Time) error {
+	postEvent, err := decodeOutboxEvent(event)
+	if err != nil {
+		// A payload that cannot be decoded will never succeed
+		event.Attempts++
+		return d.outboxRepo.MoveToDeadLetter(event, "", err.Error())
+	}
+
+	delivered, err := d.outboxRepo.FindDelivered(event.ID)
+	if err != nil {
+		return err
+	}
+
+	results := d.postService.Deliver(postEvent, delivered)
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failed []string
+	var lastErr error
+	for _, name := range names {
+		if err := results[name]; err != nil {
+			failed = append(failed, name)
+			lastErr = err
+			continue
+		}
+
+		if err := d.outboxRepo.MarkDelivered(event.ID, name); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) == 0 {
+		return d.outboxRepo.Complete(event.ID)
+	}
+
+	event.Attempts++
+	if event.Attempts >= d.maxAttempts {
+		log.Printf("Outbox event %d exhausted %d attempts, moving to dead letters", event.ID, event.Attempts)
+		return d.outboxRepo.MoveToDeadLetter(event, strings.Join(failed, ","), lastErr.Error())
+	}
+
+	return d.outboxRepo.ScheduleRetry(event.ID, event.Attempts, now.Add(exponentialBackoff(d.baseBackoff, d.maxBackoff, event.Attempts)), lastErr.Error())
+}
+
+// PendingEvents lists undelivered events for inspection
+func (d *OutboxDispatcher) PendingEvents(limit, offset int) ([]*models.OutboxEvent, int, error) {
+	total, err := d.outboxRepo.CountPending()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events, err := d.outboxRepo.FindPending(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if events == nil {
+		events = []*models.OutboxEvent{}
+	}
+
+	return events, total, nil
+}
+
+// DeadLetters lists events that exhausted their attempts, newest first
+func (d *OutboxDispatcher) DeadLetters(limit, offset int) ([]*models.DeadLetter, error) {
+	letters, err := d.outboxRepo.FindDeadLetters(limit, offset)
+	if letters == nil && err == nil {
+		letters = []*models.DeadLetter{}
+	}
+	return letters, err
+}
+
+// Replay returns a dead letter to the outbox for another round of attempts
+func (d *OutboxDispatcher) Replay(deadLetterID int64) error {
+	replayed, err := d.outboxRepo.ReplayDeadLetter(deadLetterID)
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// decodeOutboxEvent restores the typed event observers expect. Payloads
+// recorded at older schema versions are upgraded to the current one.
+func decodeOutboxEvent(event *models.OutboxEvent) (PostEvent, error) {
+	data, err := models.DecodeEventPayload(event.EventType, event.SchemaVersion, event.Payload)
+	if err != nil {
+		return PostEvent{}, err
+	}
+
+	return PostEvent{
+		ID:            event.ID,
+		SchemaVersion: models.EventSchemaVersion,
+		EventType:     event.EventType,
+		PostID:        event.PostID,
+		OccurredAt:    event.CreatedAt,
+		ActorID:       event.ActorID,
+		CorrelationID: event.CorrelationID,
+		Data:          data,
+	}, nil
+}
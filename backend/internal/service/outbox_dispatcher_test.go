@@ -0,0 +1,310 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// newTestDB opens a private in-memory database with the full schema
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := models.OpenDatabase("file:" + name + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("OpenDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// recordingObserver records the events it receives and fails them while
+// fail returns an error
+type recordingObserver struct {
+	name string
+
+	mu     sync.Mutex
+	events []PostEvent
+	fail   func(event PostEvent) error
+}
+
+func (o *recordingObserver) Name() string { return o.name }
+
+func (o *recordingObserver) Update(event PostEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+	if o.fail != nil {
+		return o.fail(event)
+	}
+	return nil
+}
+
+func (o *recordingObserver) setFail(fail func(event PostEvent) error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fail = fail
+}
+
+func (o *recordingObserver) received() []PostEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]PostEvent(nil), o.events...)
+}
+
+var errBroken = errors.New("broken")
+
+func alwaysFail(PostEvent) error { return errBroken }
+
+// testOutbox is a dispatcher over an in-memory database
+type testOutbox struct {
+	db         *sql.DB
+	outboxRepo *models.OutboxRepository
+	postRepo   *models.PostRepository
+	dispatcher *OutboxDispatcher
+}
+
+// newTestOutbox wires a dispatcher to the observers
+func newTestOutbox(t *testing.T, observers ...Observer) *testOutbox {
+	t.Helper()
+	db := newTestDB(t)
+	outboxRepo := models.NewOutboxRepository(db)
+
+	postService := NewPostService()
+	t.Cleanup(postService.Close)
+	for _, observer := range observers {
+		postService.Subscribe(observer)
+	}
+
+	return &testOutbox{
+		db:         db,
+		outboxRepo: outboxRepo,
+		postRepo:   models.NewPostRepository(db, outboxRepo),
+		dispatcher: NewOutboxDispatcher(outboxRepo, fakeLeaseRepo{}, postService, time.Minute),
+	}
+}
+
+func createTestPost(t *testing.T, postRepo *models.PostRepository, post *models.Post) *models.Post {
+	t.Helper()
+	if post.Format == "" {
+		post.Format = models.FormatMarkdown
+	}
+	if post.Status == "" {
+		post.Status = "draft"
+	}
+	if post.Locale == "" {
+		post.Locale = "en"
+	}
+	if err := postRepo.Create(post, models.PostTaxonomy{}, models.EventMeta{}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return post
+}
+
+// eventTitle is the title of the post an event carries
+func eventTitle(event PostEvent) string {
+	switch data := event.Data.(type) {
+	case models.PostCreated:
+		return data.Post.Title
+	case models.PostUpdated:
+		return data.Post.Title
+	}
+	return ""
+}
+
+func pendingEvents(t *testing.T, outboxRepo *models.OutboxRepository) []*models.OutboxEvent {
+	t.Helper()
+	events, err := outboxRepo.FindPending(100, 0)
+	if err != nil {
+		t.Fatalf("FindPending: %v", err)
+	}
+	return events
+}
+
+func TestOutboxDispatcherDeliversAndCompletes(t *testing.T) {
+	observer := &recordingObserver{name: "recorder"}
+	ob := newTestOutbox(t, observer)
+	dispatcher, outboxRepo := ob.dispatcher, ob.outboxRepo
+	post := createTestPost(t, ob.postRepo, &models.Post{Title: "Hello", Content: "World", Slug: "hello"})
+
+	dispatcher.RunOnce(time.Now())
+
+	events := observer.received()
+	if len(events) != 1 || events[0].EventType != models.EventPostCreated || events[0].PostID != post.ID {
+		t.Fatalf("observer got %+v, want one post_created for post %d", events, post.ID)
+	}
+	if created, ok := events[0].Data.(models.PostCreated); !ok || created.Post.Title != "Hello" {
+		t.Errorf("data = %#v, want the created post", events[0].Data)
+	}
+	if pending := pendingEvents(t, outboxRepo); len(pending) != 0 {
+		t.Errorf("%d events still pending, want 0", len(pending))
+	}
+
+	// Completed events stay readable for replicas following the outbox
+	followed, err := outboxRepo.FindAfter(0, 10)
+	if err != nil || len(followed) != 1 {
+		t.Errorf("FindAfter = %d events, %v; want the completed event", len(followed), err)
+	}
+
+	dispatcher.RunOnce(time.Now())
+	if n := len(observer.received()); n != 1 {
+		t.Errorf("observer got %d events after a second run, want 1", n)
+	}
+}
+
+func TestOutboxDispatcherRetriesOnlyFailedObservers(t *testing.T) {
+	healthy := &recordingObserver{name: "healthy"}
+	flaky := &recordingObserver{name: "flaky", fail: alwaysFail}
+	ob := newTestOutbox(t, healthy, flaky)
+	dispatcher, outboxRepo := ob.dispatcher, ob.outboxRepo
+	createTestPost(t, ob.postRepo, &models.Post{Title: "Retry", Content: "Body", Slug: "retry"})
+
+	now := time.Now()
+	dispatcher.RunOnce(now)
+
+	pending := pendingEvents(t, outboxRepo)
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError != errBroken.Error() {
+		t.Fatalf("pending = %+v, want one event after 1 failed attempt", pending)
+	}
+
+	// Not due again until the backoff has passed
+	dispatcher.RunOnce(now)
+	if n := len(flaky.received()); n != 1 {
+		t.Fatalf("flaky observer called %d times before the backoff passed, want 1", n)
+	}
+
+	flaky.setFail(nil)
+	dispatcher.RunOnce(now.Add(2 * time.Second))
+
+	if n := len(flaky.received()); n != 2 {
+		t.Errorf("flaky observer called %d times, want 2", n)
+	}
+	if n := len(healthy.received()); n != 1 {
+		t.Errorf("healthy observer called %d times, want 1: retries must skip observers that succeeded", n)
+	}
+	if pending := pendingEvents(t, outboxRepo); len(pending) != 0 {
+		t.Errorf("%d events still pending, want 0", len(pending))
+	}
+}
+
+func TestOutboxDispatcherKeepsPostEventsInOrder(t *testing.T) {
+	observer := &recordingObserver{name: "recorder"}
+	observer.fail = func(event PostEvent) error {
+		if event.EventType == models.EventPostCreated {
+			return errBroken
+		}
+		return nil
+	}
+	ob := newTestOutbox(t, observer)
+	dispatcher, postRepo := ob.dispatcher, ob.postRepo
+
+	post := createTestPost(t, postRepo, &models.Post{Title: "First", Content: "Body", Slug: "first"})
+	post.Title = "Second"
+	if err := postRepo.Update(post, models.PostTaxonomy{}, models.EventMeta{}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	createTestPost(t, postRepo, &models.Post{Title: "Other", Content: "Body", Slug: "other"})
+
+	now := time.Now()
+	dispatcher.RunOnce(now)
+
+	// The update waits behind the failed create; the other post is not held
+	// up. Different posts are delivered concurrently, so only the set counts.
+	var types []string
+	for _, event := range observer.received() {
+		types = append(types, string(event.EventType)+":"+eventTitle(event))
+	}
+	sort.Strings(types)
+	got := strings.Join(types, ",")
+	want := "post_created:First,post_created:Other"
+	if got != want {
+		t.Fatalf("first run delivered %s, want %s", got, want)
+	}
+
+	observer.setFail(nil)
+	dispatcher.RunOnce(now.Add(2 * time.Second))
+	dispatcher.RunOnce(now.Add(4 * time.Second))
+
+	types = types[:0]
+	for _, event := range observer.received() {
+		if event.PostID == post.ID {
+			types = append(types, string(event.EventType))
+		}
+	}
+	got = strings.Join(types, ",")
+	want = "post_created,post_created,post_updated"
+	if got != want {
+		t.Errorf("events for post %d = %s, want %s", post.ID, got, want)
+	}
+}
+
+func TestOutboxDispatcherDeadLettersAndReplays(t *testing.T) {
+	healthy := &recordingObserver{name: "healthy"}
+	broken := &recordingObserver{name: "broken", fail: alwaysFail}
+	ob := newTestOutbox(t, healthy, broken)
+	dispatcher, outboxRepo := ob.dispatcher, ob.outboxRepo
+	dispatcher.maxAttempts = 2
+	createTestPost(t, ob.postRepo, &models.Post{Title: "Doomed", Content: "Body", Slug: "doomed"})
+
+	now := time.Now()
+	dispatcher.RunOnce(now)
+	dispatcher.RunOnce(now.Add(time.Minute))
+
+	if pending := pendingEvents(t, outboxRepo); len(pending) != 0 {
+		t.Fatalf("%d events still pending, want the event dead-lettered", len(pending))
+	}
+	letters, err := dispatcher.DeadLetters(10, 0)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].FailedObservers != "broken" || letters[0].Attempts != 2 {
+		t.Fatalf("dead letters = %+v, want one failed by broken after 2 attempts", letters)
+	}
+
+	broken.setFail(nil)
+	if err := dispatcher.Replay(letters[0].ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if err := dispatcher.Replay(letters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("second Replay = %v, want ErrDeadLetterNotFound", err)
+	}
+	dispatcher.RunOnce(time.Now().Add(time.Second))
+
+	if n := len(broken.received()); n != 3 {
+		t.Errorf("broken observer called %d times, want 3", n)
+	}
+	if n := len(healthy.received()); n != 1 {
+		t.Errorf("healthy observer called %d times, want 1: a replay only reaches the observers that failed", n)
+	}
+	if letters, _ := dispatcher.DeadLetters(10, 0); len(letters) != 0 {
+		t.Errorf("%d dead letters left after replay, want 0", len(letters))
+	}
+}
+
+func TestOutboxDispatcherDeadLettersUndecodablePayload(t *testing.T) {
+	observer := &recordingObserver{name: "recorder"}
+	ob := newTestOutbox(t, observer)
+	dispatcher := ob.dispatcher
+
+	_, err := ob.db.Exec(`INSERT INTO outbox (event_type, schema_version, post_id, payload, next_attempt_at)
+	                  VALUES ('post_created', ?, 1, 'not json', ?)`, models.EventSchemaVersion, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	dispatcher.RunOnce(time.Now())
+
+	if n := len(observer.received()); n != 0 {
+		t.Errorf("observer got %d events, want none", n)
+	}
+	letters, _ := dispatcher.DeadLetters(10, 0)
+	if len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Errorf("dead letters = %+v, want the event after 1 attempt", letters)
+	}
+}
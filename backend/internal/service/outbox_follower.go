@@ -0,0 +1,109 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// OutboxFollower feeds every committed outbox event to an observer on each
+// replica. The dispatcher delivers an event once, on whichever replica
+// holds its lease, which suits observers with shared side effects; state
+// kept in memory by every replica, such as the clients connected to its
+// EventStream, needs every event on every replica instead. The follower
+// reads events by ID, independently of their delivery, and relies on the
+// dispatcher keeping delivered events for a while.
+type OutboxFollower struct {
+	outboxRepo models.OutboxRepositoryInterface
+	observer   Observer
+	interval   time.Duration
+	backlog    int64
+	lastID     int64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewOutboxFollower creates a follower polling every interval. On start it
+// goes back about backlog events, so the observer begins with the recent
+// history, e.g. for clients resuming on this replica after a reconnect.
+func NewOutboxFollower(
+	outboxRepo models.OutboxRepositoryInterface,
+	observer Observer,
+	interval time.Duration,
+	backlog int,
+) *OutboxFollower {
+	return &OutboxFollower{
+		outboxRepo: outboxRepo,
+		observer:   observer,
+		interval:   interval,
+		backlog:    int64(backlog),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start follows the outbox in the background until Stop
+func (f *OutboxFollower) Start() {
+	go func() {
+		defer close(f.done)
+
+		latest, err := f.outboxRepo.LatestID()
+		if err != nil {
+			log.Printf("Error reading the latest outbox event: %v", err)
+		}
+		if latest > f.backlog {
+			f.lastID = latest - f.backlog
+		}
+
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+
+		for {
+			f.Poll()
+
+			select {
+			case <-ticker.C:
+			case <-f.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background loop and waits for it to finish
+func (f *OutboxFollower) Stop() {
+	f.once.Do(func() { close(f.stop) })
+	<-f.done
+}
+
+// Poll hands the observer every event recorded since the last poll
+func (f *OutboxFollower) Poll() {
+	for {
+		events, err := f.outboxRepo.FindAfter(f.lastID, 100)
+		if err != nil {
+			log.Printf("Error following the outbox: %v", err)
+			return
+		}
+
+		for _, event := range events {
+			f.lastID = event.ID
+
+			postEvent, err := decodeOutboxEvent(event)
+			if err != nil {
+				// The dispatcher moves it to the dead letters
+				continue
+			}
+			if err := f.observer.Update(postEvent); err != nil {
+				log.Printf("Error following outbox event %d with %s: %v", event.ID, ObserverName(f.observer), err)
+			}
+		}
+
+		if len(events) < 100 {
+			return
+		}
+	}
+}
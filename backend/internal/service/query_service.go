@@ -1,39 +1,147 @@
 package service
 
 import (
+	"errors"
 	"time"
 
 	"blog-platform/internal/models"
 )
 
+var ErrInvalidRankingWindow = errors.New("window must be one of 24h, 7d, 30d")
+
+// GetPostQuery fetches a post. When the reader states a language with
+// Lang (?lang=) or AcceptLanguage, the post's translation that best matches
+// it is returned instead.
 type GetPostQuery struct {
-	ID int64
+	ID             int64
+	Lang           string
+	AcceptLanguage string
+}
+
+type GetPostBySlugQuery struct {
+	Slug string
 }
 
+// ListPostsQuery lists posts. A language preference, stated as for
+// GetPostQuery, limits the list to one version of each post in the best
+// matching locale.
 type ListPostsQuery struct {
-	Status string
+	Status         string
+	Type           string
+	Lang           string
+	AcceptLanguage string
+	Limit          int
+	Offset         int
+}
+
+type ListPostsByAuthorQuery struct {
+	AuthorID int64
+	Status   string
+	Limit    int
+	Offset   int
+}
+
+// ListRankedPostsQuery selects a page of the trending or popular posts of
+// a window (24h, 7d or 30d), optionally of one type
+type ListRankedPostsQuery struct {
+	Window string
 	Type   string
 	Limit  int
 	Offset int
 }
 
+type ListPostsByTagQuery struct {
+	TagSlug string
+	Status  string
+	Limit   int
+	Offset  int
+}
+
+// ListPostsInSeriesQuery lists the parts of a series in order, or newest
+// part first with NewestFirst
+type ListPostsInSeriesQuery struct {
+	SeriesSlug  string
+	Status      string
+	NewestFirst bool
+	Limit       int
+	Offset      int
+}
+
+type ListPostsByCategoryQuery struct {
+	CategorySlug       string
+	IncludeDescendants bool
+	Status             string
+	Limit              int
+	Offset             int
+}
+
 type PostViewModel struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Type      string    `json:"type"`
-	AuthorID  int64     `json:"author_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Status    string    `json:"status"`
+	ID           int64                  `json:"id"`
+	Title        string                 `json:"title"`
+	Slug         string                 `json:"slug"`
+	Content      string                 `json:"content,omitempty"`      // omitted in listings
+	ContentHTML  string                 `json:"content_html,omitempty"` // sanitized rendering of Content
+	Format       string                 `json:"format"`
+	Locale       string                 `json:"locale"`
+	Translations []TranslationViewModel `json:"translations"`  // other language versions
+	TOC          []TOCEntry             `json:"toc,omitempty"` // headings of ContentHTML
+	Excerpt      string                 `json:"excerpt"`
+	WordCount    int                    `json:"word_count"`
+	ReadingTime  int                    `json:"reading_time_minutes"`
+	CoverImage   string                 `json:"cover_image,omitempty"`
+	SEO          models.PostSEO         `json:"seo"` // overrides only; see SEOService for the effective metadata
+	Type         string                 `json:"type"`
+	AuthorID     int64                  `json:"author_id"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	Status       string                 `json:"status"`
+	PublishAt    *time.Time             `json:"publish_at,omitempty"`
+	UnpublishAt  *time.Time             `json:"unpublish_at,omitempty"`
+	Tags         []TagViewModel         `json:"tags"`
+	Categories   []CategoryViewModel    `json:"categories"`
+	Series       *SeriesNavigation      `json:"series,omitempty"`
+	CommentCount int                    `json:"comment_count"`  // approved comments
+	Reactions    map[string]int         `json:"reactions"`      // count per reaction
+	Bookmarks    int                    `json:"bookmark_count"` // readers who bookmarked the post
+}
+
+// TranslationViewModel is another language version of a post
+type TranslationViewModel struct {
+	ID     int64  `json:"id"`
+	Locale string `json:"locale"`
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+	Status string `json:"status"`
 }
 
 type QueryService struct {
-	postRepo models.PostRepositoryInterface
+	postRepo     models.PostRepositoryInterface
+	taxonomyRepo models.TaxonomyRepositoryInterface
+	commentRepo  models.CommentRepositoryInterface
+	seriesRepo   models.SeriesRepositoryInterface
+	reactionRepo models.ReactionRepositoryInterface
+	renderer     *ContentRenderer
+	locales      LocaleConfig
 }
 
-func NewQueryService(postRepo models.PostRepositoryInterface) *QueryService {
-	return &QueryService{postRepo: postRepo}
+func NewQueryService(
+	postRepo models.PostRepositoryInterface,
+	taxonomyRepo models.TaxonomyRepositoryInterface,
+	commentRepo models.CommentRepositoryInterface,
+	seriesRepo models.SeriesRepositoryInterface,
+	reactionRepo models.ReactionRepositoryInterface,
+	renderer *ContentRenderer,
+	locales LocaleConfig,
+) *QueryService {
+	return &QueryService{
+		postRepo:     postRepo,
+		taxonomyRepo: taxonomyRepo,
+		commentRepo:  commentRepo,
+		seriesRepo:   seriesRepo,
+		reactionRepo: reactionRepo,
+		renderer:     renderer,
+		locales:      locales,
+	}
 }
 
 func (s *QueryService) GetPost(query GetPostQuery) (*PostViewModel, error) {
@@ -46,37 +154,343 @@ func (s *QueryService) GetPost(query GetPostQuery) (*PostViewModel, error) {
 		return nil, nil
 	}
 
-	return &PostViewModel{
-		ID:        post.ID,
-		Title:     post.Title,
-		Content:   post.Content,
-		Type:      post.Type,
-		AuthorID:  post.AuthorID,
-		CreatedAt: post.CreatedAt,
-		UpdatedAt: post.UpdatedAt,
-		Status:    post.Status,
-	}, nil
+	if locales := s.locales.Negotiate(query.Lang, query.AcceptLanguage); locales != nil {
+		if post, err = s.bestTranslation(post, locales); err != nil {
+			return nil, err
+		}
+	}
+
+	viewModels, err := s.toViewModels([]*models.Post{post})
+	if err != nil {
+		return nil, err
+	}
+
+	return &viewModels[0], nil
+}
+
+// GetPostBySlug resolves a permalink. When the slug belongs to a post's
+// history rather than its current slug, redirected is true and the returned
+// post carries the slug to redirect to.
+func (s *QueryService) GetPostBySlug(query GetPostBySlugQuery) (post *PostViewModel, redirected bool, err error) {
+	found, err := s.postRepo.FindBySlug(query.Slug)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if found == nil {
+		postID, err := s.postRepo.FindSlugRedirect(query.Slug)
+		if err != nil || postID == 0 {
+			return nil, false, err
+		}
+
+		post, err := s.GetPost(GetPostQuery{ID: postID})
+		return post, post != nil, err
+	}
+
+	viewModels, err := s.toViewModels([]*models.Post{found})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &viewModels[0], false, nil
 }
 
 func (s *QueryService) ListPosts(query ListPostsQuery) ([]PostViewModel, error) {
-	posts, err := s.postRepo.FindAll(query.Status, query.Type, query.Limit, query.Offset)
+	var posts []*models.Post
+	var err error
+	if locales := s.locales.Negotiate(query.Lang, query.AcceptLanguage); locales != nil {
+		posts, err = s.postRepo.FindAllInLocales(locales, query.Status, query.Type, query.Limit, query.Offset)
+	} else {
+		posts, err = s.postRepo.FindAll(query.Status, query.Type, query.Limit, query.Offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+// bestTranslation returns the version of a post whose locale comes first
+// in locales. Unpublished translations are only considered when asked for
+// directly; a post with no version in any of the locales is returned as is.
+func (s *QueryService) bestTranslation(post *models.Post, locales []string) (*models.Post, error) {
+	if post.TranslationGroup == nil {
+		return post, nil
+	}
+
+	translations, err := s.postRepo.FindTranslations([]int64{*post.TranslationGroup})
+	if err != nil {
+		return nil, err
+	}
+
+	bestID, bestRank := post.ID, len(locales)
+	for _, translation := range translations[*post.TranslationGroup] {
+		if translation.ID != post.ID && translation.Status != "published" {
+			continue
+		}
+		for rank, locale := range locales {
+			if translation.Locale == locale && rank < bestRank {
+				bestID, bestRank = translation.ID, rank
+			}
+		}
+	}
+	if bestID == post.ID {
+		return post, nil
+	}
+
+	best, err := s.postRepo.FindByID(bestID)
+	if err != nil || best == nil {
+		return post, err
+	}
+	return best, nil
+}
+
+func (s *QueryService) ListPostsByAuthor(query ListPostsByAuthorQuery) ([]PostViewModel, error) {
+	posts, err := s.postRepo.FindByAuthor(query.AuthorID, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+// ListTrendingPosts returns posts by recent views, newer views weighing
+// more. The window defaults to 24h.
+func (s *QueryService) ListTrendingPosts(query ListRankedPostsQuery) ([]PostViewModel, error) {
+	window, err := rankingWindow(query.Window, "24h")
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.postRepo.FindTrending(window, query.Type, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+// ListPopularPosts returns posts by views within the window, which
+// defaults to 7d
+func (s *QueryService) ListPopularPosts(query ListRankedPostsQuery) ([]PostViewModel, error) {
+	window, err := rankingWindow(query.Window, "7d")
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.postRepo.FindPopular(window, query.Type, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+func rankingWindow(window, fallback string) (string, error) {
+	if window == "" {
+		return fallback, nil
+	}
+	if _, ok := models.RankingWindows[window]; !ok {
+		return "", ErrInvalidRankingWindow
+	}
+	return window, nil
+}
+
+func (s *QueryService) ListPostsByTag(query ListPostsByTagQuery) ([]PostViewModel, error) {
+	tag, err := s.taxonomyRepo.FindTagBySlug(query.TagSlug)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	posts, err := s.taxonomyRepo.FindPostsByTag(tag.ID, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+func (s *QueryService) ListPostsInSeries(query ListPostsInSeriesQuery) ([]PostViewModel, error) {
+	series, err := s.seriesRepo.FindBySlug(query.SeriesSlug)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ErrSeriesNotFound
+	}
+
+	posts, err := s.seriesRepo.FindPostsInSeries(series.ID, query.Status, query.NewestFirst, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+func (s *QueryService) ListPostsByCategory(query ListPostsByCategoryQuery) ([]PostViewModel, error) {
+	category, err := s.taxonomyRepo.FindCategoryBySlug(query.CategorySlug)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	posts, err := s.taxonomyRepo.FindPostsByCategory(category.ID, query.IncludeDescendants, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toViewModels(posts)
+}
+
+// toViewModels maps posts to view models, renders their content and
+// attaches their tags, categories and comment counts using one query per
+// association
+func (s *QueryService) toViewModels(posts []*models.Post) ([]PostViewModel, error) {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	tags, err := s.taxonomyRepo.FindTagsByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.taxonomyRepo.FindCategoriesByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	commentCounts, err := s.commentRepo.CountApprovedByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	reactionCounts, err := s.reactionRepo.CountReactionsByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarkCounts, err := s.reactionRepo.CountBookmarksByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []int64
+	for _, post := range posts {
+		if post.TranslationGroup != nil {
+			groups = append(groups, *post.TranslationGroup)
+		}
+	}
+	translations, err := s.postRepo.FindTranslations(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesByPost, err := s.seriesRepo.FindByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	var seriesIDs []int64
+	seenSeries := make(map[int64]bool)
+	for _, series := range seriesByPost {
+		if !seenSeries[series.ID] {
+			seenSeries[series.ID] = true
+			seriesIDs = append(seriesIDs, series.ID)
+		}
+	}
+	seriesParts, err := s.seriesRepo.FindPostsBySeriesIDs(seriesIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	viewModels := make([]PostViewModel, len(posts))
 	for i, post := range posts {
-		viewModels[i] = PostViewModel{
-			ID:        post.ID,
-			Title:     post.Title,
-			Content:   post.Content,
-			Type:      post.Type,
-			AuthorID:  post.AuthorID,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-			Status:    post.Status,
+		vm := toPostViewModel(post)
+		setRendered(&vm, s.renderer.RenderPost(post))
+		vm.CommentCount = commentCounts[post.ID]
+		if counts, ok := reactionCounts[post.ID]; ok {
+			vm.Reactions = counts
 		}
+		vm.Bookmarks = bookmarkCounts[post.ID]
+		for _, tag := range tags[post.ID] {
+			vm.Tags = append(vm.Tags, toTagViewModel(tag))
+		}
+		for _, category := range categories[post.ID] {
+			vm.Categories = append(vm.Categories, toCategoryViewModel(category))
+		}
+		if series, ok := seriesByPost[post.ID]; ok {
+			vm.Series = seriesNavigation(series, seriesParts[series.ID], post.ID)
+		}
+		if post.TranslationGroup != nil {
+			for _, translation := range translations[*post.TranslationGroup] {
+				if translation.ID != post.ID {
+					vm.Translations = append(vm.Translations, toTranslationViewModel(translation))
+				}
+			}
+		}
+		viewModels[i] = vm
 	}
 
 	return viewModels, nil
 }
+
+// toPostViewModel maps the stored post fields; associations start empty
+func toPostViewModel(post *models.Post) PostViewModel {
+	return PostViewModel{
+		ID:           post.ID,
+		Title:        post.Title,
+		Slug:         post.Slug,
+		Content:      post.Content,
+		Format:       post.Format,
+		Locale:       post.Locale,
+		CoverImage:   post.CoverImage,
+		SEO:          post.SEO,
+		Type:         post.Type,
+		AuthorID:     post.AuthorID,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+		Status:       post.Status,
+		PublishAt:    post.PublishAt,
+		UnpublishAt:  post.UnpublishAt,
+		Tags:         []TagViewModel{},
+		Categories:   []CategoryViewModel{},
+		Translations: []TranslationViewModel{},
+		Reactions:    map[string]int{},
+	}
+}
+
+func toTranslationViewModel(translation *models.PostTranslation) TranslationViewModel {
+	return TranslationViewModel{
+		ID:     translation.ID,
+		Locale: translation.Locale,
+		Title:  translation.Title,
+		Slug:   translation.Slug,
+		Status: translation.Status,
+	}
+}
+
+// setRendered fills the fields derived from the rendered content
+func setRendered(vm *PostViewModel, rendered RenderedContent) {
+	vm.ContentHTML = rendered.HTML
+	vm.TOC = rendered.TOC
+	vm.Excerpt = rendered.Excerpt
+	vm.WordCount = rendered.WordCount
+	vm.ReadingTime = rendered.ReadingTime()
+}
+
+// Summaries drops the content of posts, keeping their excerpts, so
+// listings stay small
+func Summaries(posts []PostViewModel) []PostViewModel {
+	for i := range posts {
+		posts[i].Content = ""
+		posts[i].ContentHTML = ""
+		posts[i].TOC = nil
+	}
+	return posts
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// rankingLease is the lease name shared by every replica refreshing rankings
+const rankingLease = "ranking_refresher"
+
+// hourlyViewRetention is how long hourly view buckets are kept; it covers
+// the longest ranking window
+const hourlyViewRetention = 31 * 24 * time.Hour
+
+// RankingRefresher recomputes the trending and popular rankings from the
+// hourly view counts and stores them in the post_rankings table, so list
+// requests read a precomputed order instead of aggregating views.
+//
+// A view's weight in the trending score halves every quarter of the window
+// (6 hours for 24h, 42 hours for 7d, 7.5 days for 30d), so recent interest
+// outranks an old spike. The popular ranking is the plain view count within
+// the window.
+type RankingRefresher struct {
+	*leasedWorker
+	analyticsRepo models.AnalyticsRepositoryInterface
+	onRefresh     func()
+}
+
+// NewRankingRefresher creates a refresher running every interval. onRefresh,
+// if set, is called after each refresh, e.g. to drop cached ranking pages.
+func NewRankingRefresher(
+	analyticsRepo models.AnalyticsRepositoryInterface,
+	leaseRepo models.LeaseRepositoryInterface,
+	interval time.Duration,
+	onRefresh func(),
+) *RankingRefresher {
+	r := &RankingRefresher{analyticsRepo: analyticsRepo, onRefresh: onRefresh}
+	r.leasedWorker = newLeasedWorker(rankingLease, leaseRepo, interval, 2*interval, nil, r.refresh)
+	return r
+}
+
+// refresh recomputes every window as of now
+func (r *RankingRefresher) refresh(now time.Time) {
+	now = now.UTC()
+	if _, err := r.analyticsRepo.PruneHourlyViews(now.Add(-hourlyViewRetention).Format(models.HourLayout)); err != nil {
+		log.Printf("Error pruning hourly views: %v", err)
+	}
+
+	var longest time.Duration
+	for _, window := range models.RankingWindows {
+		if window > longest {
+			longest = window
+		}
+	}
+
+	views, err := r.analyticsRepo.FindHourlyViews(now.Add(-longest).Format(models.HourLayout))
+	if err != nil {
+		log.Printf("Error loading hourly views: %v", err)
+		return
+	}
+
+	for name, window := range models.RankingWindows {
+		if err := r.analyticsRepo.ReplaceRankings(name, rankPosts(views, now, window), now); err != nil {
+			log.Printf("Error refreshing %s rankings: %v", name, err)
+		}
+	}
+
+	if r.onRefresh != nil {
+		r.onRefresh()
+	}
+}
+
+// rankPosts scores every post viewed within the window ending at now
+func rankPosts(views []*models.HourlyPostViews, now time.Time, window time.Duration) []*models.PostRanking {
+	halfLife := (window / 4).Hours()
+	start := now.Add(-window)
+
+	byPost := make(map[int64]*models.PostRanking)
+	for _, v := range views {
+		hour, err := time.Parse(models.HourLayout, v.Hour)
+		if err != nil {
+			continue
+		}
+		// Include the bucket the window starts in, weighted from its midpoint
+		if hour.Add(time.Hour).Before(start) || !hour.Before(now) {
+			continue
+		}
+
+		age := now.Sub(hour.Add(30 * time.Minute)).Hours()
+		if age < 0 {
+			age = 0
+		}
+
+		ranking, ok := byPost[v.PostID]
+		if !ok {
+			ranking = &models.PostRanking{PostID: v.PostID}
+			byPost[v.PostID] = ranking
+		}
+		ranking.Views += v.Views
+		ranking.Score += float64(v.Views) * math.Pow(0.5, age/halfLife)
+	}
+
+	rankings := make([]*models.PostRanking, 0, len(byPost))
+	for _, ranking := range byPost {
+		rankings = append(rankings, ranking)
+	}
+	sort.Slice(rankings, func(i, j int) bool { return rankings[i].Score > rankings[j].Score })
+
+	return rankings
+}
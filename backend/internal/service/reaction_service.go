@@ -0,0 +1,241 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"blog-platform/internal/models"
+)
+
+// ReactionLike is always available, whatever emoji are configured
+const ReactionLike = "like"
+
+var (
+	ErrReaderMissing      = errors.New("a reader account or device token is required")
+	ErrInvalidDeviceToken = errors.New("device token must be 16 to 128 characters")
+	ErrUnknownReaction    = errors.New("reaction is not one of the available reactions")
+)
+
+// Reader identifies who reacts to or bookmarks a post: an account, or an
+// anonymous device token the client generates and keeps
+type Reader struct {
+	UserID      int64
+	DeviceToken string
+}
+
+// key is how the reader is stored. Device tokens are hashed so the stored
+// value cannot be replayed as a token.
+func (r Reader) key() (string, error) {
+	if r.UserID > 0 {
+		return fmt.Sprintf("user:%d", r.UserID), nil
+	}
+	if r.DeviceToken == "" {
+		return "", ErrReaderMissing
+	}
+	if len(r.DeviceToken) < 16 || len(r.DeviceToken) > 128 {
+		return "", ErrInvalidDeviceToken
+	}
+	sum := sha256.Sum256([]byte(r.DeviceToken))
+	return "device:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ParseReactions reads a comma-separated list of emoji into the available
+// reactions, which start with ReactionLike
+func ParseReactions(list string) []string {
+	reactions := []string{ReactionLike}
+	seen := map[string]bool{ReactionLike: true}
+	for _, reaction := range strings.Split(list, ",") {
+		reaction = strings.TrimSpace(reaction)
+		if reaction != "" && !seen[reaction] {
+			seen[reaction] = true
+			reactions = append(reactions, reaction)
+		}
+	}
+	return reactions
+}
+
+// ReactCommand adds or withdraws one reaction of a reader
+type ReactCommand struct {
+	PostID   int64
+	Reader   Reader
+	Reaction string
+}
+
+type BookmarkCommand struct {
+	PostID int64
+	Reader Reader
+}
+
+// ReactionSummary is how readers responded to a post, and how the current
+// reader did
+type ReactionSummary struct {
+	PostID        int64          `json:"post_id"`
+	Counts        map[string]int `json:"counts"`
+	BookmarkCount int            `json:"bookmark_count"`
+	Mine          []string       `json:"mine"`       // the reader's reactions
+	Bookmarked    bool           `json:"bookmarked"` // by the reader
+	Available     []string       `json:"available"`
+}
+
+// ReactionService records reactions and bookmarks. Adding and removing are
+// idempotent: repeating a request leaves the counts as they are.
+type ReactionService struct {
+	reactionRepo models.ReactionRepositoryInterface
+	postRepo     models.PostRepositoryInterface
+	queryService *QueryService
+	reactions    []string
+}
+
+func NewReactionService(
+	reactionRepo models.ReactionRepositoryInterface,
+	postRepo models.PostRepositoryInterface,
+	queryService *QueryService,
+	reactions []string,
+) *ReactionService {
+	return &ReactionService{
+		reactionRepo: reactionRepo,
+		postRepo:     postRepo,
+		queryService: queryService,
+		reactions:    reactions,
+	}
+}
+
+// GetReactions summarizes the reactions to a post. The reader is optional;
+// without one Mine is empty.
+func (s *ReactionService) GetReactions(postID int64, reader Reader) (*ReactionSummary, error) {
+	if err := s.ensurePublished(postID); err != nil {
+		return nil, err
+	}
+
+	readerKey, err := reader.key()
+	if errors.Is(err, ErrReaderMissing) {
+		readerKey = ""
+	} else if err != nil {
+		return nil, err
+	}
+	return s.summary(postID, readerKey)
+}
+
+func (s *ReactionService) AddReaction(cmd ReactCommand) (*ReactionSummary, error) {
+	return s.react(cmd, s.reactionRepo.AddReaction)
+}
+
+func (s *ReactionService) RemoveReaction(cmd ReactCommand) (*ReactionSummary, error) {
+	return s.react(cmd, s.reactionRepo.RemoveReaction)
+}
+
+func (s *ReactionService) react(cmd ReactCommand, apply func(int64, string, string) (bool, error)) (*ReactionSummary, error) {
+	if !s.available(cmd.Reaction) {
+		return nil, ErrUnknownReaction
+	}
+	readerKey, err := cmd.Reader.key()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensurePublished(cmd.PostID); err != nil {
+		return nil, err
+	}
+
+	if _, err := apply(cmd.PostID, readerKey, cmd.Reaction); err != nil {
+		return nil, err
+	}
+	return s.summary(cmd.PostID, readerKey)
+}
+
+func (s *ReactionService) AddBookmark(cmd BookmarkCommand) (*ReactionSummary, error) {
+	return s.bookmark(cmd, s.reactionRepo.AddBookmark)
+}
+
+func (s *ReactionService) RemoveBookmark(cmd BookmarkCommand) (*ReactionSummary, error) {
+	return s.bookmark(cmd, s.reactionRepo.RemoveBookmark)
+}
+
+func (s *ReactionService) bookmark(cmd BookmarkCommand, apply func(int64, string) (bool, error)) (*ReactionSummary, error) {
+	readerKey, err := cmd.Reader.key()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensurePublished(cmd.PostID); err != nil {
+		return nil, err
+	}
+
+	if _, err := apply(cmd.PostID, readerKey); err != nil {
+		return nil, err
+	}
+	return s.summary(cmd.PostID, readerKey)
+}
+
+// ListBookmarks returns the reader's bookmarked posts, most recently
+// bookmarked first. Bookmarks are private: only their owner can list them.
+func (s *ReactionService) ListBookmarks(reader Reader, limit, offset int) ([]PostViewModel, error) {
+	readerKey, err := reader.key()
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.reactionRepo.FindBookmarkedPosts(readerKey, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.queryService.toViewModels(posts)
+}
+
+func (s *ReactionService) summary(postID int64, readerKey string) (*ReactionSummary, error) {
+	counts, err := s.reactionRepo.CountReactionsByPostIDs([]int64{postID})
+	if err != nil {
+		return nil, err
+	}
+	bookmarks, err := s.reactionRepo.CountBookmarksByPostIDs([]int64{postID})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ReactionSummary{
+		PostID:        postID,
+		Counts:        counts[postID],
+		BookmarkCount: bookmarks[postID],
+		Mine:          []string{},
+		Available:     s.reactions,
+	}
+	if summary.Counts == nil {
+		summary.Counts = map[string]int{}
+	}
+
+	if readerKey != "" {
+		mine, err := s.reactionRepo.FindReaderReactions(postID, readerKey)
+		if err != nil {
+			return nil, err
+		}
+		if mine != nil {
+			summary.Mine = mine
+		}
+		if summary.Bookmarked, err = s.reactionRepo.IsBookmarked(postID, readerKey); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// ensurePublished keeps readers from reacting to posts they cannot see
+func (s *ReactionService) ensurePublished(postID int64) error {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return err
+	}
+	if post == nil || post.Status != "published" {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+func (s *ReactionService) available(reaction string) bool {
+	for _, candidate := range s.reactions {
+		if candidate == reaction {
+			return true
+		}
+	}
+	return false
+}
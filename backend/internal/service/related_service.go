@@ -0,0 +1,294 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/tfidf"
+)
+
+// Related post scoring. Text similarity is between 0 and 1; shared tags and
+// a shared type nudge otherwise similar posts ahead.
+const (
+	relatedTagBoost    = 0.1 // per shared tag
+	relatedMaxTagBoost = 3   // shared tags counted at most
+	relatedTypeBoost   = 0.05
+	relatedMinScore    = 0.05 // text similarity below this is noise
+	relatedCacheTTL    = 10 * time.Minute
+	relatedMaxLimit    = 20
+)
+
+// GetRelatedPostsQuery selects the posts to read after a post; Limit
+// defaults to 5
+type GetRelatedPostsQuery struct {
+	ID    int64
+	Limit int
+}
+
+type RelatedPostViewModel struct {
+	PostViewModel
+	Score float64 `json:"score"`
+}
+
+type relatedCacheEntry struct {
+	generation uint64
+	matches    []tfidf.Match
+	expiresAt  time.Time
+}
+
+// RelatedPostsService recommends published posts similar to a given one.
+// It keeps a TF-IDF index of every published post's title and content,
+// built at startup and kept current by following the outbox, so every
+// replica reflects a change as soon as it reads the event. Rankings are
+// cached per post until the index changes.
+type RelatedPostsService struct {
+	postRepo     models.PostRepositoryInterface
+	taxonomyRepo models.TaxonomyRepositoryInterface
+	queryService *QueryService
+
+	// rebuilding serialises rebuilds with event updates, so no update is
+	// applied to an index that is about to be replaced
+	rebuilding sync.Mutex
+
+	mu         sync.Mutex
+	index      *tfidf.Index
+	types      map[int64]string
+	generation uint64
+	cache      map[int64]relatedCacheEntry
+}
+
+func NewRelatedPostsService(
+	postRepo models.PostRepositoryInterface,
+	taxonomyRepo models.TaxonomyRepositoryInterface,
+	queryService *QueryService,
+) *RelatedPostsService {
+	return &RelatedPostsService{
+		postRepo:     postRepo,
+		taxonomyRepo: taxonomyRepo,
+		queryService: queryService,
+		index:        tfidf.New(),
+		types:        make(map[int64]string),
+		cache:        make(map[int64]relatedCacheEntry),
+	}
+}
+
+func (s *RelatedPostsService) Name() string {
+	return "related_posts"
+}
+
+// Rebuild replaces the index with one of every published post, built aside
+// and swapped in
+func (s *RelatedPostsService) Rebuild() error {
+	s.rebuilding.Lock()
+	defer s.rebuilding.Unlock()
+
+	index := tfidf.New()
+	types := make(map[int64]string)
+
+	const pageSize = 500
+	for offset := 0; ; offset += pageSize {
+		posts, err := s.postRepo.FindAll("published", "", pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			index.Add(post.ID, indexText(post))
+			types[post.ID] = post.Type
+		}
+		if len(posts) < pageSize {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.index = index
+	s.types = types
+	s.invalidate()
+	s.mu.Unlock()
+
+	log.Printf("Related posts index built with %d posts", index.Len())
+	return nil
+}
+
+// Update keeps the index in step with post changes. Posts leave the index
+// when they are unpublished or deleted.
+func (s *RelatedPostsService) Update(event PostEvent) error {
+	s.rebuilding.Lock()
+	defer s.rebuilding.Unlock()
+
+	var post *models.Post
+	switch data := event.Data.(type) {
+	case models.PostCreated:
+		post = data.Post
+	case models.PostUpdated:
+		post = data.Post
+	case models.PostDeleted:
+		s.removePost(event.PostID)
+		return nil
+	}
+
+	if post == nil {
+		return nil
+	}
+	if post.Status != "published" {
+		s.removePost(post.ID)
+		return nil
+	}
+	s.indexPost(post)
+	return nil
+}
+
+// GetRelatedPosts returns the published posts most similar to a post
+func (s *RelatedPostsService) GetRelatedPosts(query GetRelatedPostsQuery) ([]RelatedPostViewModel, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > relatedMaxLimit {
+		limit = relatedMaxLimit
+	}
+
+	post, err := s.postRepo.FindByID(query.ID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, ErrPostNotFound
+	}
+
+	matches, err := s.rank(post)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []*models.Post
+	var scores []float64
+	for _, match := range matches {
+		if len(posts) == limit {
+			break
+		}
+		related, err := s.postRepo.FindByID(match.ID)
+		if err != nil {
+			return nil, err
+		}
+		if related == nil || related.Status != "published" {
+			continue
+		}
+		posts = append(posts, related)
+		scores = append(scores, match.Score)
+	}
+
+	viewModels, err := s.queryService.toViewModels(posts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RelatedPostViewModel, len(viewModels))
+	for i, vm := range Summaries(viewModels) {
+		results[i] = RelatedPostViewModel{PostViewModel: vm, Score: scores[i]}
+	}
+	return results, nil
+}
+
+// rank scores every post sharing terms with the given one, using the
+// cached ranking while the index is unchanged
+func (s *RelatedPostsService) rank(post *models.Post) ([]tfidf.Match, error) {
+	s.mu.Lock()
+	generation := s.generation
+	index := s.index
+	entry, ok := s.cache[post.ID]
+	s.mu.Unlock()
+
+	if ok && entry.generation == generation && time.Now().Before(entry.expiresAt) {
+		return entry.matches, nil
+	}
+
+	matches := index.Similar(post.ID, 0)
+	if len(matches) == 0 {
+		return matches, nil
+	}
+
+	ids := make([]int64, 0, len(matches)+1)
+	ids = append(ids, post.ID)
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	tags, err := s.taxonomyRepo.FindTagsByPostIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	postTags := make(map[int64]bool)
+	for _, tag := range tags[post.ID] {
+		postTags[tag.ID] = true
+	}
+
+	s.mu.Lock()
+	kept := matches[:0]
+	for i := range matches {
+		if matches[i].Score < relatedMinScore {
+			continue
+		}
+		shared := 0
+		for _, tag := range tags[matches[i].ID] {
+			if postTags[tag.ID] {
+				shared++
+			}
+		}
+		if shared > relatedMaxTagBoost {
+			shared = relatedMaxTagBoost
+		}
+		matches[i].Score += float64(shared) * relatedTagBoost
+		if s.types[matches[i].ID] == post.Type {
+			matches[i].Score += relatedTypeBoost
+		}
+		kept = append(kept, matches[i])
+	}
+	matches = kept
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+	// Only cache if nothing changed while ranking
+	if s.generation == generation {
+		s.cache[post.ID] = relatedCacheEntry{
+			generation: generation,
+			matches:    matches,
+			expiresAt:  time.Now().Add(relatedCacheTTL),
+		}
+	}
+	s.mu.Unlock()
+
+	return matches, nil
+}
+
+// indexPost adds or replaces a post
+func (s *RelatedPostsService) indexPost(post *models.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index.Add(post.ID, indexText(post))
+	s.types[post.ID] = post.Type
+	s.invalidate()
+}
+
+func (s *RelatedPostsService) removePost(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index.Remove(id)
+	delete(s.types, id)
+	s.invalidate()
+}
+
+// indexText is the text a post is indexed by; the title counts twice
+func indexText(post *models.Post) string {
+	return post.Title + " " + post.Title + " " + post.Content
+}
+
+// invalidate discards every cached ranking, since a change to any post
+// changes term weights everywhere; s.mu must be held
+func (s *RelatedPostsService) invalidate() {
+	s.generation++
+	s.cache = make(map[int64]relatedCacheEntry)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// publishLease is the lease name shared by every replica running the scheduler
+const publishLease = "publish_scheduler"
+
+// PublishScheduler applies scheduled publish and unpublish times. Schedules
+// live in the posts table, so they survive restarts; a database lease keeps
+// replicas from doing the same work, and the conditional updates in the
+// repository make a duplicate run harmless anyway. The repository records
+// the same post_updated event a manual status change does.
+type PublishScheduler struct {
+	*leasedWorker
+	postRepo models.PostRepositoryInterface
+}
+
+func NewPublishScheduler(
+	postRepo models.PostRepositoryInterface,
+	leaseRepo models.LeaseRepositoryInterface,
+	interval time.Duration,
+) *PublishScheduler {
+	s := &PublishScheduler{postRepo: postRepo}
+	s.leasedWorker = newLeasedWorker(publishLease, leaseRepo, interval, 2*interval, nil, s.applyDue)
+	return s
+}
+
+// applyDue applies every transition due at now
+func (s *PublishScheduler) applyDue(now time.Time) {
+	published, err := s.postRepo.PublishDue(now)
+	if err != nil {
+		log.Printf("Error publishing scheduled posts: %v", err)
+	}
+	logTransitions(published)
+
+	unpublished, err := s.postRepo.UnpublishDue(now)
+	if err != nil {
+		log.Printf("Error unpublishing scheduled posts: %v", err)
+	}
+	logTransitions(unpublished)
+}
+
+func logTransitions(posts []*models.Post) {
+	for _, post := range posts {
+		log.Printf("Scheduler moved post %d to %s", post.ID, post.Status)
+	}
+}
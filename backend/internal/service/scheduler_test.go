@@ -0,0 +1,107 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// updatedEvents returns the post_updated events recorded for the post
+func updatedEvents(t *testing.T, outboxRepo *models.OutboxRepository, postID int64) []PostEvent {
+	t.Helper()
+	events, err := outboxRepo.FindAfter(0, 100)
+	if err != nil {
+		t.Fatalf("FindAfter: %v", err)
+	}
+
+	var updates []PostEvent
+	for _, event := range events {
+		postEvent, err := decodeOutboxEvent(event)
+		if err != nil {
+			t.Fatalf("decode event %d: %v", event.ID, err)
+		}
+		if postEvent.PostID == postID && postEvent.EventType == models.EventPostUpdated {
+			updates = append(updates, postEvent)
+		}
+	}
+	return updates
+}
+
+func TestPublishSchedulerAppliesTransitionsOnce(t *testing.T) {
+	db := newTestDB(t)
+	outboxRepo := models.NewOutboxRepository(db)
+	postRepo := models.NewPostRepository(db, outboxRepo)
+
+	// Two replicas that both believe they hold the lease
+	first := NewPublishScheduler(postRepo, fakeLeaseRepo{}, time.Minute)
+	second := NewPublishScheduler(postRepo, fakeLeaseRepo{}, time.Minute)
+
+	now := time.Now()
+	publishAt := now.Add(-time.Minute)
+	unpublishAt := now.Add(time.Hour)
+	post := createTestPost(t, postRepo, &models.Post{
+		Title: "Scheduled", Content: "Body", Slug: "scheduled",
+		PublishAt: &publishAt, UnpublishAt: &unpublishAt,
+	})
+
+	first.applyDue(now)
+	second.applyDue(now)
+	first.applyDue(now)
+
+	published, err := postRepo.FindByID(post.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if published.Status != "published" || published.PublishAt != nil {
+		t.Fatalf("post status %s, publish_at %v; want published with the schedule cleared", published.Status, published.PublishAt)
+	}
+	updates := updatedEvents(t, outboxRepo, post.ID)
+	if len(updates) != 1 {
+		t.Fatalf("%d post_updated events, want 1", len(updates))
+	}
+	if changes := updates[0].Data.(models.PostUpdated).Changes; changes["status"].After != "published" {
+		t.Errorf("changes = %v, want the status change", changes)
+	}
+
+	later := now.Add(2 * time.Hour)
+	second.applyDue(later)
+	first.applyDue(later)
+
+	archived, err := postRepo.FindByID(post.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if archived.Status != "archived" || archived.UnpublishAt != nil {
+		t.Fatalf("post status %s, unpublish_at %v; want archived with the schedule cleared", archived.Status, archived.UnpublishAt)
+	}
+	if n := len(updatedEvents(t, outboxRepo, post.ID)); n != 2 {
+		t.Errorf("%d post_updated events, want 2", n)
+	}
+}
+
+func TestPublishSchedulerLeavesFutureSchedules(t *testing.T) {
+	db := newTestDB(t)
+	outboxRepo := models.NewOutboxRepository(db)
+	postRepo := models.NewPostRepository(db, outboxRepo)
+	scheduler := NewPublishScheduler(postRepo, fakeLeaseRepo{}, time.Minute)
+
+	now := time.Now()
+	publishAt := now.Add(time.Hour)
+	post := createTestPost(t, postRepo, &models.Post{
+		Title: "Later", Content: "Body", Slug: "later", PublishAt: &publishAt,
+	})
+
+	scheduler.applyDue(now)
+
+	found, err := postRepo.FindByID(post.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if found.Status != "draft" || found.PublishAt == nil {
+		t.Errorf("post status %s, publish_at %v; want an untouched draft", found.Status, found.PublishAt)
+	}
+	if n := len(updatedEvents(t, outboxRepo, post.ID)); n != 0 {
+		t.Errorf("%d post_updated events, want 0", n)
+	}
+}
@@ -10,13 +10,15 @@ import (
 // SearchService handles search operations with circuit breaker
 type SearchService struct {
 	postRepo       models.PostRepositoryInterface
+	renderer       *ContentRenderer
 	circuitBreaker *circuitbreaker.CircuitBreaker
 }
 
 // NewSearchService creates a new search service with circuit breaker
-func NewSearchService(postRepo models.PostRepositoryInterface) *SearchService {
+func NewSearchService(postRepo models.PostRepositoryInterface, renderer *ContentRenderer) *SearchService {
 	return &SearchService{
 		postRepo:       postRepo,
+		renderer:       renderer,
 		circuitBreaker: circuitbreaker.NewCircuitBreaker("search", 5, 30),
 	}
 }
@@ -65,16 +67,9 @@ func (s *SearchService) performSearch(query string) ([]PostViewModel, error) {
 		contentMatch := strings.Contains(strings.ToLower(post.Content), searchLower)
 
 		if titleMatch || contentMatch {
-			results = append(results, PostViewModel{
-				ID:        post.ID,
-				Title:     post.Title,
-				Content:   post.Content,
-				Type:      post.Type,
-				AuthorID:  post.AuthorID,
-				CreatedAt: post.CreatedAt,
-				UpdatedAt: post.UpdatedAt,
-				Status:    post.Status,
-			})
+			vm := toPostViewModel(post)
+			setRendered(&vm, s.renderer.RenderPost(post))
+			results = append(results, vm)
 		}
 	}
 
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/seo"
+)
+
+const (
+	// descriptionLength keeps derived descriptions within what search
+	// results show
+	descriptionLength = 160
+	// headlineLength is the longest headline search engines accept in
+	// structured data
+	headlineLength = 110
+)
+
+type GetPostHeadQuery struct {
+	ID int64
+}
+
+// PostHead is the <head> metadata of a post's page, both as data and as
+// ready-to-embed HTML
+type PostHead struct {
+	seo.Head
+	Image     string    `json:"image,omitempty"` // social card image
+	HTML      string    `json:"html"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// SEOService builds the search engine and social card metadata of posts.
+// Each field a post's SEO settings leave empty is derived from the post:
+// the title from its title, the description from its excerpt, the
+// canonical URL from its public page and the image from its cover or,
+// failing that, the first image in its content. Posts that are not
+// published are never indexed.
+type SEOService struct {
+	postRepo     models.PostRepositoryInterface
+	queryService *QueryService
+	renderer     *ContentRenderer
+	site         SiteConfig
+}
+
+func NewSEOService(
+	postRepo models.PostRepositoryInterface,
+	queryService *QueryService,
+	renderer *ContentRenderer,
+	site SiteConfig,
+) *SEOService {
+	return &SEOService{postRepo: postRepo, queryService: queryService, renderer: renderer, site: site}
+}
+
+// GetPostHead returns the metadata of a post's page
+func (s *SEOService) GetPostHead(query GetPostHeadQuery) (*PostHead, error) {
+	post, err := s.postRepo.FindByID(query.ID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, ErrPostNotFound
+	}
+
+	viewModels, err := s.queryService.toViewModels([]*models.Post{post})
+	if err != nil {
+		return nil, err
+	}
+	vm := viewModels[0]
+	rendered := s.renderer.RenderPost(post)
+
+	title := post.Title
+	pageTitle := post.Title + " | " + s.site.Title
+	if post.SEO.MetaTitle != "" {
+		title = post.SEO.MetaTitle
+		pageTitle = post.SEO.MetaTitle
+	}
+
+	description := post.SEO.MetaDescription
+	if description == "" {
+		description = truncateWords(vm.Excerpt, descriptionLength)
+	}
+
+	canonical := s.absoluteURL(post.SEO.CanonicalURL)
+	if canonical == "" {
+		canonical = s.site.PostURL(post.Slug)
+	}
+
+	image := post.SEO.SocialImage
+	if image == "" {
+		image = post.CoverImage
+	}
+	if image == "" {
+		image = rendered.Image
+	}
+	image = s.absoluteURL(image)
+
+	robots := "index, follow"
+	if post.SEO.NoIndex || post.Status != "published" {
+		robots = "noindex, follow"
+	}
+
+	// Published translations link each other
+	var alternates []seo.Alternate
+	var alternateLocales []string
+	for _, translation := range vm.Translations {
+		if translation.Status == "published" {
+			alternates = append(alternates, seo.Alternate{Hreflang: translation.Locale, Href: s.site.PostURL(translation.Slug)})
+			alternateLocales = append(alternateLocales, translation.Locale)
+		}
+	}
+	if len(alternates) > 0 {
+		alternates = append(alternates, seo.Alternate{Hreflang: post.Locale, Href: s.site.PostURL(post.Slug)})
+		for _, alternate := range alternates {
+			if alternate.Hreflang == s.site.Locales.Default {
+				alternates = append(alternates, seo.Alternate{Hreflang: "x-default", Href: alternate.Href})
+				break
+			}
+		}
+	}
+
+	meta := []seo.Meta{
+		{Property: "og:type", Content: "article"},
+		{Property: "og:site_name", Content: s.site.Title},
+		{Property: "og:locale", Content: ogLocale(post.Locale)},
+		{Property: "og:title", Content: title},
+		{Property: "og:description", Content: description},
+		{Property: "og:url", Content: canonical},
+	}
+	for _, locale := range alternateLocales {
+		meta = append(meta, seo.Meta{Property: "og:locale:alternate", Content: ogLocale(locale)})
+	}
+	card := "summary"
+	if image != "" {
+		meta = append(meta, seo.Meta{Property: "og:image", Content: image})
+		card = "summary_large_image"
+	}
+	meta = append(meta,
+		seo.Meta{Property: "article:published_time", Content: vm.CreatedAt.UTC().Format(time.RFC3339)},
+		seo.Meta{Property: "article:modified_time", Content: vm.UpdatedAt.UTC().Format(time.RFC3339)},
+	)
+	if len(vm.Categories) > 0 {
+		meta = append(meta, seo.Meta{Property: "article:section", Content: vm.Categories[0].Name})
+	}
+	var keywords []string
+	for _, tag := range vm.Tags {
+		meta = append(meta, seo.Meta{Property: "article:tag", Content: tag.Name})
+		keywords = append(keywords, tag.Name)
+	}
+	meta = append(meta,
+		seo.Meta{Name: "twitter:card", Content: card},
+		seo.Meta{Name: "twitter:title", Content: title},
+		seo.Meta{Name: "twitter:description", Content: description},
+	)
+	if image != "" {
+		meta = append(meta, seo.Meta{Name: "twitter:image", Content: image})
+	}
+
+	posting := seo.BlogPosting{
+		Headline:         truncateWords(post.Title, headlineLength),
+		Description:      description,
+		DatePublished:    vm.CreatedAt,
+		DateModified:     vm.UpdatedAt,
+		Author:           seo.Thing{Type: "Person", Name: fmt.Sprintf("Author %d", vm.AuthorID)},
+		Publisher:        seo.Thing{Type: "Organization", Name: s.site.Title, URL: s.site.URL},
+		URL:              canonical,
+		InLanguage:       post.Locale,
+		MainEntityOfPage: canonical,
+		Keywords:         strings.Join(keywords, ", "),
+		WordCount:        vm.WordCount,
+	}
+	if image != "" {
+		posting.Image = []string{image}
+	}
+	if len(vm.Categories) > 0 {
+		posting.ArticleSection = vm.Categories[0].Name
+	}
+
+	head := &PostHead{
+		Head: seo.Head{
+			Title:       pageTitle,
+			Description: description,
+			Canonical:   canonical,
+			Robots:      robots,
+			Alternates:  alternates,
+			Meta:        meta,
+			JSONLD:      posting,
+		},
+		Image:     image,
+		UpdatedAt: vm.UpdatedAt,
+	}
+	if head.HTML, err = head.Head.HTML(); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// ogLocale writes a BCP 47 tag the way Open Graph expects, e.g. pt_BR
+func ogLocale(locale string) string {
+	return strings.ReplaceAll(locale, "-", "_")
+}
+
+// absoluteURL resolves paths on the site, such as uploaded media, against
+// the site URL
+func (s *SEOService) absoluteURL(ref string) string {
+	if strings.HasPrefix(ref, "/") && !strings.HasPrefix(ref, "//") {
+		return s.site.URL + ref
+	}
+	return ref
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/slug"
+)
+
+// seriesMaxParts bounds the posts loaded for one series page
+const seriesMaxParts = 500
+
+var (
+	ErrSeriesNotFound     = errors.New("series not found")
+	ErrSeriesExists       = errors.New("a series with this slug already exists")
+	ErrSeriesTitleMissing = errors.New("title is required")
+	ErrSeriesPostNotFound = errors.New("post_ids must be the IDs of existing posts")
+	ErrSeriesPostRepeated = errors.New("a post can appear only once in a series")
+	ErrPostInOtherSeries  = errors.New("the post already belongs to another series")
+	ErrPostNotInSeries    = errors.New("the post is not part of the series")
+)
+
+type CreateSeriesCommand struct {
+	Title       string  `json:"title"`
+	Slug        string  `json:"slug"` // derived from the title when empty
+	Description string  `json:"description"`
+	AuthorID    int64   `json:"author_id"`
+	PostIDs     []int64 `json:"post_ids"` // parts in order
+}
+
+// UpdateSeriesCommand changes the non-empty fields of a series
+type UpdateSeriesCommand struct {
+	ID          int64   `json:"id"`
+	Title       string  `json:"title"`
+	Slug        string  `json:"slug"`
+	Description *string `json:"description"`
+}
+
+// SetSeriesPostsCommand replaces the parts of a series, in order
+type SetSeriesPostsCommand struct {
+	SeriesID int64   `json:"series_id"`
+	PostIDs  []int64 `json:"post_ids"`
+}
+
+// AddSeriesPostCommand inserts a post as part Position (from 1), moving
+// later parts down; 0 appends it
+type AddSeriesPostCommand struct {
+	SeriesID int64 `json:"series_id"`
+	PostID   int64 `json:"post_id"`
+	Position int   `json:"position"`
+}
+
+type SeriesViewModel struct {
+	ID          int64           `json:"id"`
+	Title       string          `json:"title"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	AuthorID    int64           `json:"author_id"`
+	PartCount   int             `json:"part_count"` // published parts
+	Posts       []PostViewModel `json:"posts,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// SeriesNavigation places a post within its series: part N of M, with
+// links to the parts before and after it. Only published parts count, so
+// readers never see gaps; a draft is shown where it will appear.
+type SeriesNavigation struct {
+	ID       int64           `json:"id"`
+	Title    string          `json:"title"`
+	Slug     string          `json:"slug"`
+	Part     int             `json:"part"`
+	Total    int             `json:"total"`
+	Previous *SeriesPartLink `json:"previous"`
+	Next     *SeriesPartLink `json:"next"`
+}
+
+// SeriesPartLink points at another part of a series
+type SeriesPartLink struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+	Part  int    `json:"part"`
+}
+
+// SeriesService manages series and the order of their posts
+type SeriesService struct {
+	seriesRepo   models.SeriesRepositoryInterface
+	postRepo     models.PostRepositoryInterface
+	queryService *QueryService
+}
+
+func NewSeriesService(
+	seriesRepo models.SeriesRepositoryInterface,
+	postRepo models.PostRepositoryInterface,
+	queryService *QueryService,
+) *SeriesService {
+	return &SeriesService{seriesRepo: seriesRepo, postRepo: postRepo, queryService: queryService}
+}
+
+func (s *SeriesService) CreateSeries(cmd CreateSeriesCommand) (*SeriesViewModel, error) {
+	title := strings.TrimSpace(cmd.Title)
+	seriesSlug := slug.Make(cmd.Slug)
+	if seriesSlug == "" {
+		seriesSlug = slug.Make(title)
+	}
+	if title == "" || seriesSlug == "" {
+		return nil, ErrSeriesTitleMissing
+	}
+
+	if err := s.ensureSlugFree(seriesSlug, 0); err != nil {
+		return nil, err
+	}
+	if err := s.validatePosts(0, cmd.PostIDs); err != nil {
+		return nil, err
+	}
+
+	series := &models.Series{
+		Title:       title,
+		Slug:        seriesSlug,
+		Description: cmd.Description,
+		AuthorID:    cmd.AuthorID,
+	}
+	if err := s.seriesRepo.Create(series); err != nil {
+		return nil, err
+	}
+	if len(cmd.PostIDs) > 0 {
+		if err := s.seriesRepo.SetPosts(series.ID, cmd.PostIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetSeries(series.Slug, "")
+}
+
+// GetSeries returns a series with its parts in order, optionally only
+// those with the given status
+func (s *SeriesService) GetSeries(seriesSlug, status string) (*SeriesViewModel, error) {
+	series, err := s.seriesRepo.FindBySlug(seriesSlug)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ErrSeriesNotFound
+	}
+
+	posts, err := s.queryService.ListPostsInSeries(ListPostsInSeriesQuery{
+		SeriesSlug: series.Slug, Status: status, Limit: seriesMaxParts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := s.seriesRepo.FindPosts(series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := toSeriesViewModel(series, parts)
+	vm.Posts = Summaries(posts)
+	return &vm, nil
+}
+
+// ListSeries lists series, most recently changed first
+func (s *SeriesService) ListSeries(limit, offset int) ([]SeriesViewModel, error) {
+	series, err := s.seriesRepo.FindAll(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(series))
+	for i, item := range series {
+		ids[i] = item.ID
+	}
+	parts, err := s.seriesRepo.FindPostsBySeriesIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]SeriesViewModel, len(series))
+	for i, item := range series {
+		viewModels[i] = toSeriesViewModel(item, parts[item.ID])
+	}
+	return viewModels, nil
+}
+
+func (s *SeriesService) UpdateSeries(cmd UpdateSeriesCommand) (*SeriesViewModel, error) {
+	series, err := s.findSeries(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if title := strings.TrimSpace(cmd.Title); title != "" {
+		series.Title = title
+	}
+
+	if cmd.Slug != "" {
+		seriesSlug := slug.Make(cmd.Slug)
+		if err := s.ensureSlugFree(seriesSlug, series.ID); err != nil {
+			return nil, err
+		}
+		series.Slug = seriesSlug
+	}
+
+	if cmd.Description != nil {
+		series.Description = *cmd.Description
+	}
+
+	if err := s.seriesRepo.Update(series); err != nil {
+		return nil, err
+	}
+
+	return s.GetSeries(series.Slug, "")
+}
+
+// DeleteSeries removes a series; its posts remain as standalone posts
+func (s *SeriesService) DeleteSeries(id int64) error {
+	if _, err := s.findSeries(id); err != nil {
+		return err
+	}
+	return s.seriesRepo.Delete(id)
+}
+
+// SetSeriesPosts replaces the parts of a series, e.g. to reorder them
+func (s *SeriesService) SetSeriesPosts(cmd SetSeriesPostsCommand) (*SeriesViewModel, error) {
+	series, err := s.findSeries(cmd.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePosts(series.ID, cmd.PostIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.seriesRepo.SetPosts(series.ID, cmd.PostIDs); err != nil {
+		return nil, err
+	}
+	return s.GetSeries(series.Slug, "")
+}
+
+// AddSeriesPost inserts a post into a series, or moves it when it is
+// already part of it
+func (s *SeriesService) AddSeriesPost(cmd AddSeriesPostCommand) (*SeriesViewModel, error) {
+	series, err := s.findSeries(cmd.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	postIDs, err := s.partIDs(series.ID)
+	if err != nil {
+		return nil, err
+	}
+	postIDs = removeID(postIDs, cmd.PostID)
+
+	position := cmd.Position
+	if position <= 0 || position > len(postIDs)+1 {
+		position = len(postIDs) + 1
+	}
+	postIDs = append(postIDs[:position-1], append([]int64{cmd.PostID}, postIDs[position-1:]...)...)
+
+	return s.SetSeriesPosts(SetSeriesPostsCommand{SeriesID: series.ID, PostIDs: postIDs})
+}
+
+// RemoveSeriesPost takes a post out of a series; later parts move up
+func (s *SeriesService) RemoveSeriesPost(seriesID, postID int64) (*SeriesViewModel, error) {
+	series, err := s.findSeries(seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	postIDs, err := s.partIDs(series.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := removeID(postIDs, postID)
+	if len(remaining) == len(postIDs) {
+		return nil, ErrPostNotInSeries
+	}
+
+	if err := s.seriesRepo.SetPosts(series.ID, remaining); err != nil {
+		return nil, err
+	}
+	return s.GetSeries(series.Slug, "")
+}
+
+func (s *SeriesService) findSeries(id int64) (*models.Series, error) {
+	series, err := s.seriesRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ErrSeriesNotFound
+	}
+	return series, nil
+}
+
+func (s *SeriesService) partIDs(seriesID int64) ([]int64, error) {
+	parts, err := s.seriesRepo.FindPosts(seriesID)
+	if err != nil {
+		return nil, err
+	}
+	postIDs := make([]int64, len(parts))
+	for i, part := range parts {
+		postIDs[i] = part.PostID
+	}
+	return postIDs, nil
+}
+
+// validatePosts checks that the posts exist, are listed once and belong to
+// no series other than seriesID
+func (s *SeriesService) validatePosts(seriesID int64, postIDs []int64) error {
+	seen := make(map[int64]bool, len(postIDs))
+	for _, postID := range postIDs {
+		if seen[postID] {
+			return ErrSeriesPostRepeated
+		}
+		seen[postID] = true
+
+		post, err := s.postRepo.FindByID(postID)
+		if err != nil {
+			return err
+		}
+		if post == nil {
+			return ErrSeriesPostNotFound
+		}
+	}
+
+	memberships, err := s.seriesRepo.FindByPostIDs(postIDs)
+	if err != nil {
+		return err
+	}
+	for _, series := range memberships {
+		if series.ID != seriesID {
+			return ErrPostInOtherSeries
+		}
+	}
+	return nil
+}
+
+func (s *SeriesService) ensureSlugFree(seriesSlug string, selfID int64) error {
+	existing, err := s.seriesRepo.FindBySlug(seriesSlug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != selfID {
+		return ErrSeriesExists
+	}
+	return nil
+}
+
+func removeID(ids []int64, id int64) []int64 {
+	result := make([]int64, 0, len(ids))
+	for _, candidate := range ids {
+		if candidate != id {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+func toSeriesViewModel(series *models.Series, parts []*models.SeriesPost) SeriesViewModel {
+	vm := SeriesViewModel{
+		ID:          series.ID,
+		Title:       series.Title,
+		Slug:        series.Slug,
+		Description: series.Description,
+		AuthorID:    series.AuthorID,
+		CreatedAt:   series.CreatedAt,
+		UpdatedAt:   series.UpdatedAt,
+	}
+	for _, part := range parts {
+		if part.Status == "published" {
+			vm.PartCount++
+		}
+	}
+	return vm
+}
+
+// seriesNavigation places postID among the published parts of a series
+func seriesNavigation(series *models.Series, parts []*models.SeriesPost, postID int64) *SeriesNavigation {
+	var visible []*models.SeriesPost
+	index := -1
+	for _, part := range parts {
+		if part.PostID == postID {
+			index = len(visible)
+		} else if part.Status != "published" {
+			continue
+		}
+		visible = append(visible, part)
+	}
+	if index < 0 {
+		return nil
+	}
+
+	nav := &SeriesNavigation{
+		ID:    series.ID,
+		Title: series.Title,
+		Slug:  series.Slug,
+		Part:  index + 1,
+		Total: len(visible),
+	}
+	if index > 0 {
+		nav.Previous = toSeriesPartLink(visible[index-1], index)
+	}
+	if index < len(visible)-1 {
+		nav.Next = toSeriesPartLink(visible[index+1], index+2)
+	}
+	return nav
+}
+
+func toSeriesPartLink(part *models.SeriesPost, number int) *SeriesPartLink {
+	return &SeriesPartLink{ID: part.PostID, Title: part.Title, Slug: part.Slug, Part: number}
+}
@@ -0,0 +1,32 @@
+package service
+
+import "strings"
+
+// SiteConfig describes the public site that links in feeds and other
+// published documents point to
+type SiteConfig struct {
+	URL         string // e.g. https://blog.example.com, without a trailing slash
+	Title       string
+	Description string
+	Robots      string // robots.txt content; empty serves a default
+	Locales     LocaleConfig
+}
+
+// NewSiteConfig fills in defaults for the local development frontend
+func NewSiteConfig(url, title, description string) SiteConfig {
+	if url == "" {
+		url = "http://localhost:3000"
+	}
+	if title == "" {
+		title = "Blog"
+	}
+	if description == "" {
+		description = "Latest posts from " + title
+	}
+	return SiteConfig{URL: strings.TrimRight(url, "/"), Title: title, Description: description}
+}
+
+// PostURL is the public page of a post, its permalink by slug
+func (c SiteConfig) PostURL(slug string) string {
+	return c.URL + "/posts/" + slug
+}
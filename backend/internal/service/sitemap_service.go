@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/sitemap"
+)
+
+var ErrSitemapPageNotFound = errors.New("sitemap page not found")
+
+// SitemapDocument is a generated sitemap file
+type SitemapDocument struct {
+	Body         []byte
+	LastModified time.Time
+}
+
+// SitemapService generates the sitemap of published posts. It follows the
+// outbox on every replica and regenerates on each post event, keeping the
+// result in memory. Up to sitemap.MaxURLs URLs are served as a single
+// sitemap; beyond that they are split into pages listed by a sitemap index.
+type SitemapService struct {
+	postRepo models.PostRepositoryInterface
+	site     SiteConfig
+
+	// regenerating serialises rebuilds so an older snapshot never replaces a newer one
+	regenerating sync.Mutex
+
+	mu    sync.RWMutex
+	pages []SitemapDocument
+}
+
+func NewSitemapService(postRepo models.PostRepositoryInterface, site SiteConfig) *SitemapService {
+	return &SitemapService{postRepo: postRepo, site: site}
+}
+
+func (s *SitemapService) Name() string {
+	return "sitemap"
+}
+
+// Update regenerates the sitemap after a post changed
+func (s *SitemapService) Update(event PostEvent) error {
+	switch event.Data.(type) {
+	case models.PostCreated, models.PostUpdated, models.PostDeleted:
+		return s.Regenerate()
+	}
+	return nil
+}
+
+// Regenerate rebuilds every sitemap page from the published posts
+func (s *SitemapService) Regenerate() error {
+	s.regenerating.Lock()
+	defer s.regenerating.Unlock()
+
+	modifications, err := s.postRepo.FindPublishedModifications()
+	if err != nil {
+		return err
+	}
+
+	// Translations list each other, and the default locale's version as x-default
+	groups := make(map[int64][]*models.PostModification)
+	for _, m := range modifications {
+		if m.TranslationGroup != 0 {
+			groups[m.TranslationGroup] = append(groups[m.TranslationGroup], m)
+		}
+	}
+
+	urls := make([]sitemap.URL, 0, len(modifications)+1)
+	urls = append(urls, sitemap.URL{Loc: s.site.URL + "/"})
+	for _, m := range modifications {
+		urls = append(urls, sitemap.URL{
+			Loc:        s.site.PostURL(m.Slug),
+			LastMod:    m.UpdatedAt,
+			Alternates: s.alternates(groups[m.TranslationGroup]),
+		})
+		if m.UpdatedAt.After(urls[0].LastMod) {
+			urls[0].LastMod = m.UpdatedAt
+		}
+	}
+
+	var pages []SitemapDocument
+	for _, chunk := range sitemap.Split(urls) {
+		body, err := sitemap.URLSet(chunk)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, SitemapDocument{Body: body, LastModified: sitemap.LastMod(chunk)})
+	}
+
+	s.mu.Lock()
+	s.pages = pages
+	s.mu.Unlock()
+
+	return nil
+}
+
+// alternates lists the language versions of a translation group, nil for
+// a post with no published translation
+func (s *SitemapService) alternates(group []*models.PostModification) []sitemap.Alternate {
+	if len(group) < 2 {
+		return nil
+	}
+
+	alternates := make([]sitemap.Alternate, 0, len(group)+1)
+	for _, m := range group {
+		alternates = append(alternates, sitemap.Alternate{Hreflang: m.Locale, Href: s.site.PostURL(m.Slug)})
+		if m.Locale == s.site.Locales.Default {
+			alternates = append(alternates, sitemap.Alternate{Hreflang: "x-default", Href: s.site.PostURL(m.Slug)})
+		}
+	}
+	return alternates
+}
+
+// GetSitemap returns /sitemap.xml: the sitemap itself, or an index of the
+// pages under baseURL (e.g. https://example.com/sitemaps/) when split
+func (s *SitemapService) GetSitemap(baseURL string) (*SitemapDocument, error) {
+	pages, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pages) == 1 {
+		return &pages[0], nil
+	}
+
+	refs := make([]sitemap.URL, len(pages))
+	for i, page := range pages {
+		refs[i] = sitemap.URL{Loc: fmt.Sprintf("%s%d.xml", baseURL, i+1), LastMod: page.LastModified}
+	}
+	body, err := sitemap.Index(refs)
+	if err != nil {
+		return nil, err
+	}
+	return &SitemapDocument{Body: body, LastModified: sitemap.LastMod(refs)}, nil
+}
+
+// GetSitemapPage returns page n (from 1) of a split sitemap
+func (s *SitemapService) GetSitemapPage(n int) (*SitemapDocument, error) {
+	pages, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 1 || n < 1 || n > len(pages) {
+		return nil, ErrSitemapPageNotFound
+	}
+	return &pages[n-1], nil
+}
+
+// Robots returns robots.txt: the configured content, or a default that
+// keeps crawlers out of the API and points them at the sitemap
+func (s *SitemapService) Robots(sitemapURL string) string {
+	if s.site.Robots != "" {
+		return s.site.Robots
+	}
+
+	lines := []string{
+		"User-agent: *",
+		"Disallow: /api/",
+		"Allow: /",
+		"",
+		"Sitemap: " + sitemapURL,
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// current returns the generated pages, generating them on first use
+func (s *SitemapService) current() ([]SitemapDocument, error) {
+	s.mu.RLock()
+	pages := s.pages
+	s.mu.RUnlock()
+
+	if pages != nil {
+		return pages, nil
+	}
+
+	if err := s.Regenerate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pages, nil
+}
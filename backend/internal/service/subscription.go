@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+var (
+	ErrObserverTimeout = errors.New("observer timed out")
+	ErrObserverPanic   = errors.New("observer panicked")
+)
+
+// Subscription defaults
+const (
+	defaultObserverTimeout = 10 * time.Second
+	defaultObserverWorkers = 4
+	defaultObserverQueue   = 64
+)
+
+// SubscriptionOptions tunes how an observer receives events. Zero values
+// select the defaults.
+type SubscriptionOptions struct {
+	EventTypes []models.EventType // empty receives every event
+	Timeout    time.Duration      // per event, default 10s
+	Workers    int                // concurrent deliveries, default 4
+	QueueSize  int                // events waiting per worker, default 64
+}
+
+// ObserverMetrics reports how deliveries to one observer went
+type ObserverMetrics struct {
+	Name         string             `json:"name"`
+	EventTypes   []models.EventType `json:"event_types"`
+	Delivered    int64              `json:"delivered"`
+	Failed       int64              `json:"failed"` // includes timeouts and panics
+	TimedOut     int64              `json:"timed_out"`
+	Panicked     int64              `json:"panicked"`
+	AvgLatencyMs float64            `json:"avg_latency_ms"`
+	MaxLatencyMs float64            `json:"max_latency_ms"`
+	Queued       int                `json:"queued"`
+}
+
+// delivery is one event waiting for an observer; done receives the outcome
+type delivery struct {
+	event PostEvent
+	done  func(error)
+}
+
+// subscription runs one observer on a fixed pool of workers. Events are
+// routed to a worker by post ID, so events for the same post reach the
+// observer one at a time and in the order they were queued.
+type subscription struct {
+	observer Observer
+	name     string
+	options  SubscriptionOptions
+	queues   []chan delivery
+	workers  sync.WaitGroup
+
+	// closeMu keeps enqueue from racing with close
+	closeMu sync.RWMutex
+	closed  bool
+
+	metricsMu    sync.Mutex
+	delivered    int64
+	failed       int64
+	timedOut     int64
+	panicked     int64
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+func newSubscription(observer Observer, options SubscriptionOptions) *subscription {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultObserverTimeout
+	}
+	if options.Workers <= 0 {
+		options.Workers = defaultObserverWorkers
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = defaultObserverQueue
+	}
+
+	sub := &subscription{
+		observer: observer,
+		name:     ObserverName(observer),
+		options:  options,
+		queues:   make([]chan delivery, options.Workers),
+	}
+
+	for i := range sub.queues {
+		queue := make(chan delivery, options.QueueSize)
+		sub.queues[i] = queue
+		sub.workers.Add(1)
+		go func() {
+			defer sub.workers.Done()
+			for d := range queue {
+				d.done(sub.run(d.event))
+			}
+		}()
+	}
+
+	return sub
+}
+
+// accepts reports whether the observer's filter lets the event through
+func (sub *subscription) accepts(event PostEvent) bool {
+	if len(sub.options.EventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range sub.options.EventTypes {
+		if eventType == event.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue hands the event to the worker for its post, waiting while that
+// worker's queue is full
+func (sub *subscription) enqueue(event PostEvent, done func(error)) {
+	sub.closeMu.RLock()
+	defer sub.closeMu.RUnlock()
+
+	if sub.closed {
+		done(fmt.Errorf("observer %s is unsubscribed", sub.name))
+		return
+	}
+
+	worker := event.PostID % int64(len(sub.queues))
+	if worker < 0 {
+		worker = -worker
+	}
+	sub.queues[worker] <- delivery{event: event, done: done}
+}
+
+// close stops accepting events and waits for queued ones to be delivered
+func (sub *subscription) close() {
+	sub.closeMu.Lock()
+	if !sub.closed {
+		sub.closed = true
+		for _, queue := range sub.queues {
+			close(queue)
+		}
+	}
+	sub.closeMu.Unlock()
+
+	sub.workers.Wait()
+}
+
+// run calls the observer with a timeout, turning a panic into an error. A
+// call that times out keeps running in the background; its worker moves on,
+// so a later event for the same post may then overlap with it.
+func (sub *subscription) run(event PostEvent) error {
+	start := time.Now()
+	result := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("%w: %v", ErrObserverPanic, r)
+			}
+		}()
+		result <- sub.observer.Update(event)
+	}()
+
+	timer := time.NewTimer(sub.options.Timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-timer.C:
+		err = ErrObserverTimeout
+	}
+
+	sub.record(event, time.Since(start), err)
+	return err
+}
+
+func (sub *subscription) record(event PostEvent, latency time.Duration, err error) {
+	sub.metricsMu.Lock()
+	defer sub.metricsMu.Unlock()
+
+	sub.totalLatency += latency
+	if latency > sub.maxLatency {
+		sub.maxLatency = latency
+	}
+
+	switch {
+	case err == nil:
+		sub.delivered++
+	case errors.Is(err, ErrObserverTimeout):
+		sub.failed++
+		sub.timedOut++
+	case errors.Is(err, ErrObserverPanic):
+		sub.failed++
+		sub.panicked++
+	default:
+		sub.failed++
+	}
+
+	if err != nil {
+		log.Printf("Observer %s failed on %s for post %d: %v", sub.name, event.EventType, event.PostID, err)
+	}
+}
+
+func (sub *subscription) metrics() ObserverMetrics {
+	sub.metricsMu.Lock()
+	defer sub.metricsMu.Unlock()
+
+	m := ObserverMetrics{
+		Name:         sub.name,
+		EventTypes:   sub.options.EventTypes,
+		Delivered:    sub.delivered,
+		Failed:       sub.failed,
+		TimedOut:     sub.timedOut,
+		Panicked:     sub.panicked,
+		MaxLatencyMs: float64(sub.maxLatency) / float64(time.Millisecond),
+	}
+	if m.EventTypes == nil {
+		m.EventTypes = []models.EventType{}
+	}
+	if calls := sub.delivered + sub.failed; calls > 0 {
+		m.AvgLatencyMs = float64(sub.totalLatency) / float64(calls) / float64(time.Millisecond)
+	}
+	for _, queue := range sub.queues {
+		m.Queued += len(queue)
+	}
+
+	return m
+}
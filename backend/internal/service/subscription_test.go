@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// funcObserver calls update for every event
+type funcObserver struct {
+	name   string
+	update func(event PostEvent) error
+}
+
+func (o *funcObserver) Name() string { return o.name }
+
+func (o *funcObserver) Update(event PostEvent) error { return o.update(event) }
+
+func TestSubscriptionKeepsPostEventsInOrder(t *testing.T) {
+	const posts, eventsPerPost = 8, 20
+
+	var mu sync.Mutex
+	seen := make(map[int64][]int64)
+	observer := &funcObserver{name: "ordered", update: func(event PostEvent) error {
+		// Uneven delays let workers overtake each other
+		time.Sleep(time.Duration(event.ID%3) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		seen[event.PostID] = append(seen[event.PostID], event.ID)
+		return nil
+	}}
+
+	postService := NewPostService()
+	postService.SubscribeWith(observer, SubscriptionOptions{Workers: 3})
+
+	var id int64
+	for i := 0; i < eventsPerPost; i++ {
+		for postID := int64(1); postID <= posts; postID++ {
+			id++
+			postService.Notify(PostEvent{ID: id, EventType: models.EventPostUpdated, PostID: postID})
+		}
+	}
+	postService.Close()
+
+	for postID := int64(1); postID <= posts; postID++ {
+		ids := seen[postID]
+		if len(ids) != eventsPerPost {
+			t.Fatalf("post %d got %d events, want %d", postID, len(ids), eventsPerPost)
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("post %d got events out of order: %v", postID, ids)
+			}
+		}
+	}
+}
+
+func TestSubscriptionRecoversFromPanic(t *testing.T) {
+	observer := &funcObserver{name: "panicky", update: func(event PostEvent) error {
+		if event.ID == 1 {
+			panic("boom")
+		}
+		return nil
+	}}
+
+	postService := NewPostService()
+	defer postService.Close()
+	postService.SubscribeWith(observer, SubscriptionOptions{Workers: 1})
+
+	results := postService.Deliver(PostEvent{ID: 1, EventType: models.EventPostCreated, PostID: 1}, nil)
+	if err := results["panicky"]; !errors.Is(err, ErrObserverPanic) {
+		t.Fatalf("Deliver = %v, want ErrObserverPanic", err)
+	}
+
+	// The worker survives the panic and keeps delivering
+	results = postService.Deliver(PostEvent{ID: 2, EventType: models.EventPostUpdated, PostID: 1}, nil)
+	if err := results["panicky"]; err != nil {
+		t.Fatalf("Deliver after panic = %v, want nil", err)
+	}
+
+	metrics := postService.Metrics()
+	if len(metrics) != 1 || metrics[0].Panicked != 1 || metrics[0].Failed != 1 || metrics[0].Delivered != 1 {
+		t.Errorf("metrics = %+v, want 1 panicked and 1 delivered", metrics)
+	}
+}
+
+func TestSubscriptionTimesOutSlowObserver(t *testing.T) {
+	release := make(chan struct{})
+	observer := &funcObserver{name: "slow", update: func(PostEvent) error {
+		<-release
+		return nil
+	}}
+
+	postService := NewPostService()
+	defer postService.Close()
+	defer close(release)
+	postService.SubscribeWith(observer, SubscriptionOptions{Timeout: 20 * time.Millisecond})
+
+	results := postService.Deliver(PostEvent{ID: 1, EventType: models.EventPostCreated, PostID: 1}, nil)
+	if err := results["slow"]; !errors.Is(err, ErrObserverTimeout) {
+		t.Fatalf("Deliver = %v, want ErrObserverTimeout", err)
+	}
+	if metrics := postService.Metrics(); metrics[0].TimedOut != 1 {
+		t.Errorf("timed out = %d, want 1", metrics[0].TimedOut)
+	}
+}
+
+func TestSubscriptionFiltersEventTypes(t *testing.T) {
+	var mu sync.Mutex
+	var got []models.EventType
+	observer := &funcObserver{name: "deletions", update: func(event PostEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event.EventType)
+		return nil
+	}}
+
+	postService := NewPostService()
+	postService.SubscribeWith(observer, SubscriptionOptions{EventTypes: []models.EventType{models.EventPostDeleted}})
+
+	results := postService.Deliver(PostEvent{ID: 1, EventType: models.EventPostCreated, PostID: 1}, nil)
+	if _, ok := results["deletions"]; ok {
+		t.Errorf("Deliver reached a filtered observer: %v", results)
+	}
+	postService.Deliver(PostEvent{ID: 2, EventType: models.EventPostDeleted, PostID: 1}, nil)
+
+	// Skipped observers are not called either
+	results = postService.Deliver(PostEvent{ID: 3, EventType: models.EventPostDeleted, PostID: 2}, map[string]bool{"deletions": true})
+	if len(results) != 0 {
+		t.Errorf("Deliver reached a skipped observer: %v", results)
+	}
+	postService.Close()
+
+	if len(got) != 1 || got[0] != models.EventPostDeleted {
+		t.Errorf("observer got %v, want only post_deleted", got)
+	}
+}
@@ -0,0 +1,454 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/slug"
+)
+
+var (
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTagExists           = errors.New("a tag with this slug already exists")
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryExists      = errors.New("a category with this slug already exists")
+	ErrInvalidCategoryTree = errors.New("a category cannot be its own ancestor")
+	ErrTaxonomyNameMissing = errors.New("name is required")
+	ErrSelfMerge           = errors.New("cannot merge a tag into itself")
+)
+
+type CreateTagCommand struct {
+	Name string `json:"name"`
+}
+
+type RenameTagCommand struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type MergeTagsCommand struct {
+	SourceID int64 `json:"source_id"`
+	TargetID int64 `json:"target_id"`
+}
+
+type CreateCategoryCommand struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ParentID    *int64 `json:"parent_id"`
+}
+
+// UpdateCategoryCommand changes the non-empty fields of a category. A
+// ParentID of 0 moves the category to the top level.
+type UpdateCategoryCommand struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Slug        string  `json:"slug"`
+	Description *string `json:"description"`
+	ParentID    *int64  `json:"parent_id"`
+}
+
+type TagViewModel struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	PostCount *int   `json:"post_count,omitempty"`
+}
+
+type CategoryViewModel struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *int64 `json:"parent_id,omitempty"`
+}
+
+// CategoryNode is a category with its subcategories, used to render the tree
+type CategoryNode struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	ParentID    *int64          `json:"parent_id,omitempty"`
+	PostCount   int             `json:"post_count"`
+	Children    []*CategoryNode `json:"children"`
+}
+
+// TaxonomyService manages tags and the category tree
+type TaxonomyService struct {
+	taxonomyRepo models.TaxonomyRepositoryInterface
+}
+
+func NewTaxonomyService(taxonomyRepo models.TaxonomyRepositoryInterface) *TaxonomyService {
+	return &TaxonomyService{taxonomyRepo: taxonomyRepo}
+}
+
+// Tags
+
+func (s *TaxonomyService) CreateTag(cmd CreateTagCommand) (*TagViewModel, error) {
+	name := strings.TrimSpace(cmd.Name)
+	tagSlug := slug.Make(name)
+	if tagSlug == "" {
+		return nil, ErrTaxonomyNameMissing
+	}
+
+	existing, err := s.taxonomyRepo.FindTagBySlug(tagSlug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrTagExists
+	}
+
+	tag := &models.Tag{Name: name, Slug: tagSlug}
+	if err := s.taxonomyRepo.CreateTag(tag); err != nil {
+		return nil, err
+	}
+
+	vm := toTagViewModel(tag)
+	return &vm, nil
+}
+
+// ListTags returns every tag with the number of posts using it
+func (s *TaxonomyService) ListTags() ([]TagViewModel, error) {
+	tags, err := s.taxonomyRepo.FindAllTags()
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]TagViewModel, len(tags))
+	for i, tag := range tags {
+		viewModels[i] = toTagViewModel(tag)
+		count := tag.PostCount
+		viewModels[i].PostCount = &count
+	}
+
+	return viewModels, nil
+}
+
+func (s *TaxonomyService) GetTagBySlug(tagSlug string) (*TagViewModel, error) {
+	tag, err := s.taxonomyRepo.FindTagBySlug(tagSlug)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	vm := toTagViewModel(tag)
+	return &vm, nil
+}
+
+// RenameTag changes a tag's name and slug; posts keep the tag
+func (s *TaxonomyService) RenameTag(cmd RenameTagCommand) (*TagViewModel, error) {
+	tag, err := s.taxonomyRepo.FindTagByID(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	name := strings.TrimSpace(cmd.Name)
+	tagSlug := slug.Make(name)
+	if tagSlug == "" {
+		return nil, ErrTaxonomyNameMissing
+	}
+
+	if tagSlug != tag.Slug {
+		existing, err := s.taxonomyRepo.FindTagBySlug(tagSlug)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, ErrTagExists
+		}
+	}
+
+	tag.Name = name
+	tag.Slug = tagSlug
+	if err := s.taxonomyRepo.UpdateTag(tag); err != nil {
+		return nil, err
+	}
+
+	vm := toTagViewModel(tag)
+	return &vm, nil
+}
+
+// MergeTags folds the source tag into the target tag. Posts that carried
+// the source tag end up carrying the target tag.
+func (s *TaxonomyService) MergeTags(cmd MergeTagsCommand) (*TagViewModel, error) {
+	if cmd.SourceID == cmd.TargetID {
+		return nil, ErrSelfMerge
+	}
+
+	source, err := s.taxonomyRepo.FindTagByID(cmd.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.taxonomyRepo.FindTagByID(cmd.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || target == nil {
+		return nil, ErrTagNotFound
+	}
+
+	if err := s.taxonomyRepo.MergeTags(source.ID, target.ID); err != nil {
+		return nil, err
+	}
+
+	vm := toTagViewModel(target)
+	return &vm, nil
+}
+
+func (s *TaxonomyService) DeleteTag(id int64) error {
+	tag, err := s.taxonomyRepo.FindTagByID(id)
+	if err != nil {
+		return err
+	}
+	if tag == nil {
+		return ErrTagNotFound
+	}
+
+	return s.taxonomyRepo.DeleteTag(id)
+}
+
+// Categories
+
+func (s *TaxonomyService) CreateCategory(cmd CreateCategoryCommand) (*models.Category, error) {
+	name := strings.TrimSpace(cmd.Name)
+	categorySlug := slug.Make(cmd.Slug)
+	if categorySlug == "" {
+		categorySlug = slug.Make(name)
+	}
+	if name == "" || categorySlug == "" {
+		return nil, ErrTaxonomyNameMissing
+	}
+
+	if err := s.ensureCategorySlugFree(categorySlug, 0); err != nil {
+		return nil, err
+	}
+
+	if cmd.ParentID != nil {
+		parent, err := s.taxonomyRepo.FindCategoryByID(*cmd.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, ErrCategoryNotFound
+		}
+	}
+
+	category := &models.Category{
+		Name:        name,
+		Slug:        categorySlug,
+		Description: cmd.Description,
+		ParentID:    cmd.ParentID,
+	}
+	if err := s.taxonomyRepo.CreateCategory(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *TaxonomyService) GetCategoryBySlug(categorySlug string) (*models.Category, error) {
+	category, err := s.taxonomyRepo.FindCategoryBySlug(categorySlug)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// GetCategoryTree returns the top-level categories with their descendants nested
+func (s *TaxonomyService) GetCategoryTree() ([]*CategoryNode, error) {
+	categories, err := s.taxonomyRepo.FindAllCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{
+			ID:          c.ID,
+			Name:        c.Name,
+			Slug:        c.Slug,
+			Description: c.Description,
+			ParentID:    c.ParentID,
+			PostCount:   c.PostCount,
+			Children:    []*CategoryNode{},
+		}
+	}
+
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots, nil
+}
+
+func (s *TaxonomyService) UpdateCategory(cmd UpdateCategoryCommand) (*models.Category, error) {
+	category, err := s.taxonomyRepo.FindCategoryByID(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	if name := strings.TrimSpace(cmd.Name); name != "" {
+		category.Name = name
+	}
+
+	if cmd.Slug != "" {
+		categorySlug := slug.Make(cmd.Slug)
+		if categorySlug == "" {
+			return nil, ErrTaxonomyNameMissing
+		}
+		if err := s.ensureCategorySlugFree(categorySlug, category.ID); err != nil {
+			return nil, err
+		}
+		category.Slug = categorySlug
+	}
+
+	if cmd.Description != nil {
+		category.Description = *cmd.Description
+	}
+
+	if cmd.ParentID != nil {
+		if *cmd.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			if err := s.ensureNotAncestor(category.ID, *cmd.ParentID); err != nil {
+				return nil, err
+			}
+			parentID := *cmd.ParentID
+			category.ParentID = &parentID
+		}
+	}
+
+	if err := s.taxonomyRepo.UpdateCategory(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// DeleteCategory removes a category; its subcategories move up one level
+func (s *TaxonomyService) DeleteCategory(id int64) error {
+	category, err := s.taxonomyRepo.FindCategoryByID(id)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return ErrCategoryNotFound
+	}
+
+	return s.taxonomyRepo.DeleteCategory(id)
+}
+
+func (s *TaxonomyService) ensureCategorySlugFree(categorySlug string, selfID int64) error {
+	existing, err := s.taxonomyRepo.FindCategoryBySlug(categorySlug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != selfID {
+		return ErrCategoryExists
+	}
+	return nil
+}
+
+// ensureNotAncestor walks up from the proposed parent and fails if it
+// reaches the category itself, which would create a cycle
+func (s *TaxonomyService) ensureNotAncestor(categoryID, parentID int64) error {
+	for id := parentID; ; {
+		if id == categoryID {
+			return ErrInvalidCategoryTree
+		}
+
+		parent, err := s.taxonomyRepo.FindCategoryByID(id)
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return ErrCategoryNotFound
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
+
+// Post assignment helpers shared with CommandService
+
+// resolveTags finds the tags with the given names, creating missing ones
+func resolveTags(repo models.TaxonomyRepositoryInterface, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	seen := make(map[int64]bool)
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		tagSlug := slug.Make(name)
+		if tagSlug == "" {
+			continue
+		}
+
+		tag, err := repo.FindTagBySlug(tagSlug)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			tag = &models.Tag{Name: name, Slug: tagSlug}
+			if err := repo.CreateTag(tag); err != nil {
+				return nil, err
+			}
+		}
+
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			ids = append(ids, tag.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// validateCategories makes sure every category ID exists
+func validateCategories(repo models.TaxonomyRepositoryInterface, ids []int64) error {
+	for _, id := range ids {
+		category, err := repo.FindCategoryByID(id)
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return ErrCategoryNotFound
+		}
+	}
+	return nil
+}
+
+func toTagViewModel(tag *models.Tag) TagViewModel {
+	return TagViewModel{ID: tag.ID, Name: tag.Name, Slug: tag.Slug}
+}
+
+func toCategoryViewModel(category *models.Category) CategoryViewModel {
+	return CategoryViewModel{
+		ID:       category.ID,
+		Name:     category.Name,
+		Slug:     category.Slug,
+		ParentID: category.ParentID,
+	}
+}
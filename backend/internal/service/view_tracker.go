@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/hyperloglog"
+)
+
+type statsKey struct {
+	postID int64
+	day    string
+}
+
+type hourKey struct {
+	postID int64
+	hour   string
+}
+
+// ViewTracker counts page views and engagement in memory and flushes them
+// to the daily aggregates in batches, so reading a post never waits on a
+// write. Views are also counted per hour for the trending rankings.
+// Visitors are identified by a salted hash of their IP address and
+// user agent; only HyperLogLog sketches of those hashes are stored.
+type ViewTracker struct {
+	analyticsRepo models.AnalyticsRepositoryInterface
+	salt          []byte
+	interval      time.Duration
+	maxPending    int
+
+	mu      sync.Mutex
+	pending map[statsKey]*models.DailyPostStats
+	hourly  map[hourKey]int64
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewViewTracker creates a tracker that flushes every interval, or sooner
+// once 1000 post/day entries are buffered
+func NewViewTracker(analyticsRepo models.AnalyticsRepositoryInterface, interval time.Duration) (*ViewTracker, error) {
+	salt, err := analyticsRepo.VisitorSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ViewTracker{
+		analyticsRepo: analyticsRepo,
+		salt:          salt,
+		interval:      interval,
+		maxPending:    1000,
+		pending:       make(map[statsKey]*models.DailyPostStats),
+		hourly:        make(map[hourKey]int64),
+		flush:         make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// Start flushes in the background until Stop
+func (t *ViewTracker) Start() {
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-t.flush:
+			case <-t.stop:
+				t.Flush()
+				return
+			}
+			t.Flush()
+		}
+	}()
+}
+
+// Stop flushes what is buffered and ends the background loop
+func (t *ViewTracker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+	<-t.done
+}
+
+// TrackView counts a view of a post by the visitor with the given IP
+// address and user agent
+func (t *ViewTracker) TrackView(postID int64, ip, userAgent string) {
+	hash := t.visitorHash(ip, userAgent)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.entry(postID)
+	stats.Views++
+	stats.Visitors.Add(hash)
+	t.hourly[hourKey{postID: postID, hour: time.Now().UTC().Format(models.HourLayout)}]++
+}
+
+// TrackComment counts an approved comment on a post
+func (t *ViewTracker) TrackComment(postID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entry(postID).Comments++
+}
+
+// Forget drops buffered counts for a deleted post
+func (t *ViewTracker) Forget(postID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.pending {
+		if key.postID == postID {
+			delete(t.pending, key)
+		}
+	}
+	for key := range t.hourly {
+		if key.postID == postID {
+			delete(t.hourly, key)
+		}
+	}
+}
+
+// Flush writes the buffered counts. On failure they are kept for the next flush.
+func (t *ViewTracker) Flush() {
+	t.mu.Lock()
+	batch := t.pending
+	hourly := t.hourly
+	t.pending = make(map[statsKey]*models.DailyPostStats)
+	t.hourly = make(map[hourKey]int64)
+	t.mu.Unlock()
+
+	t.flushHourly(hourly)
+
+	if len(batch) == 0 {
+		return
+	}
+
+	stats := make([]*models.DailyPostStats, 0, len(batch))
+	for _, s := range batch {
+		stats = append(stats, s)
+	}
+
+	if err := t.analyticsRepo.MergeDailyStats(stats); err != nil {
+		log.Printf("Error flushing view stats: %v", err)
+
+		t.mu.Lock()
+		for key, s := range batch {
+			current := t.entryFor(key)
+			current.Views += s.Views
+			current.Comments += s.Comments
+			current.Visitors.Merge(s.Visitors)
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *ViewTracker) flushHourly(hourly map[hourKey]int64) {
+	if len(hourly) == 0 {
+		return
+	}
+
+	views := make([]*models.HourlyPostViews, 0, len(hourly))
+	for key, count := range hourly {
+		views = append(views, &models.HourlyPostViews{PostID: key.postID, Hour: key.hour, Views: count})
+	}
+
+	if err := t.analyticsRepo.AddHourlyViews(views); err != nil {
+		log.Printf("Error flushing hourly views: %v", err)
+
+		t.mu.Lock()
+		for key, count := range hourly {
+			t.hourly[key] += count
+		}
+		t.mu.Unlock()
+	}
+}
+
+// entry returns today's buffered stats for a post; t.mu must be held
+func (t *ViewTracker) entry(postID int64) *models.DailyPostStats {
+	stats := t.entryFor(statsKey{postID: postID, day: time.Now().UTC().Format(dayLayout)})
+
+	if len(t.pending) >= t.maxPending {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+	return stats
+}
+
+func (t *ViewTracker) entryFor(key statsKey) *models.DailyPostStats {
+	stats, ok := t.pending[key]
+	if !ok {
+		stats = &models.DailyPostStats{PostID: key.postID, Day: key.day, Visitors: hyperloglog.New()}
+		t.pending[key] = stats
+	}
+	return stats
+}
+
+func (t *ViewTracker) visitorHash(ip, userAgent string) uint64 {
+	h := sha256.New()
+	h.Write(t.salt)
+	h.Write([]byte(ip))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
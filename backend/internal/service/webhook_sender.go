@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+	"blog-platform/pkg/circuitbreaker"
+)
+
+// webhookLease is the lease name shared by every replica running a sender
+const webhookLease = "webhook_sender"
+
+// Webhook request headers
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// maxResponseBody is how much of a receiver's response is kept in the log
+const maxResponseBody = 1024
+
+// SignWebhookPayload returns the signature header value for a payload:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the webhook secret. Receivers should recompute it and compare with
+// hmac.Equal, and reject stale timestamps.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookSender posts queued deliveries to their endpoints. Failures are
+// retried with exponential backoff; each endpoint has its own circuit
+// breaker so an unreachable receiver is not hammered while it is down.
+type WebhookSender struct {
+	*leasedWorker
+	webhookService *WebhookService
+	client         *http.Client
+	maxAttempts    int
+	baseBackoff    time.Duration
+	maxBackoff     time.Duration
+	breakerReset   time.Duration
+
+	breakers  map[int64]*circuitbreaker.CircuitBreaker
+	breakerMu sync.Mutex
+}
+
+// NewWebhookSender creates a sender using client for requests; a nil client
+// gets one with a 10 second timeout
+func NewWebhookSender(
+	webhookService *WebhookService,
+	leaseRepo models.LeaseRepositoryInterface,
+	client *http.Client,
+	interval time.Duration,
+) *WebhookSender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &WebhookSender{
+		webhookService: webhookService,
+		client:         client,
+		maxAttempts:    6,
+		baseBackoff:    10 * time.Second,
+		maxBackoff:     time.Hour,
+		breakerReset:   time.Minute,
+		breakers:       make(map[int64]*circuitbreaker.CircuitBreaker),
+	}
+	// Polls every interval and wakes early whenever deliveries are queued.
+	// A run stops after half the lease, so with the client timeout well
+	// below that the last request finishes while the lease is still held.
+	s.leasedWorker = newLeasedWorker(webhookLease, leaseRepo, interval, 2*time.Minute, webhookService.Queued(), s.sendDue)
+	return s
+}
+
+// sendDue sends the due deliveries, stopping once the run has used half
+// the lease; the rest stay due for the next run
+func (s *WebhookSender) sendDue(now time.Time) {
+	start := time.Now()
+	repo := s.webhookService.webhookRepo
+	deliveries, err := repo.FindDueDeliveries(now, 20)
+	if err != nil {
+		log.Printf("Error loading webhook deliveries: %v", err)
+		return
+	}
+
+	webhooks := make(map[int64]*models.Webhook)
+	for _, delivery := range deliveries {
+		if s.overrun(start) {
+			return
+		}
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			if webhook, err = repo.FindByID(delivery.WebhookID); err != nil {
+				log.Printf("Error loading webhook %d: %v", delivery.WebhookID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		s.attempt(webhook, delivery)
+		if err := repo.UpdateDelivery(delivery); err != nil {
+			log.Printf("Error saving webhook delivery %s: %v", delivery.DeliveryID, err)
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome on it
+func (s *WebhookSender) attempt(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	if webhook == nil || !webhook.Active {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = "webhook is disabled"
+		return
+	}
+
+	_, err := s.breaker(webhook.ID).Execute(func() (interface{}, error) {
+		return nil, s.post(webhook, delivery)
+	})
+
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		delivery.Attempts++
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		// The endpoint was not contacted, so this does not count as an attempt
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(s.breakerReset)
+	default:
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= s.maxAttempts {
+			delivery.Status = models.DeliveryFailed
+			log.Printf("Webhook delivery %s to %s failed after %d attempts: %v",
+				delivery.DeliveryID, webhook.URL, delivery.Attempts, err)
+		} else {
+			delivery.NextAttemptAt = now.Add(exponentialBackoff(s.baseBackoff, s.maxBackoff, delivery.Attempts))
+		}
+	}
+}
+
+// post sends the signed payload; any non-2xx response is an error
+func (s *WebhookSender) post(webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-platform-webhooks/1.0")
+	req.Header.Set(HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(HeaderWebhookDelivery, delivery.DeliveryID)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		delivery.ResponseStatus = 0
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// breaker returns the circuit breaker of an endpoint, creating it on first use
+func (s *WebhookSender) breaker(webhookID int64) *circuitbreaker.CircuitBreaker {
+	s.breakerMu.Lock()
+	defer s.breakerMu.Unlock()
+
+	cb, ok := s.breakers[webhookID]
+	if !ok {
+		cb = circuitbreaker.NewCircuitBreaker(fmt.Sprintf("webhook-%d", webhookID), 5, s.breakerReset)
+		s.breakers[webhookID] = cb
+	}
+	return cb
+}
+
+// BreakerState reports the circuit state of an endpoint as "closed",
+// "open" or "half-open"
+func (s *WebhookSender) BreakerState(webhookID int64) string {
+	switch s.breaker(webhookID).GetState() {
+	case circuitbreaker.StateOpen:
+		return "open"
+	case circuitbreaker.StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
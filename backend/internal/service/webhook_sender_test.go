@@ -0,0 +1,439 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// fakeLeaseRepo grants every lease
+type fakeLeaseRepo struct{}
+
+func (fakeLeaseRepo) Acquire(name, holder string, ttl time.Duration) (bool, error) { return true, nil }
+func (fakeLeaseRepo) Release(name, holder string) error                            { return nil }
+
+// fakeWebhookRepo keeps webhooks and deliveries in memory
+type fakeWebhookRepo struct {
+	mu         sync.Mutex
+	webhooks   map[int64]*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func newFakeWebhookRepo(webhooks ...*models.Webhook) *fakeWebhookRepo {
+	repo := &fakeWebhookRepo{webhooks: make(map[int64]*models.Webhook)}
+	for _, webhook := range webhooks {
+		repo.webhooks[webhook.ID] = webhook
+	}
+	return repo
+}
+
+func (r *fakeWebhookRepo) Create(webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook.ID = int64(len(r.webhooks) + 1)
+	r.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (r *fakeWebhookRepo) FindByID(id int64) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.webhooks[id], nil
+}
+
+func (r *fakeWebhookRepo) FindAll(activeOnly bool) ([]*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []*models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.Active || !activeOnly {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *fakeWebhookRepo) Update(webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (r *fakeWebhookRepo) Delete(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *fakeWebhookRepo) CreateDelivery(delivery *models.WebhookDelivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if delivery.EventID != 0 && existing.EventID == delivery.EventID && existing.WebhookID == delivery.WebhookID {
+			return false, nil
+		}
+	}
+	delivery.ID = int64(len(r.deliveries) + 1)
+	delivery.CreatedAt = time.Now().UTC()
+	r.deliveries = append(r.deliveries, delivery)
+	return true, nil
+}
+
+func (r *fakeWebhookRepo) FindDelivery(id int64) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			return delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWebhookRepo) FindDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deliveries []*models.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		if r.deliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, r.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *fakeWebhookRepo) FindDueDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) && len(deliveries) < limit {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return nil
+}
+
+// receiver is a webhook endpoint answering with the queued status codes,
+// then 200, after delay, and recording every request
+type receiver struct {
+	delay time.Duration
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	time.Sleep(rc.delay)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+	w.Write([]byte("ok"))
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// newTestSender wires a sender to a webhook pointing at a local receiver
+func newTestSender(t *testing.T, rc *receiver) (*WebhookSender, *WebhookService, *fakeWebhookRepo) {
+	t.Helper()
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	repo := newFakeWebhookRepo(&models.Webhook{ID: 1, URL: server.URL, Secret: "s3cret", Active: true})
+	webhookService := NewWebhookService(repo)
+	sender := NewWebhookSender(webhookService, fakeLeaseRepo{}, server.Client(), time.Minute)
+	sender.breakerReset = time.Hour // keep open circuits open for the test
+	return sender, webhookService, repo
+}
+
+func testEvent(id int64) PostEvent {
+	return PostEvent{
+		ID:            id,
+		SchemaVersion: models.EventSchemaVersion,
+		EventType:     models.EventPostDeleted,
+		PostID:        42,
+		OccurredAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:          models.PostDeleted{Post: &models.Post{ID: 42}},
+	}
+}
+
+func TestWebhookSenderSignsDeliveries(t *testing.T) {
+	rc := &receiver{}
+	sender, webhookService, repo := newTestSender(t, rc)
+
+	if err := webhookService.Enqueue(testEvent(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	sender.RunOnce(time.Now())
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.count())
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	delivery := repo.deliveries[0]
+
+	if got := req.Header.Get(HeaderWebhookEvent); got != string(models.EventPostDeleted) {
+		t.Errorf("%s = %q, want %q", HeaderWebhookEvent, got, models.EventPostDeleted)
+	}
+	if got := req.Header.Get(HeaderWebhookDelivery); got == "" || got != delivery.DeliveryID {
+		t.Errorf("%s = %q, want %q", HeaderWebhookDelivery, got, delivery.DeliveryID)
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderWebhookTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %v", HeaderWebhookTimestamp, err)
+	}
+	if want := SignWebhookPayload("s3cret", timestamp, body); req.Header.Get(HeaderWebhookSignature) != want {
+		t.Errorf("%s = %q, want %q", HeaderWebhookSignature, req.Header.Get(HeaderWebhookSignature), want)
+	}
+	if SignWebhookPayload("other", timestamp, body) == req.Header.Get(HeaderWebhookSignature) {
+		t.Error("signature does not depend on the secret")
+	}
+
+	var event struct {
+		ID     int64 `json:"id"`
+		PostID int64 `json:"post_id"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("body is not a PostEvent: %v", err)
+	}
+	if event.ID != 1 || event.PostID != 42 {
+		t.Errorf("body = %s, want event 1 for post 42", body)
+	}
+
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %s after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+	}
+	if delivery.ResponseStatus != http.StatusOK || delivery.ResponseBody != "ok" {
+		t.Errorf("response = %d %q, want 200 \"ok\"", delivery.ResponseStatus, delivery.ResponseBody)
+	}
+}
+
+func TestWebhookEnqueueSkipsQueuedEvents(t *testing.T) {
+	_, webhookService, repo := newTestSender(t, &receiver{})
+
+	for i := 0; i < 2; i++ {
+		if err := webhookService.Enqueue(testEvent(7)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if len(repo.deliveries) != 1 {
+		t.Errorf("queued %d deliveries for one event, want 1", len(repo.deliveries))
+	}
+}
+
+func TestWebhookSenderRetriesServerErrors(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	sender, webhookService, repo := newTestSender(t, rc)
+
+	if err := webhookService.Enqueue(testEvent(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	delivery := func() *models.WebhookDelivery { return repo.deliveries[0] }
+
+	start := time.Now()
+	sender.RunOnce(start)
+	if d := delivery(); d.Status != models.DeliveryPending || d.Attempts != 1 || d.ResponseStatus != 500 {
+		t.Fatalf("after a 500: %s, %d attempts, response %d; want pending, 1, 500", d.Status, d.Attempts, d.ResponseStatus)
+	}
+	if wait := delivery().NextAttemptAt.Sub(start); wait < 10*time.Second || wait > 11*time.Second {
+		t.Errorf("first retry in %v, want 10s", wait)
+	}
+
+	// Not due yet
+	sender.RunOnce(start.Add(5 * time.Second))
+	if rc.count() != 1 {
+		t.Fatalf("retried before the backoff elapsed: %d requests", rc.count())
+	}
+
+	sender.RunOnce(delivery().NextAttemptAt)
+	if d := delivery(); d.Attempts != 2 || d.ResponseStatus != 502 {
+		t.Fatalf("after a 502: %d attempts, response %d; want 2, 502", d.Attempts, d.ResponseStatus)
+	}
+	if wait := time.Until(delivery().NextAttemptAt); wait < 19*time.Second || wait > 20*time.Second {
+		t.Errorf("second retry in %v, want 20s", wait)
+	}
+
+	sender.RunOnce(delivery().NextAttemptAt)
+	if d := delivery(); d.Status != models.DeliverySucceeded || d.Attempts != 3 || d.LastError != "" {
+		t.Errorf("after a 200: %s, %d attempts, error %q; want succeeded, 3, none", d.Status, d.Attempts, d.LastError)
+	}
+}
+
+func TestWebhookSenderGivesUp(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 500, 500}}
+	sender, webhookService, repo := newTestSender(t, rc)
+	sender.maxAttempts = 3
+
+	if err := webhookService.Enqueue(testEvent(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		sender.RunOnce(time.Now().Add(24 * time.Hour))
+	}
+
+	if d := repo.deliveries[0]; d.Status != models.DeliveryFailed || d.Attempts != 3 {
+		t.Errorf("delivery = %s after %d attempts, want failed after 3", d.Status, d.Attempts)
+	}
+	if rc.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", rc.count())
+	}
+}
+
+func TestWebhookSenderOpensBreaker(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 500, 500, 500, 500}}
+	sender, webhookService, repo := newTestSender(t, rc)
+
+	for id := int64(1); id <= 6; id++ {
+		if err := webhookService.Enqueue(testEvent(id)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	sender.RunOnce(time.Now())
+
+	if rc.count() != 5 {
+		t.Errorf("receiver got %d requests, want 5 before the circuit opened", rc.count())
+	}
+	if state := sender.BreakerState(1); state != "open" {
+		t.Errorf("circuit is %s, want open", state)
+	}
+
+	skipped := repo.deliveries[5]
+	if skipped.Attempts != 0 || skipped.Status != models.DeliveryPending {
+		t.Errorf("delivery behind the open circuit: %s after %d attempts, want pending after 0", skipped.Status, skipped.Attempts)
+	}
+	if !strings.Contains(skipped.LastError, "open") {
+		t.Errorf("last error = %q, want the open circuit", skipped.LastError)
+	}
+	if wait := time.Until(skipped.NextAttemptAt); wait < 59*time.Minute {
+		t.Errorf("delivery behind the open circuit retried in %v, want after the reset", wait)
+	}
+
+	// Still open, so nothing reaches the receiver
+	sender.RunOnce(time.Now().Add(2 * time.Hour))
+	if rc.count() != 5 {
+		t.Errorf("receiver got %d requests while the circuit was open, want 5", rc.count())
+	}
+}
+
+func TestWebhookSenderStopsWithinLease(t *testing.T) {
+	rc := &receiver{delay: 60 * time.Millisecond}
+	sender, webhookService, repo := newTestSender(t, rc)
+	sender.leaseTTL = 100 * time.Millisecond
+
+	for id := int64(1); id <= 3; id++ {
+		if err := webhookService.Enqueue(testEvent(id)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	sender.RunOnce(time.Now())
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1 before half the lease was used", rc.count())
+	}
+	if d := repo.deliveries[2]; d.Status != models.DeliveryPending || d.Attempts != 0 {
+		t.Errorf("delivery left for the next run: %s after %d attempts, want pending after 0", d.Status, d.Attempts)
+	}
+
+	sender.RunOnce(time.Now())
+	if rc.count() != 2 {
+		t.Errorf("receiver got %d requests after a second run, want 2", rc.count())
+	}
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	sender, webhookService, repo := newTestSender(t, rc)
+	sender.maxAttempts = 1
+
+	if err := webhookService.Enqueue(testEvent(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	sender.RunOnce(time.Now())
+	original := repo.deliveries[0]
+	if original.Status != models.DeliveryFailed {
+		t.Fatalf("original delivery = %s, want failed", original.Status)
+	}
+
+	if _, err := webhookService.Redeliver(2, original.ID); err != ErrDeliveryNotFound {
+		t.Errorf("redelivering through another webhook: err = %v, want %v", err, ErrDeliveryNotFound)
+	}
+
+	redelivery, err := webhookService.Redeliver(1, original.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivery.DeliveryID == original.DeliveryID {
+		t.Error("redelivery reused the original delivery ID")
+	}
+	select {
+	case <-webhookService.Queued():
+	default:
+		t.Error("redelivery did not wake the sender")
+	}
+
+	sender.RunOnce(time.Now())
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", rc.count())
+	}
+	if got := rc.requests[1].Header.Get(HeaderWebhookDelivery); got != redelivery.DeliveryID {
+		t.Errorf("%s = %q, want the redelivery's %q", HeaderWebhookDelivery, got, redelivery.DeliveryID)
+	}
+	if string(rc.bodies[1]) != string(rc.bodies[0]) {
+		t.Errorf("redelivered body = %s, want the original %s", rc.bodies[1], rc.bodies[0])
+	}
+	if redelivery.Status != models.DeliverySucceeded {
+		t.Errorf("redelivery = %s, want succeeded", redelivery.Status)
+	}
+	if original.Status != models.DeliveryFailed || original.Attempts != 1 {
+		t.Errorf("original delivery changed to %s after %d attempts", original.Status, original.Attempts)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{6, 320 * time.Second},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := exponentialBackoff(10*time.Second, time.Hour, tt.attempts); got != tt.want {
+			t.Errorf("exponentialBackoff(10s, 1h, %d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+var (
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https URL")
+	ErrInvalidWebhookEvent = fmt.Errorf("unknown webhook event; expected one of %v", models.EventTypes)
+)
+
+type CreateWebhookCommand struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // empty subscribes to every event
+	Secret string   `json:"secret"` // generated when empty
+	Active *bool    `json:"active"` // defaults to true
+}
+
+// UpdateWebhookCommand changes the non-nil fields of a webhook
+type UpdateWebhookCommand struct {
+	ID     int64     `json:"id"`
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Secret *string   `json:"secret"`
+	Active *bool     `json:"active"`
+}
+
+// WebhookViewModel describes a webhook. The secret is only included in the
+// response that creates or changes it.
+type WebhookViewModel struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	Circuit   string    `json:"circuit,omitempty"` // closed, open or half-open
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookService manages webhook subscriptions and queues deliveries for
+// the WebhookSender
+type WebhookService struct {
+	webhookRepo models.WebhookRepositoryInterface
+	queued      chan struct{}
+}
+
+func NewWebhookService(webhookRepo models.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		queued:      make(chan struct{}, 1),
+	}
+}
+
+// Queued fires after deliveries are queued; it may also fire spuriously
+func (s *WebhookService) Queued() <-chan struct{} {
+	return s.queued
+}
+
+func (s *WebhookService) CreateWebhook(cmd CreateWebhookCommand) (*WebhookViewModel, error) {
+	if err := validateWebhookURL(cmd.URL); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeWebhookEvents(cmd.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := cmd.Secret
+	if secret == "" {
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &models.Webhook{
+		URL:    cmd.URL,
+		Events: events,
+		Secret: secret,
+		Active: cmd.Active == nil || *cmd.Active,
+	}
+	if err := s.webhookRepo.Create(webhook); err != nil {
+		return nil, err
+	}
+
+	vm := toWebhookViewModel(webhook)
+	vm.Secret = webhook.Secret
+	return vm, nil
+}
+
+func (s *WebhookService) UpdateWebhook(cmd UpdateWebhookCommand) (*WebhookViewModel, error) {
+	webhook, err := s.webhookRepo.FindByID(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if cmd.URL != nil {
+		if err := validateWebhookURL(*cmd.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *cmd.URL
+	}
+	if cmd.Events != nil {
+		if webhook.Events, err = normalizeWebhookEvents(*cmd.Events); err != nil {
+			return nil, err
+		}
+	}
+	if cmd.Secret != nil {
+		webhook.Secret = *cmd.Secret
+		if webhook.Secret == "" {
+			if webhook.Secret, err = randomHex(32); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cmd.Active != nil {
+		webhook.Active = *cmd.Active
+	}
+
+	if err := s.webhookRepo.Update(webhook); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.webhookRepo.FindByID(webhook.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := toWebhookViewModel(updated)
+	if cmd.Secret != nil {
+		vm.Secret = updated.Secret
+	}
+	return vm, nil
+}
+
+func (s *WebhookService) DeleteWebhook(id int64) error {
+	webhook, err := s.webhookRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return ErrWebhookNotFound
+	}
+
+	return s.webhookRepo.Delete(id)
+}
+
+func (s *WebhookService) GetWebhook(id int64) (*WebhookViewModel, error) {
+	webhook, err := s.webhookRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	return toWebhookViewModel(webhook), nil
+}
+
+func (s *WebhookService) ListWebhooks() ([]*WebhookViewModel, error) {
+	webhooks, err := s.webhookRepo.FindAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	viewModels := make([]*WebhookViewModel, len(webhooks))
+	for i, webhook := range webhooks {
+		viewModels[i] = toWebhookViewModel(webhook)
+	}
+
+	return viewModels, nil
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first
+func (s *WebhookService) ListDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	webhook, err := s.webhookRepo.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	deliveries, err := s.webhookRepo.FindDeliveries(webhookID, limit, offset)
+	if deliveries == nil && err == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	return deliveries, err
+}
+
+// Redeliver queues the payload of an earlier delivery again under a new
+// delivery ID, leaving the original entry in the log untouched
+func (s *WebhookService) Redeliver(webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	original, err := s.webhookRepo.FindDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil || original.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	delivery, err := s.queue(webhookID, 0, original.EventType, original.PostID, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	s.signal()
+	return delivery, nil
+}
+
+// Enqueue records a pending delivery of the event for every active webhook
+// subscribed to its type. Webhooks that already have a delivery of the
+// event are skipped, so an event handed over again after a failure is not
+// delivered twice.
+func (s *WebhookService) Enqueue(event PostEvent) error {
+	webhooks, err := s.webhookRepo.FindAll(true)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(string(event.EventType)) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+
+		if _, err := s.queue(webhook.ID, event.ID, string(event.EventType), event.PostID, payload); err != nil {
+			return err
+		}
+	}
+
+	if payload != nil {
+		s.signal()
+	}
+	return nil
+}
+
+// queue stores a pending delivery; the returned delivery has no ID when
+// the webhook already had one for the event
+func (s *WebhookService) queue(webhookID, eventID int64, eventType string, postID int64, payload []byte) (*models.WebhookDelivery, error) {
+	id, err := newDeliveryID()
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhookID,
+		DeliveryID:    id,
+		EventID:       eventID,
+		EventType:     eventType,
+		PostID:        postID,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if _, err := s.webhookRepo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (s *WebhookService) signal() {
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
+
+// WebhookObserver forwards every post event to the webhook delivery queue
+type WebhookObserver struct {
+	webhookService *WebhookService
+}
+
+func NewWebhookObserver(webhookService *WebhookService) *WebhookObserver {
+	return &WebhookObserver{webhookService: webhookService}
+}
+
+func (o *WebhookObserver) Name() string {
+	return "webhooks"
+}
+
+func (o *WebhookObserver) Update(event PostEvent) error {
+	return o.webhookService.Enqueue(event)
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// normalizeWebhookEvents checks the event filter and drops duplicates
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if seen[event] {
+			continue
+		}
+
+		known := false
+		for _, candidate := range models.EventTypes {
+			if event == string(candidate) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, ErrInvalidWebhookEvent
+		}
+
+		seen[event] = true
+		normalized = append(normalized, event)
+	}
+
+	return normalized, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newDeliveryID returns a random (version 4) UUID
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func toWebhookViewModel(webhook *models.Webhook) *WebhookViewModel {
+	return &WebhookViewModel{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
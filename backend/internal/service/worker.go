@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// leasedWorker runs a task every interval on one replica at a time. The
+// replicas share a database lease named after the worker, and only the
+// holder runs the task. Workers embed it to get Start, Stop and RunOnce.
+type leasedWorker struct {
+	name      string
+	leaseRepo models.LeaseRepositoryInterface
+	interval  time.Duration
+	leaseTTL  time.Duration
+	wake      <-chan struct{}
+	task      func(now time.Time)
+	holder    string
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// newLeasedWorker creates a worker running task every interval, and early
+// whenever wake fires; wake may be nil. The lease is held for leaseTTL, so
+// it must outlast a slow run or another replica takes over mid-way.
+func newLeasedWorker(
+	name string,
+	leaseRepo models.LeaseRepositoryInterface,
+	interval, leaseTTL time.Duration,
+	wake <-chan struct{},
+	task func(now time.Time),
+) *leasedWorker {
+	hostname, _ := os.Hostname()
+	return &leasedWorker{
+		name:      name,
+		leaseRepo: leaseRepo,
+		interval:  interval,
+		leaseTTL:  leaseTTL,
+		wake:      wake,
+		task:      task,
+		holder:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start runs the worker in the background, beginning immediately so work
+// that fell due while the process was down is caught up on
+func (w *leasedWorker) Start() {
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.RunOnce(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-w.wake:
+			case <-w.stop:
+				if err := w.leaseRepo.Release(w.name, w.holder); err != nil {
+					log.Printf("Error releasing %s lease: %v", w.name, err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background loop and waits for it to finish
+func (w *leasedWorker) Stop() {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// RunOnce runs the task as of now, if this process holds the lease
+func (w *leasedWorker) RunOnce(now time.Time) {
+	acquired, err := w.leaseRepo.Acquire(w.name, w.holder, w.leaseTTL)
+	if err != nil {
+		log.Printf("Error acquiring %s lease: %v", w.name, err)
+		return
+	}
+	if acquired {
+		w.task(now)
+	}
+}
+
+// overrun reports whether a run that began at start has used half the
+// lease. Long tasks stop taking on work then, so the work in hand finishes
+// before the lease can expire and another replica takes over mid-way.
+func (w *leasedWorker) overrun(start time.Time) bool {
+	return time.Since(start) > w.leaseTTL/2
+}
+
+// stopping is closed once Stop is called, for tasks that wait mid-run
+func (w *leasedWorker) stopping() <-chan struct{} {
+	return w.stop
+}
+
+// exponentialBackoff returns the delay before the next attempt: base after
+// the first attempt, doubling with every further one, up to maxDelay
+func exponentialBackoff(base, maxDelay time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
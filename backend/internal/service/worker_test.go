@@ -0,0 +1,88 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"blog-platform/internal/models"
+)
+
+// countingTask counts the runs of a worker
+type countingTask struct {
+	mu   sync.Mutex
+	runs int
+}
+
+func (c *countingTask) run(time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runs++
+}
+
+func (c *countingTask) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runs
+}
+
+// newTestWorkers creates two replicas of a worker sharing one lease table
+func newTestWorkers(t *testing.T, leaseTTL time.Duration) (*leasedWorker, *countingTask, *leasedWorker, *countingTask) {
+	t.Helper()
+	leaseRepo := models.NewLeaseRepository(newTestDB(t))
+
+	firstTask, secondTask := &countingTask{}, &countingTask{}
+	first := newLeasedWorker("test_worker", leaseRepo, time.Hour, leaseTTL, nil, firstTask.run)
+	first.holder = "replica-1"
+	second := newLeasedWorker("test_worker", leaseRepo, time.Hour, leaseTTL, nil, secondTask.run)
+	second.holder = "replica-2"
+	return first, firstTask, second, secondTask
+}
+
+func TestLeasedWorkerRunsOnlyOnHolder(t *testing.T) {
+	first, firstTask, second, secondTask := newTestWorkers(t, time.Minute)
+
+	now := time.Now()
+	first.RunOnce(now)
+	second.RunOnce(now)
+	first.RunOnce(now)
+
+	if n := firstTask.count(); n != 2 {
+		t.Errorf("holder ran %d times, want 2: it renews its own lease", n)
+	}
+	if n := secondTask.count(); n != 0 {
+		t.Errorf("other replica ran %d times while the lease was held, want 0", n)
+	}
+}
+
+func TestLeasedWorkerHandsOverExpiredLease(t *testing.T) {
+	first, firstTask, second, secondTask := newTestWorkers(t, 50*time.Millisecond)
+
+	first.RunOnce(time.Now())
+	time.Sleep(100 * time.Millisecond)
+	second.RunOnce(time.Now())
+	first.RunOnce(time.Now())
+
+	if n := secondTask.count(); n != 1 {
+		t.Errorf("other replica ran %d times after the lease expired, want 1", n)
+	}
+	if n := firstTask.count(); n != 1 {
+		t.Errorf("previous holder ran %d times, want 1: the lease moved on", n)
+	}
+}
+
+func TestLeasedWorkerStopReleasesLease(t *testing.T) {
+	first, firstTask, second, secondTask := newTestWorkers(t, time.Hour)
+
+	first.Start()
+	first.Stop()
+	if n := firstTask.count(); n != 1 {
+		t.Fatalf("worker ran %d times on start, want 1", n)
+	}
+
+	// Without the release the other replica would wait out the hour
+	second.RunOnce(time.Now())
+	if n := secondTask.count(); n != 1 {
+		t.Errorf("other replica ran %d times after Stop, want 1", n)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+// ErrOpen is returned by Execute while the circuit is open
+var ErrOpen = errors.New("circuit breaker is open")
+
 type CircuitState int
 
 const (
@@ -34,8 +37,8 @@ func NewCircuitBreaker(name string, maxRequests int32, resetTimeout time.Duratio
 }
 
 func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	if cb.state == StateOpen {
-		return nil, errors.New("circuit breaker is open")
+	if cb.GetState() == StateOpen {
+		return nil, ErrOpen
 	}
 
 	result, err := req()
@@ -55,7 +58,8 @@ func (cb *CircuitBreaker) recordFailure() {
 	cb.failureCount++
 	cb.lastFailureTime = time.Now()
 
-	if cb.failureCount >= cb.maxRequests {
+	// A failed trial request in the half-open state reopens the circuit
+	if cb.state == StateHalfOpen || (cb.state == StateClosed && cb.failureCount >= cb.maxRequests) {
 		cb.state = StateOpen
 		go cb.startResetTimer()
 	}
@@ -0,0 +1,366 @@
+package highlight
+
+import (
+	"strings"
+)
+
+// Token classes
+const (
+	Plain   = ""
+	Keyword = "keyword"
+	Type    = "type"
+	Literal = "literal" // true, false, nil and the like
+	String  = "string"
+	Number  = "number"
+	Comment = "comment"
+)
+
+// Token is a run of source code of one class
+type Token struct {
+	Class string
+	Text  string
+}
+
+// language describes just enough of a language's lexical syntax to colour
+// it: comments, strings, numbers and reserved words
+type language struct {
+	keywords      []string
+	types         []string
+	literals      []string
+	lineComments  []string
+	blockComment  [2]string
+	quotes        string // characters that delimit single-line strings
+	rawQuotes     string // characters that delimit multi-line strings without escapes
+	tripleQuotes  bool   // Python's """ and ''' strings
+	caseSensitive bool
+
+	words map[string]string // word -> class, built from the lists above
+}
+
+var languages = map[string]*language{}
+
+// aliases maps common fence names to a language
+var aliases = map[string]string{
+	"golang": "go", "js": "javascript", "jsx": "javascript", "ts": "typescript", "tsx": "typescript",
+	"py": "python", "python3": "python", "sh": "bash", "shell": "bash", "zsh": "bash", "console": "bash",
+	"c++": "cpp", "cc": "cpp", "h": "c", "hpp": "cpp", "rs": "rust", "rb": "ruby", "kt": "kotlin",
+	"postgres": "sql", "postgresql": "sql", "mysql": "sql", "sqlite": "sql", "yml": "yaml",
+}
+
+func init() {
+	cLike := language{lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: `"'`, caseSensitive: true}
+
+	register := func(name string, lang language) {
+		lang.words = make(map[string]string)
+		for _, group := range []struct {
+			class string
+			words []string
+		}{{Keyword, lang.keywords}, {Type, lang.types}, {Literal, lang.literals}} {
+			for _, word := range group.words {
+				if !lang.caseSensitive {
+					word = strings.ToLower(word)
+				}
+				lang.words[word] = group.class
+			}
+		}
+		languages[name] = &lang
+	}
+
+	golang := cLike
+	golang.rawQuotes = "`"
+	golang.keywords = strings.Fields(`break case chan const continue default defer else fallthrough for func go goto
+		if import interface map package range return select struct switch type var`)
+	golang.types = strings.Fields(`bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64
+		rune string uint uint8 uint16 uint32 uint64 uintptr any`)
+	golang.literals = strings.Fields(`true false nil iota`)
+	register("go", golang)
+
+	javascript := cLike
+	javascript.rawQuotes = "`"
+	javascript.keywords = strings.Fields(`async await break case catch class const continue debugger default delete do
+		else export extends finally for from function if import in instanceof let new of return static super switch
+		this throw try typeof var void while with yield`)
+	javascript.literals = strings.Fields(`true false null undefined NaN Infinity`)
+	register("javascript", javascript)
+
+	typescript := javascript
+	typescript.keywords = append(strings.Fields(`abstract as declare enum implements interface keyof namespace
+		private protected public readonly type`), javascript.keywords...)
+	typescript.types = strings.Fields(`any boolean never number object string symbol unknown void bigint`)
+	register("typescript", typescript)
+
+	java := cLike
+	java.keywords = strings.Fields(`abstract assert break case catch class continue default do else enum extends final
+		finally for if implements import instanceof interface native new package private protected public return
+		static super switch synchronized this throw throws transient try var volatile while record`)
+	java.types = strings.Fields(`boolean byte char double float int long short void String Object`)
+	java.literals = strings.Fields(`true false null`)
+	register("java", java)
+
+	kotlin := cLike
+	kotlin.rawQuotes = ""
+	kotlin.keywords = strings.Fields(`as break class continue do else for fun if in interface is object package return
+		super this throw try typealias val var when while by constructor data enum import override private
+		protected public sealed companion lateinit open`)
+	kotlin.types = strings.Fields(`Any Boolean Byte Char Double Float Int Long Short String Unit`)
+	kotlin.literals = strings.Fields(`true false null`)
+	register("kotlin", kotlin)
+
+	c := cLike
+	c.keywords = strings.Fields(`break case const continue default do else enum extern for goto if inline register
+		return sizeof static struct switch typedef union volatile while #include #define #ifdef #ifndef #endif #if
+		#else #pragma`)
+	c.types = strings.Fields(`char double float int long short signed unsigned void size_t bool`)
+	c.literals = strings.Fields(`NULL true false`)
+	register("c", c)
+
+	cpp := c
+	cpp.keywords = append(strings.Fields(`auto catch class constexpr delete explicit friend namespace new noexcept
+		operator private protected public template this throw try typename using virtual`), c.keywords...)
+	cpp.types = append(strings.Fields(`std string vector map`), c.types...)
+	cpp.literals = strings.Fields(`nullptr true false NULL`)
+	register("cpp", cpp)
+
+	rust := cLike
+	rust.quotes = `"`
+	rust.keywords = strings.Fields(`as async await break const continue crate dyn else enum extern fn for if impl in
+		let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while`)
+	rust.types = strings.Fields(`bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64 u128 usize String
+		Vec Option Result Box`)
+	rust.literals = strings.Fields(`true false None Some Ok Err`)
+	register("rust", rust)
+
+	register("python", language{
+		lineComments: []string{"#"}, quotes: `"'`, tripleQuotes: true, caseSensitive: true,
+		keywords: strings.Fields(`and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield match case`),
+		types:    strings.Fields(`int float str bool list dict set tuple bytes object`),
+		literals: strings.Fields(`True False None self`),
+	})
+
+	register("ruby", language{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		keywords: strings.Fields(`alias and begin break case class def defined? do else elsif end ensure for if in
+			module next not or redo rescue retry return self super then undef unless until when while yield
+			require attr_accessor attr_reader private protected public`),
+		literals: strings.Fields(`true false nil`),
+	})
+
+	register("bash", language{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		keywords: strings.Fields(`if then else elif fi case esac for while until do done in function return exit
+			export local readonly set unset source echo cd sudo`),
+		literals: strings.Fields(`true false`),
+	})
+
+	register("sql", language{
+		lineComments: []string{"--"}, blockComment: [2]string{"/*", "*/"}, quotes: `'"`,
+		keywords: strings.Fields(`add all alter and as asc begin between by case check column commit constraint create
+			cross default delete desc distinct drop else end exists foreign from full group having if in index inner
+			insert into is join key left like limit not on or order outer primary references returning right
+			rollback select set table then transaction union unique update values view when where with offset
+			conflict do nothing autoincrement`),
+		types: strings.Fields(`bigint blob boolean char date datetime decimal double float int integer numeric real
+			smallint text timestamp varchar`),
+		literals: strings.Fields(`null true false current_timestamp`),
+	})
+
+	register("json", language{
+		quotes: `"`, caseSensitive: true,
+		literals: strings.Fields(`true false null`),
+	})
+
+	register("yaml", language{
+		lineComments: []string{"#"}, quotes: `"'`, caseSensitive: true,
+		literals: strings.Fields(`true false null yes no on off ~`),
+	})
+}
+
+// Supported reports whether lang, a fence info string such as "go" or
+// "js", names a language that can be highlighted
+func Supported(lang string) bool {
+	return lookup(lang) != nil
+}
+
+func lookup(lang string) *language {
+	lang = strings.ToLower(lang)
+	if alias, ok := aliases[lang]; ok {
+		lang = alias
+	}
+	return languages[lang]
+}
+
+// Tokenize splits code into classified tokens. Code in an unsupported
+// language comes back as a single plain token.
+func Tokenize(lang, code string) []Token {
+	l := lookup(lang)
+	if l == nil {
+		return []Token{{Class: Plain, Text: code}}
+	}
+
+	var tokens []Token
+	add := func(class, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].Class == class {
+			tokens[n-1].Text += text
+			return
+		}
+		tokens = append(tokens, Token{Class: class, Text: text})
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		c := code[i]
+
+		if n := l.comment(rest); n > 0 {
+			add(Comment, rest[:n])
+			i += n
+			continue
+		}
+
+		if n := l.str(rest); n > 0 {
+			add(String, rest[:n])
+			i += n
+			continue
+		}
+
+		if isDigit(c) && (i == 0 || !isWordByte(code[i-1])) {
+			n := number(rest)
+			add(Number, rest[:n])
+			i += n
+			continue
+		}
+
+		if isWordStart(c) {
+			n := 1
+			for n < len(rest) && isWordByte(rest[n]) {
+				n++
+			}
+			// Preprocessor directives and Ruby predicates are words too
+			if n < len(rest) && rest[n] == '?' {
+				if _, ok := l.words[rest[:n+1]]; ok {
+					n++
+				}
+			}
+			word := rest[:n]
+			key := word
+			if !l.caseSensitive {
+				key = strings.ToLower(word)
+			}
+			add(l.words[key], word)
+			i += n
+			continue
+		}
+
+		if c == '#' && i+1 < len(code) && isWordStart(code[i+1]) {
+			n := 2
+			for n < len(rest) && isWordByte(rest[n]) {
+				n++
+			}
+			if class, ok := l.words[rest[:n]]; ok {
+				add(class, rest[:n])
+				i += n
+				continue
+			}
+		}
+
+		add(Plain, rest[:1])
+		i++
+	}
+
+	return tokens
+}
+
+// comment returns the length of a comment at the start of s
+func (l *language) comment(s string) int {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+
+	open, close := l.blockComment[0], l.blockComment[1]
+	if open != "" && strings.HasPrefix(s, open) {
+		if end := strings.Index(s[len(open):], close); end >= 0 {
+			return len(open) + end + len(close)
+		}
+		return len(s)
+	}
+	return 0
+}
+
+// str returns the length of a string literal at the start of s. An
+// unterminated single-line string ends at the end of the line.
+func (l *language) str(s string) int {
+	q := s[0]
+
+	if l.tripleQuotes && (strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''")) {
+		if end := strings.Index(s[3:], s[:3]); end >= 0 {
+			return 3 + end + 3
+		}
+		return len(s)
+	}
+
+	if strings.IndexByte(l.rawQuotes, q) >= 0 {
+		if end := strings.IndexByte(s[1:], q); end >= 0 {
+			return end + 2
+		}
+		return len(s)
+	}
+
+	if strings.IndexByte(l.quotes, q) < 0 {
+		return 0
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			return i + 1
+		case '\n':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// number returns the length of a numeric literal at the start of s,
+// including hex, binary and octal prefixes, separators and exponents
+func number(s string) int {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case isDigit(c) || c == '_' || c == '.' && i+1 < len(s) && isDigit(s[i+1]):
+		case (c == 'e' || c == 'E') && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '-' || s[i+1] == '+'):
+			i++
+		case (c == 'x' || c == 'X' || c == 'b' || c == 'B' || c == 'o' || c == 'O') && i == 1 && s[0] == '0':
+		case isHexLetter(c) && len(s) > 1 && (s[1] == 'x' || s[1] == 'X'):
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexLetter(c byte) bool {
+	return (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isWordStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$'
+}
+
+func isWordByte(c byte) bool {
+	return isWordStart(c) || isDigit(c)
+}
@@ -0,0 +1,85 @@
+package hyperloglog
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// Precision is the number of index bits. 2^12 registers give a standard
+// error of about 1.6% in 4 KiB.
+const Precision = 12
+
+const registers = 1 << Precision
+
+var ErrInvalidSketch = errors.New("hyperloglog: invalid encoded sketch")
+
+// Sketch estimates the number of distinct 64-bit hashes added to it. Inputs
+// must already be uniformly distributed hashes.
+type Sketch struct {
+	registers [registers]uint8
+}
+
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add records a hash
+func (s *Sketch) Add(hash uint64) {
+	index := hash >> (64 - Precision)
+	// The remaining bits, with a sentinel so the rank is at most 64-Precision+1
+	w := hash<<Precision | 1<<(Precision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge folds another sketch into this one; the result estimates the union
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct hashes
+func (s *Sketch) Count() uint64 {
+	m := float64(registers)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes the sketch as its precision followed by the registers
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1+registers)
+	data[0] = Precision
+	copy(data[1:], s.registers[:])
+	return data, nil
+}
+
+// UnmarshalBinary decodes a sketch written by MarshalBinary
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) != 1+registers || data[0] != Precision {
+		return ErrInvalidSketch
+	}
+	copy(s.registers[:], data[1:])
+	return nil
+}
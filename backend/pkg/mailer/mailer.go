@@ -0,0 +1,125 @@
+// Package mailer sends email. Message builds a multipart text and HTML
+// email; Mailer implementations hand it to an SMTP server or write it to
+// local files for development.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("header values must not contain line breaks")
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Message is an email with a plain text and an HTML body
+type Message struct {
+	From    string // e.g. "Blog <noreply@example.com>"
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // extra headers such as List-Unsubscribe
+}
+
+// Bytes encodes the message in RFC 5322 format as multipart/alternative,
+// with both bodies quoted-printable
+func (m *Message) Bytes() ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+
+	headers := map[string]string{
+		"From":         from.String(),
+		"To":           to.String(),
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID(from.Address),
+		"MIME-Version": "1.0",
+	}
+	for name, value := range m.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	headers["Content-Type"] = `multipart/alternative; boundary="` + parts.Boundary() + `"`
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	for _, name := range names {
+		value := headers[name]
+		if strings.ContainsAny(name+value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+		out.WriteString(name + ": " + value + "\r\n")
+	}
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// Recipient returns the bare address the message is sent to
+func (m *Message) Recipient() (string, error) {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return "", err
+	}
+	return to.Address, nil
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
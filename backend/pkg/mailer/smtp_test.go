@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSMTPSendTimesOutOnStalledServer(t *testing.T) {
+	// Accepts connections but never sends the greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	m, err := NewSMTP(listener.Addr().String(), "", "")
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	m.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err = m.Send(&Message{From: "Blog <noreply@example.com>", To: "reader@example.com", Subject: "Hi", Text: "Hello"})
+	if err == nil {
+		t.Fatal("Send succeeded against a server that never answered")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send gave up after %v, want about the 100ms timeout", elapsed)
+	}
+}
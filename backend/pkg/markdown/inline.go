@@ -0,0 +1,478 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	autolinkURL   = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	autolinkEmail = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	entity        = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	inlineHTML    = regexp.MustCompile(`^(?:<!--[\s\S]*?-->|</?[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>)`)
+)
+
+// node is a piece of inline output. Delimiter runs (*, _ and ~) start as
+// literal text and are turned into emphasis tags once matched.
+type node struct {
+	html string // rendered output of non-delimiter nodes
+
+	delim    byte
+	count    int // unmatched delimiters left in the run
+	canOpen  bool
+	canClose bool
+	opens    []string // tags emitted after the remaining delimiters
+	closes   []string // tags emitted before the remaining delimiters
+}
+
+// inline renders the inline content of a block
+func inline(text string) string {
+	var nodes []*node
+	var plain strings.Builder
+
+	flushText := func() {
+		if plain.Len() > 0 {
+			nodes = append(nodes, &node{html: html.EscapeString(plain.String())})
+			plain.Reset()
+		}
+	}
+	emit := func(rendered string) {
+		flushText()
+		nodes = append(nodes, &node{html: rendered})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		rest := text[i:]
+
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			emit("<br>\n")
+			i += 2
+
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			plain.WriteByte(text[i+1])
+			i += 2
+
+		case c == '`':
+			if rendered, n := codeSpan(rest); n > 0 {
+				emit(rendered)
+				i += n
+			} else {
+				run := len(rest) - len(strings.TrimLeft(rest, "`"))
+				plain.WriteString(rest[:run])
+				i += run
+			}
+
+		case c == '\n':
+			// Two or more trailing spaces make a hard break
+			current := plain.String()
+			trimmed := strings.TrimRight(current, " ")
+			hard := len(current)-len(trimmed) >= 2
+			plain.Reset()
+			plain.WriteString(trimmed)
+			if hard {
+				emit("<br>\n")
+			} else {
+				plain.WriteByte('\n')
+			}
+			i++
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+
+		case c == '$':
+			if rendered, n := mathSpan(rest); n > 0 {
+				emit(rendered)
+				i += n
+			} else {
+				plain.WriteByte(c)
+				i++
+			}
+
+		case c == '<':
+			if m := autolinkURL.FindStringSubmatch(rest); m != nil {
+				emit(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + `</a>`)
+				i += len(m[0])
+			} else if m := autolinkEmail.FindStringSubmatch(rest); m != nil {
+				emit(`<a href="mailto:` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + `</a>`)
+				i += len(m[0])
+			} else if m := inlineHTML.FindString(rest); m != "" {
+				emit(m)
+				i += len(m)
+			} else {
+				plain.WriteByte(c)
+				i++
+			}
+
+		case c == '!' && strings.HasPrefix(rest, "!["):
+			if rendered, n := link(rest[1:], true); n > 0 {
+				emit(rendered)
+				i += n + 1
+			} else {
+				plain.WriteByte(c)
+				i++
+			}
+
+		case c == '[':
+			if rendered, n := link(rest, false); n > 0 {
+				emit(rendered)
+				i += n
+			} else {
+				plain.WriteByte(c)
+				i++
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			run := len(rest) - len(strings.TrimLeft(rest, string(c)))
+			if c == '~' && run != 2 {
+				plain.WriteString(rest[:run])
+				i += run
+				continue
+			}
+			flushText()
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			after, _ := utf8.DecodeRuneInString(text[i+run:])
+			if i == 0 {
+				before = ' '
+			}
+			if i+run >= len(text) {
+				after = ' '
+			}
+			open, close := flanking(c, before, after)
+			nodes = append(nodes, &node{delim: c, count: run, canOpen: open, canClose: close})
+			i += run
+
+		case c == '&':
+			// Entity references pass through; other ampersands are escaped
+			if m := entity.FindString(rest); m != "" && html.UnescapeString(m) != m {
+				emit(m)
+				i += len(m)
+			} else {
+				plain.WriteByte(c)
+				i++
+			}
+
+		default:
+			plain.WriteByte(c)
+			i++
+		}
+	}
+	flushText()
+
+	matchEmphasis(nodes)
+
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.delim == 0 {
+			b.WriteString(n.html)
+			continue
+		}
+		for _, tag := range n.closes {
+			b.WriteString(tag)
+		}
+		b.WriteString(strings.Repeat(string(n.delim), n.count))
+		for _, tag := range n.opens {
+			b.WriteString(tag)
+		}
+	}
+	return b.String()
+}
+
+// flanking applies CommonMark's rules for whether a delimiter run can open
+// or close emphasis
+func flanking(delim byte, before, after rune) (canOpen, canClose bool) {
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	if delim == '_' {
+		return left && (!right || isPunct(before)), right && (!left || isPunct(after))
+	}
+	return left, right
+}
+
+// matchEmphasis pairs closers with the nearest compatible opener, innermost
+// first, as in CommonMark's "process emphasis" procedure
+func matchEmphasis(nodes []*node) {
+	for c := 0; c < len(nodes); c++ {
+		closer := nodes[c]
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+
+		for closer.count > 0 {
+			o := c - 1
+			for ; o >= 0; o-- {
+				opener := nodes[o]
+				if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+					continue
+				}
+				// Rule of three: a run that can both open and close only
+				// pairs when the lengths allow it
+				if (opener.canClose || closer.canOpen) && (opener.count+closer.count)%3 == 0 &&
+					!(opener.count%3 == 0 && closer.count%3 == 0) {
+					continue
+				}
+				break
+			}
+			if o < 0 {
+				break
+			}
+
+			opener := nodes[o]
+			use := 1
+			if opener.count >= 2 && closer.count >= 2 {
+				use = 2
+			}
+			if closer.delim == '~' {
+				if use != 2 {
+					break
+				}
+			}
+
+			tag := "em"
+			if closer.delim == '~' {
+				tag = "del"
+			} else if use == 2 {
+				tag = "strong"
+			}
+
+			opener.count -= use
+			closer.count -= use
+			opener.opens = append([]string{"<" + tag + ">"}, opener.opens...)
+			closer.closes = append(closer.closes, "</"+tag+">")
+
+			// Delimiters between the pair can no longer match
+			for k := o + 1; k < c; k++ {
+				if nodes[k].delim != 0 {
+					nodes[k].canOpen = false
+					nodes[k].canClose = false
+				}
+			}
+		}
+	}
+}
+
+// codeSpan renders a code span starting at s, returning its length in s
+func codeSpan(s string) (string, int) {
+	run := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:run]
+
+	for i := run; i < len(s); {
+		j := strings.Index(s[i:], fence)
+		if j < 0 {
+			return "", 0
+		}
+		start := i + j
+		end := start + run
+		// The closing run must be exactly as long as the opening one
+		if (start > 0 && s[start-1] == '`') || (end < len(s) && s[end] == '`') {
+			i = end
+			for i < len(s) && s[i] == '`' {
+				i++
+			}
+			continue
+		}
+
+		code := strings.ReplaceAll(s[run:start], "\n", " ")
+		if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		return "<code>" + html.EscapeString(code) + "</code>", end
+	}
+	return "", 0
+}
+
+// mathSpan renders $tex$ or $$tex$$ at the start of s, returning its
+// length in s. As in Pandoc, the opening $ must be followed by a non-space
+// and the closing one preceded by a non-space and not followed by a digit,
+// so prices like $5 and $10 stay text.
+func mathSpan(s string) (string, int) {
+	if strings.HasPrefix(s, "$$") {
+		end := strings.Index(s[2:], "$$")
+		if end <= 0 {
+			return "", 0
+		}
+		return mathMarkup(strings.TrimSpace(s[2:2+end]), true), end + 4
+	}
+
+	if len(s) < 3 || isSpaceByte(s[1]) {
+		return "", 0
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '$':
+			if isSpaceByte(s[i-1]) || (i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9') {
+				continue
+			}
+			return mathMarkup(s[1:i], false), i + 1
+		}
+	}
+	return "", 0
+}
+
+// mathMarkup wraps TeX in the delimiters and classes KaTeX looks for
+func mathMarkup(tex string, display bool) string {
+	if display {
+		return `<span class="math display">\[` + html.EscapeString(tex) + `\]</span>`
+	}
+	return `<span class="math inline">\(` + html.EscapeString(tex) + `\)</span>`
+}
+
+// link renders [text](destination "title") at the start of s, or the image
+// form when image is set, returning its length in s
+func link(s string, image bool) (string, int) {
+	end := closingBracket(s)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", 0
+	}
+	label := s[1:end]
+
+	i := end + 2
+	for i < len(s) && isSpaceByte(s[i]) {
+		i++
+	}
+
+	// Destination: <...> or a run without spaces and with balanced parentheses
+	var dest string
+	if i < len(s) && s[i] == '<' {
+		close := strings.IndexAny(s[i+1:], ">\n")
+		if close < 0 || s[i+1+close] != '>' {
+			return "", 0
+		}
+		dest = s[i+1 : i+1+close]
+		i += close + 2
+	} else {
+		start, depth := i, 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				continue
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if c <= ' ' {
+				break
+			}
+		}
+		dest = s[start:i]
+	}
+
+	for i < len(s) && isSpaceByte(s[i]) {
+		i++
+	}
+
+	var title string
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closer := s[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		j := i + 1
+		for ; j < len(s) && s[j] != closer; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(s) {
+			return "", 0
+		}
+		title = s[i+1 : j]
+		i = j + 1
+		for i < len(s) && isSpaceByte(s[i]) {
+			i++
+		}
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", 0
+	}
+
+	href := html.EscapeString(unescape(dest))
+	titleAttr := ""
+	if title != "" {
+		titleAttr = ` title="` + html.EscapeString(unescape(title)) + `"`
+	}
+
+	if image {
+		return `<img src="` + href + `" alt="` + html.EscapeString(plainText(label)) + `"` + titleAttr + `>`, i + 1
+	}
+	return `<a href="` + href + `"` + titleAttr + `>` + inline(label) + `</a>`, i + 1
+}
+
+// closingBracket finds the ] matching the [ at the start of s, skipping
+// code spans, escapes and nested brackets
+func closingBracket(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			if _, n := codeSpan(s[i:]); n > 0 {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// plainText strips Markdown punctuation from image alt text
+func plainText(s string) string {
+	rendered := inline(s)
+	var b strings.Builder
+	inTag := false
+	for _, r := range rendered {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+// unescape removes backslash escapes and decodes entities
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
@@ -0,0 +1,529 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Render converts Markdown to HTML. It covers the commonly used part of
+// CommonMark: ATX and setext headings, paragraphs, block quotes, ordered
+// and unordered lists, fenced and indented code blocks, thematic breaks,
+// emphasis, code spans, links, images, autolinks and hard line breaks, plus
+// GitHub-style tables and strikethrough.
+//
+// TeX between $...$ (inline) and $$...$$ (display) becomes
+// <span class="math inline">\(...\)</span> and
+// <span class="math display">\[...\]</span>, which KaTeX's auto-render
+// extension and MathJax both recognise.
+//
+// Raw HTML is passed through as CommonMark requires, so the output must be
+// sanitized before it is served.
+func Render(source string) string {
+	var b strings.Builder
+	renderBlocks(&b, splitLines(source), false)
+	return b.String()
+}
+
+var (
+	atxHeading    = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicBreak = regexp.MustCompile(`^(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextLine    = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+	fenceOpen     = regexp.MustCompile("^(`{3,}|~{3,})[ \t]*([^`]*)$")
+	bulletItem    = regexp.MustCompile(`^([-*+])([ \t]+|$)`)
+	orderedItem   = regexp.MustCompile(`^(\d{1,9})([.)])([ \t]+|$)`)
+	tableDelim    = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	htmlBlockTag  = regexp.MustCompile(`^<(?:!--|/?([a-zA-Z][a-zA-Z0-9-]*)(?:[\s/>]|$))`)
+)
+
+// blockTags start an HTML block when they open a line
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "audio": true, "blockquote": true,
+	"details": true, "dialog": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "iframe": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "script": true, "section": true, "style": true, "summary": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"tr": true, "ul": true, "video": true,
+}
+
+// splitLines normalises line endings and expands leading tabs
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandIndent(line)
+	}
+	return lines
+}
+
+func expandIndent(line string) string {
+	width := 0
+	for i, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return strings.Repeat(" ", width) + line[i:]
+		}
+	}
+	return strings.Repeat(" ", width)
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// renderBlocks renders a sequence of block-level lines. In a tight list,
+// paragraphs are written without <p> tags.
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		text := inline(strings.TrimSpace(strings.Join(paragraph, "\n")))
+		if tight {
+			b.WriteString(text + "\n")
+		} else {
+			b.WriteString("<p>" + text + "</p>\n")
+		}
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		indent := indentOf(line)
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+			i++
+
+		case indent >= 4 && len(paragraph) == 0:
+			i = renderIndentedCode(b, lines, i)
+
+		case indent >= 4:
+			// Indented lines continue a paragraph
+			paragraph = append(paragraph, trimmed)
+			i++
+
+		case fenceOpen.MatchString(trimmed):
+			flush()
+			i = renderFencedCode(b, lines, i, indent)
+
+		case len(paragraph) == 0 && strings.HasPrefix(trimmed, "$$"):
+			i = renderMathBlock(b, lines, i)
+
+		case atxHeading.MatchString(trimmed):
+			flush()
+			m := atxHeading.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, inline(strings.TrimSpace(m[2])), level)
+			i++
+
+		case len(paragraph) > 0 && setextLine.MatchString(trimmed):
+			level := 2
+			if trimmed[0] == '=' {
+				level = 1
+			}
+			text := inline(strings.TrimSpace(strings.Join(paragraph, "\n")))
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, text, level)
+			paragraph = nil
+			i++
+
+		case thematicBreak.MatchString(trimmed):
+			flush()
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			i = renderBlockquote(b, lines, i)
+
+		case startsListItem(trimmed, len(paragraph) > 0):
+			flush()
+			i = renderList(b, lines, i)
+
+		case len(paragraph) == 0 && i+1 < len(lines) && strings.Contains(trimmed, "|") &&
+			tableDelim.MatchString(strings.TrimSpace(lines[i+1])) &&
+			len(splitRow(trimmed)) == len(splitRow(strings.TrimSpace(lines[i+1]))):
+			i = renderTable(b, lines, i)
+
+		case len(paragraph) == 0 && isHTMLBlock(trimmed):
+			for i < len(lines) && !isBlank(lines[i]) {
+				b.WriteString(lines[i] + "\n")
+				i++
+			}
+
+		default:
+			paragraph = append(paragraph, line)
+			i++
+		}
+	}
+
+	flush()
+}
+
+func isHTMLBlock(line string) bool {
+	m := htmlBlockTag.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	return m[1] == "" || blockTags[strings.ToLower(m[1])]
+}
+
+func renderIndentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4) {
+		if isBlank(lines[i]) {
+			code = append(code, "")
+		} else {
+			code = append(code, lines[i][4:])
+		}
+		i++
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")+"\n") + "</code></pre>\n")
+	return i
+}
+
+func renderFencedCode(b *strings.Builder, lines []string, i, indent int) int {
+	m := fenceOpen.FindStringSubmatch(strings.TrimSpace(lines[i]))
+	fence := m[1]
+	info := strings.Fields(unescape(m[2]))
+	i++
+
+	var code []string
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+			i++
+			break
+		}
+		// Strip up to the fence's own indentation
+		line := lines[i]
+		strip := indentOf(line)
+		if strip > indent {
+			strip = indent
+		}
+		code = append(code, line[strip:])
+	}
+
+	class := ""
+	if len(info) > 0 {
+		class = ` class="language-` + html.EscapeString(info[0]) + `"`
+	}
+	content := strings.Join(code, "\n")
+	if len(code) > 0 {
+		content += "\n"
+	}
+	b.WriteString("<pre><code" + class + ">" + html.EscapeString(content) + "</code></pre>\n")
+	return i
+}
+
+// renderMathBlock renders display math from an opening $$ to the line
+// ending with the closing $$
+func renderMathBlock(b *strings.Builder, lines []string, i int) int {
+	var tex []string
+	first := strings.TrimPrefix(strings.TrimSpace(lines[i]), "$$")
+	if strings.HasSuffix(first, "$$") {
+		tex = append(tex, strings.TrimSuffix(first, "$$"))
+		i++
+	} else {
+		tex = append(tex, first)
+		for i++; i < len(lines); i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if strings.HasSuffix(trimmed, "$$") {
+				tex = append(tex, strings.TrimSuffix(trimmed, "$$"))
+				i++
+				break
+			}
+			tex = append(tex, trimmed)
+		}
+	}
+
+	b.WriteString("<p>" + mathMarkup(strings.TrimSpace(strings.Join(tex, "\n")), true) + "</p>\n")
+	return i
+}
+
+func renderBlockquote(b *strings.Builder, lines []string, i int) int {
+	var inner []string
+	for i < len(lines) {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.HasPrefix(trimmed, ">") {
+			trimmed = strings.TrimPrefix(trimmed, ">")
+			trimmed = strings.TrimPrefix(trimmed, " ")
+			inner = append(inner, trimmed)
+			i++
+			continue
+		}
+		// A lazy continuation line extends the quoted paragraph
+		if !isBlank(lines[i]) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !startsBlock(strings.TrimSpace(lines[i])) {
+			inner = append(inner, lines[i])
+			i++
+			continue
+		}
+		break
+	}
+
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// startsBlock reports whether a line would begin a new block rather than
+// continue a paragraph
+func startsBlock(trimmed string) bool {
+	return fenceOpen.MatchString(trimmed) || atxHeading.MatchString(trimmed) ||
+		thematicBreak.MatchString(trimmed) || strings.HasPrefix(trimmed, ">") || startsListItem(trimmed, true)
+}
+
+// startsListItem reports whether a line opens a list item. Inside a
+// paragraph only bullets and ordered lists starting at 1 interrupt it.
+func startsListItem(trimmed string, inParagraph bool) bool {
+	if m := bulletItem.FindStringSubmatch(trimmed); m != nil {
+		return !inParagraph || m[2] != ""
+	}
+	if m := orderedItem.FindStringSubmatch(trimmed); m != nil {
+		return !inParagraph || (m[1] == "1" && m[3] != "")
+	}
+	return false
+}
+
+type listItem struct {
+	lines []string
+}
+
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first := strings.TrimLeft(lines[i], " ")
+	baseIndent := indentOf(lines[i])
+
+	ordered := false
+	marker := ""
+	start := 1
+	if m := orderedItem.FindStringSubmatch(first); m != nil {
+		ordered = true
+		marker = m[2]
+		start, _ = strconv.Atoi(m[1])
+	} else {
+		marker = bulletItem.FindStringSubmatch(first)[1]
+	}
+
+	var items []*listItem
+	var contentIndent int
+	loose := false
+	blankBefore := false
+
+	for i < len(lines) {
+		line := lines[i]
+
+		if isBlank(line) {
+			if len(items) > 0 {
+				items[len(items)-1].lines = append(items[len(items)-1].lines, "")
+			}
+			blankBefore = true
+			i++
+			continue
+		}
+
+		indent := indentOf(line)
+		trimmed := strings.TrimLeft(line, " ")
+
+		if indent < contentIndent || len(items) == 0 {
+			// A sibling item continues the list
+			if indent >= baseIndent && indent < baseIndent+4 {
+				if width, ok := itemMarker(trimmed, ordered, marker); ok {
+					if blankBefore && len(items) > 0 {
+						loose = true
+					}
+					contentIndent = indent + width
+					content := ""
+					if len(line) > contentIndent {
+						content = line[contentIndent:]
+					}
+					items = append(items, &listItem{lines: []string{content}})
+					blankBefore = false
+					i++
+					continue
+				}
+			}
+
+			// A lazy continuation of the item's paragraph
+			if !blankBefore && !startsBlock(strings.TrimSpace(line)) {
+				last := items[len(items)-1]
+				last.lines = append(last.lines, strings.TrimSpace(line))
+				i++
+				continue
+			}
+			break
+		}
+
+		last := items[len(items)-1]
+		if blankBefore && len(last.lines) > 1 {
+			loose = true
+		}
+		last.lines = append(last.lines, line[contentIndent:])
+		blankBefore = false
+		i++
+	}
+
+	// Trailing blank lines belong to whatever follows the list
+	for _, item := range items {
+		for len(item.lines) > 0 && item.lines[len(item.lines)-1] == "" {
+			item.lines = item.lines[:len(item.lines)-1]
+		}
+		for j := 1; j < len(item.lines); j++ {
+			if item.lines[j] == "" {
+				loose = true
+			}
+		}
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	if ordered && start != 1 {
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", start)
+	} else {
+		b.WriteString("<" + tag + ">\n")
+	}
+	for _, item := range items {
+		b.WriteString("<li>")
+		var inner strings.Builder
+		renderBlocks(&inner, item.lines, !loose)
+		content := inner.String()
+		if !loose {
+			content = strings.TrimSuffix(content, "\n")
+		} else {
+			b.WriteString("\n")
+		}
+		b.WriteString(content)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+
+	return i
+}
+
+// itemMarker returns the width of a list marker and the spaces after it
+// when the line opens an item of the same kind of list
+func itemMarker(trimmed string, ordered bool, marker string) (int, bool) {
+	if ordered {
+		m := orderedItem.FindStringSubmatch(trimmed)
+		if m == nil || m[2] != marker {
+			return 0, false
+		}
+		return markerWidth(len(m[1])+1, m[3]), true
+	}
+
+	m := bulletItem.FindStringSubmatch(trimmed)
+	if m == nil || m[1] != marker {
+		return 0, false
+	}
+	return markerWidth(1, m[2]), true
+}
+
+// markerWidth is the marker plus its following spaces, where five or more
+// spaces mean the content is indented code and only one belongs to the marker
+func markerWidth(marker int, spaces string) int {
+	n := len(spaces)
+	if n == 0 || n > 4 {
+		n = 1
+	}
+	return marker + n
+}
+
+func renderTable(b *strings.Builder, lines []string, i int) int {
+	header := splitRow(strings.TrimSpace(lines[i]))
+	var aligns []string
+	for _, cell := range splitRow(strings.TrimSpace(lines[i+1])) {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case right:
+			aligns = append(aligns, "right")
+		case left:
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	cell := func(tag string, col int, text string) string {
+		align := ""
+		if aligns[col] != "" {
+			align = ` align="` + aligns[col] + `"`
+		}
+		return "<" + tag + align + ">" + inline(text) + "</" + tag + ">"
+	}
+
+	b.WriteString("<table>\n<thead>\n<tr>")
+	for col, text := range header {
+		b.WriteString(cell("th", col, text))
+	}
+	b.WriteString("</tr>\n</thead>\n")
+
+	i += 2
+	if i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|") {
+		b.WriteString("<tbody>\n")
+		for ; i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+			row := splitRow(strings.TrimSpace(lines[i]))
+			b.WriteString("<tr>")
+			for col := range header {
+				text := ""
+				if col < len(row) {
+					text = row[col]
+				}
+				b.WriteString(cell("td", col, text))
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+
+	return i
+}
+
+// splitRow splits a table row on unescaped pipes, ignoring the outer ones
+func splitRow(row string) []string {
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"testing"
+
+	"blog-platform/pkg/sanitize"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "# Title #", "<h1>Title</h1>\n"},
+		{"setext heading", "Title\n-----", "<h2>Title</h2>\n"},
+		{"emphasis", "*a* **b** ~~c~~", "<p><em>a</em> <strong>b</strong> <del>c</del></p>\n"},
+		{"list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"ordered list", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"quote", "> a", "<blockquote>\n<p>a</p>\n</blockquote>\n"},
+		{"fenced code", "```go\nx := 1\n```", "<pre><code class=\"language-go\">x := 1\n</code></pre>\n"},
+		{"inline math", "$x^2$", "<p><span class=\"math inline\">\\(x^2\\)</span></p>\n"},
+		{"hard break", "a  \nb", "<p>a<br>\nb</p>\n"},
+		{"text escaped", "5 < 6 > 4 & \"x\"", "<p>5 &lt; 6 &gt; 4 &amp; &#34;x&#34;</p>\n"},
+		{"entities kept", "a &amp; b &lt;", "<p>a &amp; b &lt;</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.in); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// Raw HTML is passed through untouched, as CommonMark requires; HTML in
+// code and in link and image attributes is escaped
+func TestRenderRawHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"html block", "<div onclick=\"x\">\n*hi*\n</div>", "<div onclick=\"x\">\n*hi*\n</div>\n"},
+		{"html block with markdown", "<div>\n\n*hi*\n\n</div>", "<div>\n<p><em>hi</em></p>\n</div>\n"},
+		{"script block", "<script>\nalert(1)\n</script>\n\npara", "<script>\nalert(1)\n</script>\n<p>para</p>\n"},
+		{"style block", "<style>\np{}\n</style>", "<style>\np{}\n</style>\n"},
+		{"comment", "<!-- c -->", "<!-- c -->\n"},
+		{"inline html", "a <span onclick=\"x\">b</span> c", "<p>a <span onclick=\"x\">b</span> c</p>\n"},
+		{"inline img", "x <img src=x onerror=alert(1)> y", "<p>x <img src=x onerror=alert(1)> y</p>\n"},
+		{"inline svg", "<svg><script>1</script></svg>", "<p><svg><script>1</script></svg></p>\n"},
+		{"code span", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+		{"indented code", "    <script>x</script>", "<pre><code>&lt;script&gt;x&lt;/script&gt;\n</code></pre>\n"},
+		{"fenced code", "```\n<b>x</b>\n```", "<pre><code>&lt;b&gt;x&lt;/b&gt;\n</code></pre>\n"},
+		{"link attribute breakout", `[x]("onmouseover="alert(1))`, "<p><a href=\"&#34;onmouseover=&#34;alert(1)\">x</a></p>\n"},
+		{"image attributes", `![a"b](/i.png "t\"x")`, "<p><img src=\"/i.png\" alt=\"a&#34;b\" title=\"t&#34;x\"></p>\n"},
+		{"autolink", `<https://example.com/?a=1&b="2">`, "<p><a href=\"https://example.com/?a=1&amp;b=&#34;2&#34;\">https://example.com/?a=1&amp;b=&#34;2&#34;</a></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.in); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// What Render passes through is removed by the sanitizer posts are
+// rendered with
+func TestRenderSanitized(t *testing.T) {
+	const rel = ` rel="nofollow noopener noreferrer"`
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"script block", "<script>\nalert(1)\n</script>\n\npara", "\n<p>para</p>\n"},
+		{"style block", "<style>\np{}\n</style>\n\npara", "\n<p>para</p>\n"},
+		{"event handler", "a <span onclick=\"alert(1)\">b</span> c", "<p>a <span>b</span> c</p>\n"},
+		{"img onerror", "x <img src=x onerror=alert(1)> y", "<p>x <img src=\"x\"/> y</p>\n"},
+		{"svg", "a <svg onload=alert(1)><circle/></svg> b", "<p>a  b</p>\n"},
+		{"math", "a <math><mi>x</mi></math> b", "<p>a  b</p>\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p><a>x</a></p>\n"},
+		{"javascript link entity", "[x](java&#x09;script:alert(1))", "<p><a>x</a></p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p><a>javascript:alert(1)</a></p>\n"},
+		{"data image", "![x](data:image/svg+xml,<svg>)", "<p><img alt=\"x\"/></p>\n"},
+		{"backslash link", `[x](\\\\evil.com)`, "<p><a href=\"\\\\evil.com\"" + rel + ">x</a></p>\n"},
+		{"external link", "[x](https://example.com)", "<p><a href=\"https://example.com\"" + rel + ">x</a></p>\n"},
+		{"code kept", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+	}
+
+	policy := sanitize.UGC()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Sanitize(Render(tt.in)); got != tt.want {
+				t.Errorf("Sanitize(Render(%q))\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -13,6 +14,12 @@ type CacheEntry struct {
 	ExpiresAt time.Time
 }
 
+// RankingCacheEntry is a cached page of trending or popular posts
+type RankingCacheEntry struct {
+	Posts     []*models.Post
+	ExpiresAt time.Time
+}
+
 // CacheStatistics tracks cache performance metrics
 type CacheStatistics struct {
 	Hits         int64
@@ -29,6 +36,7 @@ type CacheStatistics struct {
 type PostRepositoryCachingProxy struct {
 	realRepository models.PostRepositoryInterface
 	cache          map[int64]*CacheEntry
+	rankings       map[string]*RankingCacheEntry // keyed by list, window, type and page
 	cacheMutex     sync.RWMutex
 	maxCacheSize   int
 	cacheTTL       time.Duration
@@ -49,6 +57,7 @@ func NewPostRepositoryCachingProxy(realRepo models.PostRepositoryInterface, maxS
 	return &PostRepositoryCachingProxy{
 		realRepository: realRepo,
 		cache:          make(map[int64]*CacheEntry),
+		rankings:       make(map[string]*RankingCacheEntry),
 		maxCacheSize:   maxSize,
 		cacheTTL:       ttl,
 	}
@@ -80,32 +89,52 @@ func (p *PostRepositoryCachingProxy) FindByID(id int64) (*models.Post, error) {
 	return post, nil
 }
 
+// FindBySlug passes through to real repository and caches the result by ID
+func (p *PostRepositoryCachingProxy) FindBySlug(slug string) (*models.Post, error) {
+	post, err := p.realRepository.FindBySlug(slug)
+	if err != nil || post == nil {
+		return post, err
+	}
+
+	p.addToCache(post.ID, post)
+	return post, nil
+}
+
+// FindSlugRedirect passes through to real repository
+func (p *PostRepositoryCachingProxy) FindSlugRedirect(slug string) (int64, error) {
+	return p.realRepository.FindSlugRedirect(slug)
+}
+
 // Create passes through to real repository and invalidates cache
-func (p *PostRepositoryCachingProxy) Create(post *models.Post) error {
-	err := p.realRepository.Create(post)
+func (p *PostRepositoryCachingProxy) Create(post *models.Post, taxonomy models.PostTaxonomy, meta models.EventMeta) error {
+	err := p.realRepository.Create(post, taxonomy, meta)
 	if err == nil {
 		// Add newly created post to cache
 		p.addToCache(post.ID, post)
+		p.invalidateGroup(post)
 	}
 	return err
 }
 
 // Update passes through and invalidates cache entry
-func (p *PostRepositoryCachingProxy) Update(post *models.Post) error {
-	err := p.realRepository.Update(post)
+func (p *PostRepositoryCachingProxy) Update(post *models.Post, taxonomy models.PostTaxonomy, meta models.EventMeta) error {
+	err := p.realRepository.Update(post, taxonomy, meta)
 	if err == nil {
 		// Invalidate cache for this post
 		p.invalidateCache(post.ID)
+		p.invalidateGroup(post)
+		p.InvalidateRankings()
 	}
 	return err
 }
 
 // Delete passes through and invalidates cache entry
-func (p *PostRepositoryCachingProxy) Delete(id int64) error {
-	err := p.realRepository.Delete(id)
+func (p *PostRepositoryCachingProxy) Delete(id int64, meta models.EventMeta) error {
+	err := p.realRepository.Delete(id, meta)
 	if err == nil {
 		// Remove from cache
 		p.invalidateCache(id)
+		p.InvalidateRankings()
 	}
 	return err
 }
@@ -116,6 +145,114 @@ func (p *PostRepositoryCachingProxy) FindAll(status, contentType string, limit,
 	return p.realRepository.FindAll(status, contentType, limit, offset)
 }
 
+// FindAllInLocales passes through to real repository
+func (p *PostRepositoryCachingProxy) FindAllInLocales(locales []string, status, contentType string, limit, offset int) ([]*models.Post, error) {
+	return p.realRepository.FindAllInLocales(locales, status, contentType, limit, offset)
+}
+
+// FindTranslations passes through to real repository
+func (p *PostRepositoryCachingProxy) FindTranslations(groups []int64) (map[int64][]*models.PostTranslation, error) {
+	return p.realRepository.FindTranslations(groups)
+}
+
+// FindByAuthor passes through to real repository
+func (p *PostRepositoryCachingProxy) FindByAuthor(authorID int64, status string, limit, offset int) ([]*models.Post, error) {
+	return p.realRepository.FindByAuthor(authorID, status, limit, offset)
+}
+
+// FindPublishedModifications passes through to real repository
+func (p *PostRepositoryCachingProxy) FindPublishedModifications() ([]*models.PostModification, error) {
+	return p.realRepository.FindPublishedModifications()
+}
+
+// FindTrending serves ranking pages from cache. Rankings only change when
+// they are refreshed, so a page can be reused until the TTL expires or
+// InvalidateRankings is called.
+func (p *PostRepositoryCachingProxy) FindTrending(window, contentType string, limit, offset int) ([]*models.Post, error) {
+	return p.findRanked("trending", window, contentType, limit, offset, p.realRepository.FindTrending)
+}
+
+// FindPopular serves ranking pages from cache, like FindTrending
+func (p *PostRepositoryCachingProxy) FindPopular(window, contentType string, limit, offset int) ([]*models.Post, error) {
+	return p.findRanked("popular", window, contentType, limit, offset, p.realRepository.FindPopular)
+}
+
+func (p *PostRepositoryCachingProxy) findRanked(
+	list, window, contentType string,
+	limit, offset int,
+	find func(window, contentType string, limit, offset int) ([]*models.Post, error),
+) ([]*models.Post, error) {
+	key := fmt.Sprintf("%s|%s|%s|%d|%d", list, window, contentType, limit, offset)
+
+	p.cacheMutex.RLock()
+	entry, exists := p.rankings[key]
+	p.cacheMutex.RUnlock()
+
+	if exists && time.Now().Before(entry.ExpiresAt) {
+		p.recordHit()
+		return entry.Posts, nil
+	}
+
+	p.recordMiss()
+	posts, err := find(window, contentType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cacheMutex.Lock()
+	if len(p.rankings) >= p.maxCacheSize {
+		p.rankings = make(map[string]*RankingCacheEntry)
+		p.recordEviction()
+	}
+	p.rankings[key] = &RankingCacheEntry{Posts: posts, ExpiresAt: time.Now().Add(p.cacheTTL)}
+	p.cacheMutex.Unlock()
+
+	return posts, nil
+}
+
+// InvalidateRankings drops every cached ranking page. Call it after the
+// rankings are refreshed, and it is called whenever a post changes so an
+// edited or deleted post is not served from a stale page.
+func (p *PostRepositoryCachingProxy) InvalidateRankings() {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+	p.rankings = make(map[string]*RankingCacheEntry)
+}
+
+// PublishDue passes through and invalidates every post it changed
+func (p *PostRepositoryCachingProxy) PublishDue(now time.Time) ([]*models.Post, error) {
+	posts, err := p.realRepository.PublishDue(now)
+	for _, post := range posts {
+		p.invalidateCache(post.ID)
+	}
+	if len(posts) > 0 {
+		p.InvalidateRankings()
+	}
+	return posts, err
+}
+
+// UnpublishDue passes through and invalidates every post it changed
+func (p *PostRepositoryCachingProxy) UnpublishDue(now time.Time) ([]*models.Post, error) {
+	posts, err := p.realRepository.UnpublishDue(now)
+	for _, post := range posts {
+		p.invalidateCache(post.ID)
+	}
+	if len(posts) > 0 {
+		p.InvalidateRankings()
+	}
+	return posts, err
+}
+
+// AssignDefaultLocale passes through and clears the cache, since any post
+// may have changed
+func (p *PostRepositoryCachingProxy) AssignDefaultLocale(locale string) (int64, error) {
+	assigned, err := p.realRepository.AssignDefaultLocale(locale)
+	if assigned > 0 {
+		p.ClearCache()
+	}
+	return assigned, err
+}
+
 // addToCache adds or updates a cache entry with LRU eviction
 func (p *PostRepositoryCachingProxy) addToCache(id int64, post *models.Post) {
 	p.cacheMutex.Lock()
@@ -150,11 +287,20 @@ func (p *PostRepositoryCachingProxy) invalidateCache(id int64) {
 	delete(p.cache, id)
 }
 
+// invalidateGroup drops the post a translation group is named after, which
+// the repository updates when a post starts the group by joining it
+func (p *PostRepositoryCachingProxy) invalidateGroup(post *models.Post) {
+	if post.TranslationGroup != nil {
+		p.invalidateCache(*post.TranslationGroup)
+	}
+}
+
 // ClearCache removes all cached entries
 func (p *PostRepositoryCachingProxy) ClearCache() {
 	p.cacheMutex.Lock()
 	defer p.cacheMutex.Unlock()
 	p.cache = make(map[int64]*CacheEntry)
+	p.rankings = make(map[string]*RankingCacheEntry)
 }
 
 // GetStatistics returns cache performance metrics
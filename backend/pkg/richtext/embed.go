@@ -0,0 +1,132 @@
+package richtext
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// provider turns a link to one of its pages into the URL of its embeddable
+// player. Only the IDs captured by pattern reach the player URL.
+type provider struct {
+	name    string
+	title   string
+	pattern *regexp.Regexp
+	player  func(match []string) string
+}
+
+// providers is the allow list of embeddable sites
+var providers = []provider{
+	{
+		name:    "youtube",
+		title:   "YouTube video",
+		pattern: regexp.MustCompile(`^https?://(?:www\.|m\.)?(?:youtube\.com/(?:watch\?(?:[^#\s]*&)?v=|shorts/|embed/)|youtu\.be/)([A-Za-z0-9_-]{11})(?:[?&#][^\s]*)?$`),
+		player: func(m []string) string {
+			return "https://www.youtube-nocookie.com/embed/" + m[1]
+		},
+	},
+	{
+		name:    "vimeo",
+		title:   "Vimeo video",
+		pattern: regexp.MustCompile(`^https?://(?:www\.)?vimeo\.com/(\d+)(?:[?#][^\s]*)?$`),
+		player: func(m []string) string {
+			return "https://player.vimeo.com/video/" + m[1]
+		},
+	},
+	{
+		name:    "codepen",
+		title:   "CodePen",
+		pattern: regexp.MustCompile(`^https?://codepen\.io/([A-Za-z0-9_-]+)/pen/([A-Za-z0-9]+)/?(?:[?#][^\s]*)?$`),
+		player: func(m []string) string {
+			return "https://codepen.io/" + m[1] + "/embed/" + m[2] + "?default-tab=result"
+		},
+	},
+	{
+		name:    "spotify",
+		title:   "Spotify player",
+		pattern: regexp.MustCompile(`^https?://open\.spotify\.com/(track|album|playlist|episode|show)/([A-Za-z0-9]+)(?:[?#][^\s]*)?$`),
+		player: func(m []string) string {
+			return "https://open.spotify.com/embed/" + m[1] + "/" + m[2]
+		},
+	},
+}
+
+// EmbedLinks replaces each paragraph holding nothing but a link to an
+// allow-listed provider, either as bare text or as a link whose text is
+// its URL, with a sandboxed player:
+//
+//	<figure class="embed embed-youtube"><iframe src="..."></iframe></figure>
+func EmbedLinks(root *html.Node) {
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.P {
+			return true
+		}
+
+		url := standaloneURL(n)
+		if url == "" {
+			return false
+		}
+		for _, p := range providers {
+			if m := p.pattern.FindStringSubmatch(url); m != nil {
+				n.Parent.InsertBefore(p.embed(p.player(m)), n)
+				n.Parent.RemoveChild(n)
+				break
+			}
+		}
+		return false
+	})
+}
+
+// standaloneURL returns the URL a paragraph consists of, if any
+func standaloneURL(p *html.Node) string {
+	var only *html.Node
+	for child := p.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode && strings.TrimSpace(child.Data) == "" {
+			continue
+		}
+		if only != nil {
+			return ""
+		}
+		only = child
+	}
+	if only == nil {
+		return ""
+	}
+
+	switch {
+	case only.Type == html.TextNode:
+		return strings.TrimSpace(only.Data)
+	case only.Type == html.ElementNode && only.DataAtom == atom.A:
+		href := attr(only, "href")
+		if href == TextContent(only) {
+			return href
+		}
+	}
+	return ""
+}
+
+func (p provider) embed(src string) *html.Node {
+	figure := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "figure",
+		DataAtom: atom.Figure,
+		Attr:     []html.Attribute{{Key: "class", Val: "embed embed-" + p.name}},
+	}
+	figure.AppendChild(&html.Node{
+		Type:     html.ElementNode,
+		Data:     "iframe",
+		DataAtom: atom.Iframe,
+		Attr: []html.Attribute{
+			{Key: "src", Val: src},
+			{Key: "title", Val: p.title},
+			{Key: "loading", Val: "lazy"},
+			{Key: "sandbox", Val: "allow-scripts allow-same-origin allow-popups allow-presentation"},
+			{Key: "allow", Val: "encrypted-media; fullscreen; picture-in-picture"},
+			{Key: "referrerpolicy", Val: "strict-origin-when-cross-origin"},
+			{Key: "allowfullscreen", Val: ""},
+		},
+	})
+	return figure
+}
@@ -0,0 +1,193 @@
+package richtext
+
+import (
+	"strings"
+
+	"blog-platform/pkg/highlight"
+	"blog-platform/pkg/slug"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Heading is an entry of a document's table of contents
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// AnchorHeadings gives every h1-h6 a unique id derived from its text and
+// returns the headings in document order
+func AnchorHeadings(root *html.Node) []Heading {
+	var headings []Heading
+	used := make(map[string]bool)
+
+	walk(root, func(n *html.Node) bool {
+		level := headingLevel(n)
+		if level == 0 {
+			return true
+		}
+
+		text := TextContent(n)
+		base := slug.Make(text)
+		if base == "" {
+			base = "section"
+		}
+		id, _ := slug.Unique(base, func(candidate string) (bool, error) {
+			return used[candidate], nil
+		})
+		used[id] = true
+
+		setAttr(n, "id", id)
+		headings = append(headings, Heading{Level: level, ID: id, Text: text})
+		return false
+	})
+
+	return headings
+}
+
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode {
+		return 0
+	}
+	switch n.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+// HighlightCode colours <pre><code class="language-x"> blocks of a
+// supported language, wrapping tokens in <span class="hl-keyword"> and
+// similar. Code blocks in other languages are left alone.
+func HighlightCode(root *html.Node) {
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Code || n.Parent == nil || n.Parent.DataAtom != atom.Pre {
+			return true
+		}
+
+		lang := codeLanguage(n)
+		if !highlight.Supported(lang) {
+			return false
+		}
+
+		code := TextContent(n)
+		for child := n.FirstChild; child != nil; child = n.FirstChild {
+			n.RemoveChild(child)
+		}
+		for _, token := range highlight.Tokenize(lang, code) {
+			text := &html.Node{Type: html.TextNode, Data: token.Text}
+			if token.Class == highlight.Plain {
+				n.AppendChild(text)
+				continue
+			}
+			span := &html.Node{
+				Type:     html.ElementNode,
+				Data:     "span",
+				DataAtom: atom.Span,
+				Attr:     []html.Attribute{{Key: "class", Val: "hl-" + token.Class}},
+			}
+			span.AppendChild(text)
+			n.AppendChild(span)
+		}
+		return false
+	})
+}
+
+// codeLanguage reads the language from a language-x class
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if lang := strings.TrimPrefix(class, "language-"); lang != class {
+			return lang
+		}
+	}
+	return ""
+}
+
+// FirstParagraph returns the text of the first paragraph that has any,
+// with whitespace collapsed
+func FirstParagraph(root *html.Node) string {
+	var text string
+	walk(root, func(n *html.Node) bool {
+		if text != "" {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.P {
+			text = strings.Join(strings.Fields(TextContent(n)), " ")
+			return false
+		}
+		return true
+	})
+	return text
+}
+
+// FirstImage returns the src of the first image, or "" when there is none
+func FirstImage(root *html.Node) string {
+	var src string
+	walk(root, func(n *html.Node) bool {
+		if src != "" {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+			src = attr(n, "src")
+		}
+		return true
+	})
+	return src
+}
+
+// TextContent returns the text of a node and its descendants
+func TextContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(node *html.Node) bool {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+		return true
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// walk visits n and its descendants in document order. Returning false
+// from visit skips the node's children.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; {
+		// Read the sibling first so visit may replace the child
+		next := child.NextSibling
+		walk(child, visit)
+		child = next
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,311 @@
+package sanitize
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Policy is an allow list of elements, attributes and URL schemes. Markup
+// it does not allow is unwrapped (its text is kept) except for elements
+// whose content is never meant to be shown, which are dropped entirely.
+type Policy struct {
+	elements   map[string]map[string]bool // allowed attributes per element
+	urlAttrs   map[string]bool
+	schemes    map[string]bool
+	classes    *regexp.Regexp
+	dropped    map[string]bool
+	linkRel    string
+	transforms []func(*html.Node)
+}
+
+// NewPolicy returns an empty policy that allows nothing but text
+func NewPolicy() *Policy {
+	return &Policy{
+		elements: make(map[string]map[string]bool),
+		urlAttrs: map[string]bool{"href": true, "src": true, "cite": true},
+		schemes:  make(map[string]bool),
+		dropped: map[string]bool{
+			"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+			"noscript": true, "template": true, "textarea": true, "select": true, "title": true,
+			"head": true, "meta": true, "link": true, "base": true, "svg": true, "math": true,
+			"frame": true, "frameset": true, "applet": true, "button": true,
+		},
+	}
+}
+
+// Allow permits an element with the given attributes
+func (p *Policy) Allow(element string, attrs ...string) *Policy {
+	allowed := p.elements[element]
+	if allowed == nil {
+		allowed = make(map[string]bool)
+		p.elements[element] = allowed
+	}
+	for _, attr := range attrs {
+		allowed[attr] = true
+	}
+	delete(p.dropped, element)
+	return p
+}
+
+// AllowSchemes permits absolute URLs with these schemes in href, src and
+// cite. Relative URLs are always allowed.
+func (p *Policy) AllowSchemes(schemes ...string) *Policy {
+	for _, scheme := range schemes {
+		p.schemes[scheme] = true
+	}
+	return p
+}
+
+// AllowClasses keeps class names matching pattern on elements that allow
+// the class attribute
+func (p *Policy) AllowClasses(pattern *regexp.Regexp) *Policy {
+	p.classes = pattern
+	return p
+}
+
+// RequireLinkRel sets rel on every link to an absolute URL
+func (p *Policy) RequireLinkRel(rel string) *Policy {
+	p.linkRel = rel
+	return p
+}
+
+// Transform adds a function run on the sanitized tree before it is
+// rendered. It may add markup; what it adds is not checked again.
+func (p *Policy) Transform(transform func(root *html.Node)) *Policy {
+	p.transforms = append(p.transforms, transform)
+	return p
+}
+
+// UGC returns a policy for user-generated content: text formatting,
+// headings, lists, quotes, code, tables, images and math markup, with links
+// limited to http, https and mailto and marked rel="nofollow noopener
+// noreferrer". The only classes kept are code languages, highlighting and
+// math ones.
+func UGC() *Policy {
+	p := NewPolicy().
+		AllowSchemes("http", "https", "mailto").
+		AllowClasses(regexp.MustCompile(`^(?:language-[a-zA-Z0-9_+-]+|hl-[a-z]+|math|inline|display)$`)).
+		RequireLinkRel("nofollow noopener noreferrer")
+
+	for _, element := range []string{
+		"b", "br", "caption", "dd", "del", "details", "dl", "dt", "em", "figcaption", "figure",
+		"h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "ins", "kbd", "li", "mark", "p", "pre",
+		"s", "samp", "small", "strong", "sub", "summary", "sup", "table", "tbody",
+		"tfoot", "thead", "tr", "u", "ul", "var",
+	} {
+		p.Allow(element)
+	}
+
+	p.Allow("a", "href", "title")
+	p.Allow("abbr", "title")
+	p.Allow("blockquote", "cite")
+	p.Allow("code", "class")
+	p.Allow("img", "src", "alt", "title", "width", "height")
+	p.Allow("ol", "start")
+	p.Allow("q", "cite")
+	p.Allow("span", "class")
+	p.Allow("td", "align", "colspan", "rowspan")
+	p.Allow("th", "align", "colspan", "rowspan", "scope")
+
+	return p
+}
+
+// Sanitize parses an HTML fragment and returns it with everything the
+// policy does not allow removed
+func (p *Policy) Sanitize(fragment string) string {
+	return Render(p.Tree(fragment))
+}
+
+// Render writes the children of a container returned by Tree as HTML
+func Render(root *html.Node) string {
+	var b strings.Builder
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		html.Render(&b, child)
+	}
+	return b.String()
+}
+
+// Tree parses and sanitizes a fragment, returning a container node whose
+// children are the result
+func (p *Policy) Tree(fragment string) *html.Node {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		// The tokenizer accepts any input, so this only happens on reader errors
+		root.AppendChild(&html.Node{Type: html.TextNode, Data: fragment})
+		return root
+	}
+
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+	p.clean(root)
+
+	for _, transform := range p.transforms {
+		transform(root)
+	}
+	return root
+}
+
+// clean sanitizes the children of n in place
+func (p *Policy) clean(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+
+		switch child.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			name := strings.ToLower(child.Data)
+			allowed, ok := p.elements[name]
+			switch {
+			case p.dropped[name]:
+				n.RemoveChild(child)
+			case !ok:
+				// Unwrap: keep the children in place of the element
+				p.clean(child)
+				for grandchild := child.FirstChild; grandchild != nil; {
+					following := grandchild.NextSibling
+					child.RemoveChild(grandchild)
+					n.InsertBefore(grandchild, child)
+					grandchild = following
+				}
+				n.RemoveChild(child)
+			default:
+				child.Attr = p.cleanAttrs(name, child.Attr, allowed)
+				p.clean(child)
+			}
+		default:
+			// Comments, doctypes and anything else
+			n.RemoveChild(child)
+		}
+
+		child = next
+	}
+}
+
+func (p *Policy) cleanAttrs(element string, attrs []html.Attribute, allowed map[string]bool) []html.Attribute {
+	var kept []html.Attribute
+	absoluteLink := false
+
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !allowed[key] {
+			continue
+		}
+
+		if p.urlAttrs[key] {
+			safe, absolute := p.safeURL(attr.Val)
+			if !safe {
+				continue
+			}
+			if element == "a" && key == "href" && absolute {
+				absoluteLink = true
+			}
+		}
+
+		if key == "class" {
+			attr.Val = p.cleanClasses(attr.Val)
+			if attr.Val == "" {
+				continue
+			}
+		}
+
+		kept = append(kept, html.Attribute{Key: key, Val: attr.Val})
+	}
+
+	if absoluteLink && p.linkRel != "" {
+		kept = append(kept, html.Attribute{Key: "rel", Val: p.linkRel})
+	}
+	return kept
+}
+
+func (p *Policy) cleanClasses(value string) string {
+	if p.classes == nil {
+		return ""
+	}
+	var kept []string
+	for _, class := range strings.Fields(value) {
+		if p.classes.MatchString(class) {
+			kept = append(kept, class)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// safeURL reports whether a URL is relative or uses an allowed scheme.
+// Browsers ignore whitespace and control characters in schemes, so they
+// are removed before checking, and read backslashes as slashes, so
+// "\\host" is protocol-relative like "//host".
+func (p *Policy) safeURL(raw string) (safe, absolute bool) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		if r == '\\' {
+			return '/'
+		}
+		return r
+	}, raw)
+
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return false, false
+	}
+	if u.Scheme == "" {
+		// Protocol-relative URLs point at another host
+		return !strings.HasPrefix(cleaned, "//") || p.schemes["https"], strings.HasPrefix(cleaned, "//")
+	}
+	return p.schemes[strings.ToLower(u.Scheme)], true
+}
+
+// blockElements separate words when HTML is flattened to text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true, "blockquote": true,
+	"pre": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"tr": true, "td": true, "th": true, "table": true, "hr": true, "dd": true, "dt": true,
+	"figure": true, "figcaption": true, "section": true, "article": true,
+}
+
+// Text returns the visible text of an HTML fragment with whitespace
+// collapsed. Script and style content is not included.
+func Text(fragment string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+			block := blockElements[n.Data]
+			if block {
+				b.WriteByte(' ')
+			}
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+			if block {
+				b.WriteByte(' ')
+			}
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,176 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const rel = ` rel="nofollow noopener noreferrer"`
+
+func TestUGCSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Allowed markup
+		{"formatting", `<p><strong>bold</strong> and <em>italic</em></p>`, `<p><strong>bold</strong> and <em>italic</em></p>`},
+		{"relative link", `<a href="/posts/1">post</a>`, `<a href="/posts/1">post</a>`},
+		{"absolute link", `<a href="https://example.com">x</a>`, `<a href="https://example.com"` + rel + `>x</a>`},
+		{"mailto link", `<a href="mailto:a@example.com">mail</a>`, `<a href="mailto:a@example.com"` + rel + `>mail</a>`},
+		{"image", `<img src="/media/a.png" alt="a">`, `<img src="/media/a.png" alt="a"/>`},
+		{"code language class", `<code class="language-go evil">x</code>`, `<code class="language-go">x</code>`},
+		{"author rel replaced", `<a href="https://example.com" rel="opener">x</a>`, `<a href="https://example.com"` + rel + `>x</a>`},
+
+		// Dangerous schemes, including whitespace and control characters
+		// browsers skip when reading the scheme
+		{"javascript", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript upper case", `<a href="JavaScript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript leading space", `<a href="  javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript tab", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript newline", "<a href=\"java\nscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript entity tab", `<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript entity newline", `<a href="java&#10;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript null", `<a href="java&#0;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript control char", "<a href=\"\x01javascript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript delete char", "<a href=\"java\x7fscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript entity colon", `<a href="javascript&colon;alert(1)">x</a>`, `<a>x</a>`},
+		{"vbscript", `<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{"data image src", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img/>`},
+		{"data text link", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a>x</a>`},
+		{"data with whitespace", `<a href=" d a t a:text/html,x">x</a>`, `<a>x</a>`},
+		{"javascript blockquote cite", `<blockquote cite="javascript:alert(1)">q</blockquote>`, `<blockquote>q</blockquote>`},
+
+		// Protocol-relative links, which browsers also spell with backslashes
+		{"protocol relative", `<a href="//evil.com/x">x</a>`, `<a href="//evil.com/x"` + rel + `>x</a>`},
+		{"backslashes", `<a href="\\evil.com/x">x</a>`, `<a href="\\evil.com/x"` + rel + `>x</a>`},
+		{"slash backslash", `<a href="/\evil.com/x">x</a>`, `<a href="/\evil.com/x"` + rel + `>x</a>`},
+		{"backslashes with space", `<a href=" \\evil.com">x</a>`, `<a href=" \\evil.com"` + rel + `>x</a>`},
+
+		// Event handlers and other attributes not allowed
+		{"onclick", `<p onclick="alert(1)">x</p>`, `<p>x</p>`},
+		{"onerror", `<img src="/a.png" onerror="alert(1)">`, `<img src="/a.png"/>`},
+		{"onmouseover upper case", `<a href="/" ONMOUSEOVER="alert(1)">x</a>`, `<a href="/">x</a>`},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, `<p>x</p>`},
+		{"srcset", `<img src="/a.png" srcset="javascript:alert(1) 2x">`, `<img src="/a.png"/>`},
+		{"attribute breakout", `<a href="/" title='"><script>alert(1)</script>'>x</a>`, `<a href="/" title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</a>`},
+
+		// Elements dropped with their content
+		{"script", `<p>a<script>alert(1)</script>b</p>`, `<p>ab</p>`},
+		{"style", `<style>body{background:url(javascript:alert(1))}</style><p>x</p>`, `<p>x</p>`},
+		{"style in paragraph", `<p>a<style>@import "//evil.com/x.css";</style>b</p>`, `<p>ab</p>`},
+		{"svg", `<svg onload="alert(1)"><script>alert(1)</script></svg><p>x</p>`, `<p>x</p>`},
+		{"svg animate", `<svg><a><animate attributeName="href" to="javascript:alert(1)"/><text>x</text></a></svg>`, ``},
+		{"svg foreign object", `<svg><foreignObject><img src="x" onerror="alert(1)"></foreignObject></svg>`, ``},
+		{"math", `<math><mtext><table><mglyph><style><img src=x onerror=alert(1)></style></mglyph></table></mtext></math>`, ``},
+		{"math href", `<math href="javascript:alert(1)"><mi>x</mi></math>`, ``},
+		{"iframe", `<iframe src="https://evil.com"></iframe>`, ``},
+		{"object", `<object data="evil.swf"><param name="x"></object>`, ``},
+		{"form controls", `<form action="/x"><button formaction="javascript:alert(1)">go</button></form>`, ``},
+		{"base", `<base href="https://evil.com/">`, ``},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`, ``},
+		// Browsers with scripting end noscript at the first </noscript>
+		{"noscript", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`, `<img src="x"/>&#34;&gt;`},
+		{"template", `<template><img src=x onerror=alert(1)></template>`, ``},
+
+		// Elements not allowed are unwrapped
+		{"div unwrapped", `<div><p>x</p></div>`, `<p>x</p>`},
+		{"font unwrapped", `<font color="red">x</font>`, `x`},
+		{"comment", `<p>a<!-- <script>alert(1)</script> -->b</p>`, `<p>ab</p>`},
+		{"conditional comment", `<!--[if IE]><script>alert(1)</script><![endif]--><p>x</p>`, `<p>x</p>`},
+		{"text escaped", `1 < 2 & "3"`, `1 &lt; 2 &amp; &#34;3&#34;`},
+	}
+
+	policy := UGC()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		safe     bool
+		absolute bool
+	}{
+		{"/posts/1", true, false},
+		{"posts/1", true, false},
+		{"#section", true, false},
+		{"?page=2", true, false},
+		{"https://example.com", true, true},
+		{"HTTPS://example.com", true, true},
+		{"//example.com", true, true},
+		{`\\example.com`, true, true},
+		{`\/example.com`, true, true},
+		{`/\example.com`, true, true},
+		{"\t//example.com", true, true},
+		{"javascript:alert(1)", false, true},
+		{" javascript:alert(1)", false, true},
+		{"jav\x00ascript:alert(1)", false, true},
+		{"jav\x1fascript:alert(1)", false, true},
+		{"data:text/html,x", false, true},
+		{"ftp://example.com", false, true},
+	}
+
+	policy := UGC()
+	for _, tt := range tests {
+		safe, absolute := policy.safeURL(tt.url)
+		if safe != tt.safe || absolute != tt.absolute {
+			t.Errorf("safeURL(%q) = %v, %v; want %v, %v", tt.url, safe, absolute, tt.safe, tt.absolute)
+		}
+	}
+
+	// Without https, protocol-relative links are refused however they are spelled
+	strict := NewPolicy().AllowSchemes("mailto").Allow("a", "href")
+	for _, url := range []string{"//example.com", `\\example.com`, `/\example.com`} {
+		if safe, _ := strict.safeURL(url); safe {
+			t.Errorf("safeURL(%q) allowed a protocol-relative URL without https", url)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`<p>Hello <b>world</b></p><p>again</p>`, "Hello world again"},
+		{`<h1>Title</h1>text<script>alert(1)</script><style>p{}</style>`, "Title text"},
+		{"a &amp; b &lt;c&gt;", "a & b <c>"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeNeverEmitsActiveContent(t *testing.T) {
+	payloads := []string{
+		`<img src=x onerror=alert(1)//`,
+		`<svg/onload=alert(1)>`,
+		`<a href="jav&#x0A;ascript:alert(1)">x`,
+		`<<script>alert(1)//<</script>`,
+		`<scr<script>ipt>alert(1)</scr</script>ipt>`,
+		`<p <img src=x onerror=alert(1)>>`,
+		`<math><style><img src=x onerror=alert(1)></style></math>`,
+		`<svg><style><img src=x onerror=alert(1)></style></svg>`,
+		`<table><style><img src=x onerror=alert(1)></style></table>`,
+		`<select><style><img src=x onerror=alert(1)></style></select>`,
+		`<a href="\\evil.com" onclick="alert(1)">x</a>`,
+	}
+
+	policy := UGC()
+	for _, payload := range payloads {
+		out := strings.ToLower(policy.Sanitize(payload))
+		for _, bad := range []string{"<script", "<svg", "<math", "<style", "onerror", "onload", "onclick", "javascript:"} {
+			if strings.Contains(out, bad) {
+				t.Errorf("Sanitize(%q) = %q contains %q", payload, out, bad)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package seo
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"time"
+)
+
+// Head is the metadata a page declares in its <head>
+type Head struct {
+	Title       string      `json:"title"` // content of <title>
+	Description string      `json:"description"`
+	Canonical   string      `json:"canonical_url"`
+	Robots      string      `json:"robots"` // e.g. "index, follow"
+	Alternates  []Alternate `json:"alternates,omitempty"`
+	Meta        []Meta      `json:"meta"` // Open Graph, Twitter card and article properties
+	JSONLD      interface{} `json:"json_ld,omitempty"`
+}
+
+// Meta is one <meta> tag. Open Graph tags are keyed by Property, others by
+// Name.
+type Meta struct {
+	Name     string `json:"name,omitempty"`
+	Property string `json:"property,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Alternate is a language version of the page. Hreflang is a BCP 47 tag,
+// or x-default for the version shown to readers matching no other.
+type Alternate struct {
+	Hreflang string `json:"hreflang"`
+	Href     string `json:"href"`
+}
+
+// HTML renders the head as tags ready to paste into a page's <head>
+func (h Head) HTML() (string, error) {
+	var b strings.Builder
+
+	b.WriteString("<title>" + html.EscapeString(h.Title) + "</title>\n")
+	if h.Description != "" {
+		writeMeta(&b, "name", "description", h.Description)
+	}
+	if h.Robots != "" {
+		writeMeta(&b, "name", "robots", h.Robots)
+	}
+	if h.Canonical != "" {
+		b.WriteString(`<link rel="canonical" href="` + html.EscapeString(h.Canonical) + "\">\n")
+	}
+	for _, alternate := range h.Alternates {
+		b.WriteString(`<link rel="alternate" hreflang="` + html.EscapeString(alternate.Hreflang) +
+			`" href="` + html.EscapeString(alternate.Href) + "\">\n")
+	}
+	for _, m := range h.Meta {
+		if m.Property != "" {
+			writeMeta(&b, "property", m.Property, m.Content)
+		} else {
+			writeMeta(&b, "name", m.Name, m.Content)
+		}
+	}
+
+	if h.JSONLD != nil {
+		// json.Marshal escapes <, > and &, so the data cannot close the script
+		data, err := json.Marshal(h.JSONLD)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(`<script type="application/ld+json">` + string(data) + "</script>\n")
+	}
+
+	return b.String(), nil
+}
+
+func writeMeta(b *strings.Builder, key, name, content string) {
+	b.WriteString(`<meta ` + key + `="` + html.EscapeString(name) + `" content="` + html.EscapeString(content) + "\">\n")
+}
+
+// BlogPosting is schema.org structured data describing a blog post
+type BlogPosting struct {
+	Headline         string    `json:"headline"`
+	Description      string    `json:"description,omitempty"`
+	Image            []string  `json:"image,omitempty"`
+	DatePublished    time.Time `json:"datePublished"`
+	DateModified     time.Time `json:"dateModified"`
+	Author           Thing     `json:"author"`
+	Publisher        Thing     `json:"publisher"`
+	URL              string    `json:"url"`
+	InLanguage       string    `json:"inLanguage,omitempty"`
+	MainEntityOfPage string    `json:"mainEntityOfPage"`
+	Keywords         string    `json:"keywords,omitempty"` // comma separated
+	ArticleSection   string    `json:"articleSection,omitempty"`
+	WordCount        int       `json:"wordCount,omitempty"`
+}
+
+// Thing is a schema.org entity such as a Person or an Organization
+type Thing struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// MarshalJSON adds the JSON-LD context and type
+func (p BlogPosting) MarshalJSON() ([]byte, error) {
+	type fields BlogPosting
+	return json.Marshal(struct {
+		Context string `json:"@context"`
+		Type    string `json:"@type"`
+		fields
+	}{"https://schema.org", "BlogPosting", fields(p)})
+}
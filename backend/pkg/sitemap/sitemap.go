@@ -0,0 +1,118 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs is the most URLs one sitemap file may list; larger sites are
+// split into several files listed by a sitemap index
+const MaxURLs = 50000
+
+const (
+	ContentType    = "application/xml; charset=utf-8"
+	namespace      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+)
+
+// URL is one location in a sitemap or sitemap index
+type URL struct {
+	Loc     string
+	LastMod time.Time
+
+	// Alternates lists every language version of the page, including the
+	// page itself, as search engines expect
+	Alternates []Alternate
+}
+
+// Alternate is a language version of a page. Hreflang is a BCP 47 tag, or
+// x-default for the version shown to readers matching no other.
+type Alternate struct {
+	Hreflang string
+	Href     string
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	NS      string     `xml:"xmlns,attr"`
+	XHTMLNS string     `xml:"xmlns:xhtml,attr,omitempty"`
+	URLs    []location `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	NS       string     `xml:"xmlns,attr"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc     string      `xml:"loc"`
+	LastMod string      `xml:"lastmod,omitempty"`
+	Links   []xhtmlLink `xml:"xhtml:link"`
+}
+
+type xhtmlLink struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// URLSet encodes up to MaxURLs locations as a sitemap
+func URLSet(urls []URL) ([]byte, error) {
+	set := urlSet{NS: namespace, URLs: locations(urls)}
+	for _, u := range urls {
+		if len(u.Alternates) > 0 {
+			set.XHTMLNS = xhtmlNamespace
+			break
+		}
+	}
+	return encode(set)
+}
+
+// Index encodes a sitemap index pointing at sitemap files
+func Index(sitemaps []URL) ([]byte, error) {
+	return encode(sitemapIndex{NS: namespace, Sitemaps: locations(sitemaps)})
+}
+
+// Split divides urls into chunks of at most MaxURLs
+func Split(urls []URL) [][]URL {
+	var chunks [][]URL
+	for len(urls) > MaxURLs {
+		chunks = append(chunks, urls[:MaxURLs])
+		urls = urls[MaxURLs:]
+	}
+	return append(chunks, urls)
+}
+
+// LastMod returns the latest modification time among urls
+func LastMod(urls []URL) time.Time {
+	var latest time.Time
+	for _, u := range urls {
+		if u.LastMod.After(latest) {
+			latest = u.LastMod
+		}
+	}
+	return latest
+}
+
+func locations(urls []URL) []location {
+	locs := make([]location, len(urls))
+	for i, u := range urls {
+		locs[i] = location{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			locs[i].LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		for _, alternate := range u.Alternates {
+			locs[i].Links = append(locs[i].Links, xhtmlLink{Rel: "alternate", Hreflang: alternate.Hreflang, Href: alternate.Href})
+		}
+	}
+	return locs
+}
+
+func encode(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
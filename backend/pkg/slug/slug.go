@@ -0,0 +1,117 @@
+package slug
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make will produce
+const MaxLength = 80
+
+// transliterations covers letters that do not decompose into an ASCII base
+// letter plus combining marks
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l",
+	'þ': "th", 'ı': "i", 'ħ': "h", 'ŋ': "ng", 'ſ': "s",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make converts arbitrary text into a lowercase, hyphen-separated slug
+// suitable for use in URLs, e.g. "Héllo, Wörld!" becomes "hello-world".
+// Accented letters are reduced to their base letter and common Latin,
+// Cyrillic and Greek letters are transliterated; anything else is dropped.
+func Make(text string) string {
+	var b strings.Builder
+	pendingHyphen := false
+
+	emit := func(s string) {
+		if s == "" {
+			return
+		}
+		if pendingHyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingHyphen = false
+		b.WriteString(s)
+	}
+
+	// The table is consulted first, since decomposing would turn "ё" into
+	// "е" plus a diaeresis. Other runes go through NFKD, which splits "é"
+	// into "e" plus a combining accent that is skipped below.
+	var letter func(r rune)
+	letter = func(r rune) {
+		if t, ok := transliterations[r]; ok {
+			emit(t)
+			return
+		}
+		if decomposed := norm.NFKD.String(string(r)); decomposed != string(r) {
+			// Compatibility forms may decompose to capitals, e.g. "№" to "No"
+			for _, d := range strings.ToLower(decomposed) {
+				letter(d)
+			}
+			return
+		}
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			emit(string(r))
+		case unicode.Is(unicode.Mn, r):
+			// combining mark left over from decomposition
+		case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			pendingHyphen = true
+		}
+	}
+
+	for _, r := range norm.NFC.String(strings.ToLower(text)) {
+		letter(r)
+	}
+
+	return truncate(b.String(), MaxLength)
+}
+
+// Unique returns base, or base with the smallest numeric suffix ("-2",
+// "-3", ...) for which taken reports false
+func Unique(base string, taken func(candidate string) (bool, error)) (string, error) {
+	candidate := base
+	for n := 2; ; n++ {
+		exists, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix := fmt.Sprintf("-%d", n)
+		candidate = truncate(base, MaxLength-len(suffix)) + suffix
+	}
+}
+
+// truncate shortens a slug to at most max bytes, cutting at a hyphen when
+// possible so words are not split
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	s = s[:max]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, "-")
+}
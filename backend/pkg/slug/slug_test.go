@@ -0,0 +1,30 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"ascii", "Hello, World!", "hello-world"},
+		{"accents", "Héllo, Wörld!", "hello-world"},
+		{"decomposed input", "Café Crème", "cafe-creme"},
+		{"ligatures", "Straße Œuvre", "strasse-oeuvre"},
+		{"cyrillic", "Привет мир", "privet-mir"},
+		{"cyrillic yo", "ёлка", "yolka"},
+		{"cyrillic short i", "Йод", "yod"},
+		{"greek", "Καλημέρα", "kalimera"},
+		{"compatibility forms", "ﬁle №1", "file-no1"},
+		{"soft sign dropped", "Любовь", "lyubov"},
+		{"nothing usable", "!!! ???", ""},
+		{"hyphens collapse", "  a -- b  ", "a-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.text); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage keeps uploaded files as objects addressed by key. Keys are flat
+// names such as "3a7bd3e2...png"; implementations may map them to paths,
+// object names or anything else.
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// validKey rejects keys that could escape a directory or bucket prefix
+func validKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) || strings.ContainsRune(key, 0) {
+		return ErrInvalidKey
+	}
+	return nil
+}
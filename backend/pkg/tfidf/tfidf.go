@@ -0,0 +1,186 @@
+package tfidf
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// stopWords are common English words that carry no topic
+var stopWords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true, "and": true,
+	"any": true, "are": true, "as": true, "at": true, "be": true, "been": true, "but": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true, "has": true,
+	"have": true, "how": true, "if": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "just": true, "more": true, "most": true, "not": true, "of": true, "on": true,
+	"one": true, "or": true, "our": true, "out": true, "so": true, "some": true, "than": true,
+	"that": true, "the": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "to": true, "up": true, "use": true, "was": true,
+	"we": true, "were": true, "what": true, "when": true, "which": true, "while": true,
+	"who": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// Match is a document similar to the one queried
+type Match struct {
+	ID    int64
+	Score float64 // cosine similarity, 0 to 1
+}
+
+// Index holds term counts of documents and finds similar documents by
+// cosine similarity of their TF-IDF vectors. Documents can be added,
+// replaced and removed at any time; weights always reflect the current
+// set of documents. It is safe for concurrent use.
+type Index struct {
+	mu       sync.Mutex
+	docs     map[int64]map[string]int
+	postings map[string]map[int64]struct{} // documents containing each term
+	norms    map[int64]float64             // vector lengths, reset on every change
+}
+
+func New() *Index {
+	return &Index{
+		docs:     make(map[int64]map[string]int),
+		postings: make(map[string]map[int64]struct{}),
+		norms:    make(map[int64]float64),
+	}
+}
+
+// Tokenize lowercases text and splits it into words, dropping stop words
+// and single characters
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) > 1 && !stopWords[field] {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// Add indexes a document, replacing any earlier version with the same ID
+func (ix *Index) Add(id int64, text string) {
+	counts := make(map[string]int)
+	for _, term := range Tokenize(text) {
+		counts[term]++
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+	ix.docs[id] = counts
+	for term := range counts {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[int64]struct{})
+		}
+		ix.postings[term][id] = struct{}{}
+	}
+	ix.norms = make(map[int64]float64)
+}
+
+// Remove drops a document from the index
+func (ix *Index) Remove(id int64) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.remove(id) {
+		ix.norms = make(map[int64]float64)
+	}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.docs)
+}
+
+// Similar returns the documents sharing terms with the given one, most
+// similar first. A limit of 0 returns every match.
+func (ix *Index) Similar(id int64, limit int) []Match {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	doc, ok := ix.docs[id]
+	if !ok {
+		return nil
+	}
+	norm := ix.norm(id)
+	if norm == 0 {
+		return nil
+	}
+
+	dots := make(map[int64]float64)
+	for term, count := range doc {
+		weight := ix.weight(term, count)
+		for other := range ix.postings[term] {
+			if other != id {
+				dots[other] += weight * ix.weight(term, ix.docs[other][term])
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(dots))
+	for other, dot := range dots {
+		if dot > 0 {
+			matches = append(matches, Match{ID: other, Score: dot / (norm * ix.norm(other))})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func (ix *Index) remove(id int64) bool {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return false
+	}
+
+	for term := range doc {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+	return true
+}
+
+// weight is the sublinear TF-IDF weight of a term occurring count times
+func (ix *Index) weight(term string, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	idf := math.Log(float64(len(ix.docs)+1)/float64(len(ix.postings[term])+1)) + 1
+	return (1 + math.Log(float64(count))) * idf
+}
+
+func (ix *Index) norm(id int64) float64 {
+	if norm, ok := ix.norms[id]; ok {
+		return norm
+	}
+
+	var sum float64
+	for term, count := range ix.docs[id] {
+		w := ix.weight(term, count)
+		sum += w * w
+	}
+	norm := math.Sqrt(sum)
+	ix.norms[id] = norm
+	return norm
+}
@@ -33,8 +33,8 @@ function EditPost() {
     try {
       setLoading(true)
       setError(null)
-      await postsAPI.updatePost(id, postData)
-      navigate(`/posts/${id}`)
+      const response = await postsAPI.updatePost(id, postData)
+      navigate(`/posts/${response.data.slug}`)
     } catch (err) {
       setError('Failed to update post. Please try again.')
       console.error('Error updating post:', err)
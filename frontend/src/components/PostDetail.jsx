@@ -3,6 +3,19 @@ import { useParams, useNavigate, Link } from 'react-router-dom'
 import { postsAPI } from '../services/api'
 import './PostDetail.css'
 
+// Post pages are addressed by slug. Links made before slugs existed use
+// the numeric ID, which is tried when no post has the slug.
+const fetchPostByPermalink = async (param) => {
+  try {
+    return (await postsAPI.getPostBySlug(param)).data
+  } catch (err) {
+    if (err.response?.status !== 404 || !/^\d+$/.test(param)) {
+      throw err
+    }
+    return (await postsAPI.getPost(param)).data
+  }
+}
+
 function PostDetail() {
   const { id } = useParams()
   const navigate = useNavigate()
@@ -17,8 +30,7 @@ function PostDetail() {
   const fetchPost = async () => {
     try {
       setLoading(true)
-      const response = await postsAPI.getPost(id)
-      setPost(response.data)
+      setPost(await fetchPostByPermalink(id))
       setError(null)
     } catch (err) {
       setError('Failed to fetch post')
@@ -34,7 +46,7 @@ function PostDetail() {
     }
 
     try {
-      await postsAPI.deletePost(id)
+      await postsAPI.deletePost(post.id)
       navigate('/')
     } catch (err) {
       alert('Failed to delete post')
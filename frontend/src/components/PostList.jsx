@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react'
 import { Link } from 'react-router-dom'
-import { postsAPI } from '../services/api'
+import { postsAPI, subscribeToPostEvents } from '../services/api'
 import './PostList.css'
 
 function PostList() {
@@ -13,6 +13,11 @@ function PostList() {
     fetchPosts()
   }, [filter])
 
+  // Refresh when posts change elsewhere instead of polling
+  useEffect(() => {
+    return subscribeToPostEvents(() => fetchPosts())
+  }, [filter])
+
   const fetchPosts = async () => {
     try {
       setLoading(true)
@@ -136,13 +141,12 @@ function PostList() {
                 </span>
               </div>
 
-              <Link to={`/posts/${post.id}`} className="post-link">
+              <Link to={`/posts/${post.slug}`} className="post-link">
                 <h2 className="post-title">{post.title}</h2>
               </Link>
 
               <p className="post-excerpt">
-                {post.content.substring(0, 150)}
-                {post.content.length > 150 ? '...' : ''}
+                {post.excerpt}
               </p>
 
               <div className="post-meta">
@@ -155,7 +159,7 @@ function PostList() {
               </div>
 
               <div className="post-actions">
-                <Link to={`/posts/${post.id}`} className="btn btn-view">
+                <Link to={`/posts/${post.slug}`} className="btn btn-view">
                   View
                 </Link>
                 <Link to={`/edit/${post.id}`} className="btn btn-edit">
@@ -21,6 +21,11 @@ export const postsAPI = {
     return api.get(`/posts/${id}`);
   },
 
+  // Get a single post by its permalink slug; retired slugs redirect
+  getPostBySlug: (slug) => {
+    return api.get(`/posts/slug/${encodeURIComponent(slug)}`);
+  },
+
   // Create a new post
   createPost: (postData) => {
     return api.post('/posts', postData);
@@ -42,4 +47,15 @@ export const postsAPI = {
   },
 };
 
+// Subscribe to real-time post events (Server-Sent Events). The browser
+// reconnects on its own and resumes from the last event it saw.
+// Returns a function that closes the stream.
+export const subscribeToPostEvents = (onEvent, types = ['post_created', 'post_updated', 'post_deleted']) => {
+  const source = new EventSource(`${API_BASE_URL}/events?types=${types.join(',')}`);
+  types.forEach((type) => {
+    source.addEventListener(type, (e) => onEvent(JSON.parse(e.data)));
+  });
+  return () => source.close();
+};
+
 export default api;